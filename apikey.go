@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cert-manager/webhook-example/pkg/solver"
+)
+
+// apiKeys supplies the webhook-wide API key. main replaces it with a
+// KeyFile or VaultKey when the key is read from a file or Vault.
+var apiKeys solver.APIKeyProvider = solver.StaticAPIKey("")
+
+// configureAPIKey returns the provider of the webhook-wide API key. Keys
+// read from a file or Vault are refreshed until ctx is done.
+func configureAPIKey(ctx context.Context, opts Options) (solver.APIKeyProvider, error) {
+	if opts.VaultAddress != "" {
+		v, err := solver.NewVaultKey(ctx, solver.VaultConfig{
+			Address:        opts.VaultAddress,
+			Namespace:      opts.VaultNamespace,
+			CACertFile:     opts.VaultCACertFile,
+			KVMount:        opts.VaultKVMount,
+			SecretPath:     opts.VaultSecretPath,
+			Key:            opts.VaultSecretKey,
+			Token:          opts.VaultToken,
+			KubernetesRole: opts.VaultKubernetesRole,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read API key from Vault: %w", err)
+		}
+		go v.Watch(ctx, opts.VaultRefreshInterval.Duration)
+		return v, nil
+	}
+	if opts.APIKeyFile == "" {
+		return solver.StaticAPIKey(opts.APIKey), nil
+	}
+	f, err := solver.NewKeyFile(opts.APIKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load API key file: %w", err)
+	}
+	go f.Watch(ctx, solver.DefaultKeyFilePollInterval)
+	return f, nil
+}
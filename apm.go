@@ -0,0 +1,45 @@
+package main
+
+import (
+	"log"
+	"sync"
+
+	"github.com/cert-manager/webhook-example/pkg/apm"
+	"github.com/cert-manager/webhook-example/pkg/solver"
+)
+
+var (
+	instrumentationOnce sync.Once
+	instrumentation     solver.Instrumentation
+)
+
+// solverInstrumentation returns the APM adapters enabled by the options,
+// shared by every solver, or nil if none are.
+func solverInstrumentation() solver.Instrumentation {
+	instrumentationOnce.Do(func() {
+		var adapters []solver.Instrumentation
+		if options.DogStatsDAddress != "" {
+			dd, err := apm.NewDatadog(options.DogStatsDAddress)
+			if err != nil {
+				log.Fatalf("failed to configure Datadog: %v", err)
+			}
+			adapters = append(adapters, dd)
+		}
+		if options.NewRelicAccountID != "" {
+			nr, err := apm.NewNewRelic(options.NewRelicAccountID, options.NewRelicInsertKey, options.NewRelicRegion)
+			if err != nil {
+				log.Fatalf("failed to configure New Relic: %v", err)
+			}
+			adapters = append(adapters, nr)
+		}
+
+		switch len(adapters) {
+		case 0:
+		case 1:
+			instrumentation = adapters[0]
+		default:
+			instrumentation = apm.Multi(adapters...)
+		}
+	})
+	return instrumentation
+}
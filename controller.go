@@ -0,0 +1,381 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook"
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	cmacme "github.com/cert-manager/cert-manager/pkg/apis/acme/v1"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmclient "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned"
+	cminformers "github.com/cert-manager/cert-manager/pkg/client/informers/externalversions"
+	cmlisters "github.com/cert-manager/cert-manager/pkg/client/listers/acme/v1"
+	"github.com/cert-manager/cert-manager/pkg/issuer/acme/dns/util"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/cert-manager/webhook-example/pkg/solver"
+)
+
+// Options.Mode selects how the binary runs. "webhook" (the default) serves
+// the cert-manager aggregated API; "controller" watches Challenge resources
+// directly for clusters where APIService aggregation is not available.
+const (
+	modeWebhook    = "webhook"
+	modeController = "controller"
+
+	controllerResync = 10 * time.Minute
+)
+
+// challengeController drives a solver from Challenge resources instead of
+// from requests proxied through the aggregated API.
+type challengeController struct {
+	groupName string
+	solver    webhook.Solver
+
+	// clusterResourceNamespace is where ClusterIssuer challenges read
+	// their Secrets from, as in cert-manager.
+	clusterResourceNamespace string
+
+	lister cmlisters.ChallengeLister
+	queue  workqueue.TypedRateLimitingInterface[string]
+
+	// findZone resolves the zone of a challenge record.
+	findZone func(ctx context.Context, fqdn string) (string, error)
+
+	// presented and cleanedUp hold the UIDs of Challenges this process has
+	// handled, so resyncs don't repeat the calls. They are not the record
+	// of what needs cleaning up: requests are rebuilt from the Challenge,
+	// so Challenges presented before a restart are cleaned up all the same.
+	// deleted holds the last state of deleted Challenges until then.
+	mu        sync.Mutex
+	presented map[types.UID]bool
+	cleanedUp map[types.UID]bool
+	deleted   map[string]*cmacme.Challenge
+}
+
+func newChallengeController(groupName, clusterResourceNamespace string, s webhook.Solver, lister cmlisters.ChallengeLister) *challengeController {
+	return &challengeController{
+		groupName:                groupName,
+		solver:                   s,
+		clusterResourceNamespace: clusterResourceNamespace,
+		lister:                   lister,
+		queue: workqueue.NewTypedRateLimitingQueue(
+			workqueue.DefaultTypedControllerRateLimiter[string](),
+		),
+		findZone:  findZoneByFqdn,
+		presented: make(map[types.UID]bool),
+		cleanedUp: make(map[types.UID]bool),
+		deleted:   make(map[string]*cmacme.Challenge),
+	}
+}
+
+// runController drives solvers from the Challenges of groupName. Each
+// solver handles the Challenges selecting it by name, from one shared
+// informer.
+func runController(groupName string, solvers []*solver.Solver) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	restConfig, err := loadRestConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load kubernetes config: %w", err)
+	}
+
+	client, err := cmclient.NewForConfig(restConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create cert-manager client: %w", err)
+	}
+
+	factory := cminformers.NewSharedInformerFactory(client, controllerResync)
+	informer := factory.Acme().V1().Challenges()
+
+	for _, s := range solvers {
+		c := newChallengeController(groupName, options.ClusterResourceNamespace, s, informer.Lister())
+		defer c.queue.ShutDown()
+
+		if _, err := informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    c.enqueue,
+			UpdateFunc: func(_, obj interface{}) { c.enqueue(obj) },
+			DeleteFunc: func(obj interface{}) {
+				c.rememberDeleted(obj)
+				c.enqueue(obj)
+			},
+		}); err != nil {
+			return fmt.Errorf("failed to register event handler: %w", err)
+		}
+
+		// Only the leader processes Challenges when several replicas are
+		// running, so each is presented and cleaned up once. The solver
+		// starts the worker with its own background jobs.
+		s.AddBackgroundJob(solver.BackgroundJob{
+			Name: "challenge-controller",
+			Run: func(ctx context.Context) {
+				for ctx.Err() == nil && c.processNextItem(ctx) {
+				}
+			},
+		})
+
+		if err := s.Initialize(restConfig, ctx.Done()); err != nil {
+			return fmt.Errorf("failed to initialize solver %s: %w", s.Name(), err)
+		}
+	}
+
+	factory.Start(ctx.Done())
+	for typ, ok := range factory.WaitForCacheSync(ctx.Done()) {
+		if !ok {
+			return fmt.Errorf("failed to sync informer cache for %v", typ)
+		}
+	}
+
+	for _, s := range solvers {
+		slog.Info("Watching Challenge resources", "group", groupName, "solver", s.Name())
+	}
+
+	<-ctx.Done()
+	return nil
+}
+
+func loadRestConfig() (*rest.Config, error) {
+	var (
+		cfg *rest.Config
+		err error
+	)
+	if kubeconfig := os.Getenv("KUBECONFIG"); kubeconfig != "" {
+		cfg, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
+	} else {
+		cfg, err = rest.InClusterConfig()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return withClientRateLimits(cfg), nil
+}
+
+// withClientRateLimits applies the configured client-side rate limits for
+// API server requests. Large clusters with strict API priority and fairness
+// settings may need to lower them, busy webhooks to raise them.
+func withClientRateLimits(cfg *rest.Config) *rest.Config {
+	cfg = rest.CopyConfig(cfg)
+	if options.KubeAPIQPS > 0 {
+		cfg.QPS = options.KubeAPIQPS
+	}
+	if options.KubeAPIBurst > 0 {
+		cfg.Burst = options.KubeAPIBurst
+	}
+	return cfg
+}
+
+func (c *challengeController) enqueue(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		slog.Error("failed to compute key for challenge", "error", err)
+		return
+	}
+	c.queue.Add(key)
+}
+
+func (c *challengeController) processNextItem(ctx context.Context) bool {
+	key, shutdown := c.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	if err := c.sync(ctx, key); err != nil {
+		slog.Error("failed to sync challenge", "challenge", key, "error", err)
+		c.queue.AddRateLimited(key)
+		return true
+	}
+
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *challengeController) sync(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+
+	ch, err := c.lister.Challenges(namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		return c.syncDeleted(ctx, key)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !c.handles(ch) {
+		return nil
+	}
+
+	if ch.DeletionTimestamp != nil || isFinalState(ch.Status.State) {
+		return c.cleanUp(ctx, ch)
+	}
+
+	c.mu.Lock()
+	done := c.presented[ch.UID]
+	c.mu.Unlock()
+	if done {
+		return nil
+	}
+
+	req, err := c.challengeRequest(ctx, ch)
+	if err != nil {
+		return err
+	}
+
+	req.Action = v1alpha1.ChallengeActionPresent
+	if err := c.solver.Present(req); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.presented[ch.UID] = true
+	c.mu.Unlock()
+	return nil
+}
+
+// cleanUp deletes the record of ch unless this process already did. The
+// record may have been presented by a previous leader, so it is cleaned up
+// whether or not this process presented it; CleanUp tolerates records
+// that don't exist.
+func (c *challengeController) cleanUp(ctx context.Context, ch *cmacme.Challenge) error {
+	c.mu.Lock()
+	done := c.cleanedUp[ch.UID]
+	c.mu.Unlock()
+	if done {
+		return nil
+	}
+
+	req, err := c.challengeRequest(ctx, ch)
+	if err != nil {
+		return err
+	}
+
+	req.Action = v1alpha1.ChallengeActionCleanUp
+	if err := c.solver.CleanUp(req); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.cleanedUp[ch.UID] = true
+	delete(c.presented, ch.UID)
+	c.mu.Unlock()
+	return nil
+}
+
+// syncDeleted cleans up after the Challenge with key, which is no longer in
+// the informer cache, from the last state seen of it.
+func (c *challengeController) syncDeleted(ctx context.Context, key string) error {
+	c.mu.Lock()
+	ch, ok := c.deleted[key]
+	c.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	if err := c.cleanUp(ctx, ch); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	if c.deleted[key] == ch {
+		delete(c.deleted, key)
+	}
+	delete(c.cleanedUp, ch.UID)
+	c.mu.Unlock()
+	return nil
+}
+
+// rememberDeleted keeps the last state of a deleted Challenge handled by
+// this controller for syncDeleted.
+func (c *challengeController) rememberDeleted(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	ch, ok := obj.(*cmacme.Challenge)
+	if !ok || !c.handles(ch) {
+		return
+	}
+	key, err := cache.MetaNamespaceKeyFunc(ch)
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	c.deleted[key] = ch
+	c.mu.Unlock()
+}
+
+func (c *challengeController) handles(ch *cmacme.Challenge) bool {
+	if ch.Spec.Type != cmacme.ACMEChallengeTypeDNS01 {
+		return false
+	}
+	if ch.Spec.Solver.DNS01 == nil || ch.Spec.Solver.DNS01.Webhook == nil {
+		return false
+	}
+	wh := ch.Spec.Solver.DNS01.Webhook
+	return wh.GroupName == c.groupName && wh.SolverName == c.solver.Name()
+}
+
+func isFinalState(s cmacme.State) bool {
+	switch s {
+	case cmacme.Valid, cmacme.Invalid, cmacme.Errored, cmacme.Expired:
+		return true
+	}
+	return false
+}
+
+// challengeRequest builds the request cert-manager would have sent to the
+// aggregated API for ch, resolving the zone the same way cert-manager does.
+func (c *challengeController) challengeRequest(ctx context.Context, ch *cmacme.Challenge) (*v1alpha1.ChallengeRequest, error) {
+	fqdn := fmt.Sprintf("_acme-challenge.%s.", ch.Spec.DNSName)
+
+	zone, err := c.findZone(ctx, fqdn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve zone for %s: %w", fqdn, err)
+	}
+
+	namespace, allowAmbient := c.issuerScope(ch)
+	return &v1alpha1.ChallengeRequest{
+		UID:                     ch.UID,
+		Type:                    string(ch.Spec.Type),
+		DNSName:                 ch.Spec.DNSName,
+		Key:                     ch.Spec.Key,
+		ResourceNamespace:       namespace,
+		AllowAmbientCredentials: allowAmbient,
+		ResolvedFQDN:            fqdn,
+		ResolvedZone:            zone,
+		Config:                  ch.Spec.Solver.DNS01.Webhook.Config,
+	}, nil
+}
+
+// issuerScope returns the resource namespace and whether ambient
+// credentials are allowed for ch, mirroring what cert-manager does with its
+// default flags: ClusterIssuers use the cluster resource namespace and may
+// use ambient credentials, Issuers use their own namespace and may not.
+func (c *challengeController) issuerScope(ch *cmacme.Challenge) (string, bool) {
+	if ch.Spec.IssuerRef.Kind == cmapi.ClusterIssuerKind {
+		return c.clusterResourceNamespace, true
+	}
+	return ch.Namespace, false
+}
+
+// findZoneByFqdn resolves the zone of fqdn through the recursive
+// nameservers cert-manager uses.
+func findZoneByFqdn(ctx context.Context, fqdn string) (string, error) {
+	return util.FindZoneByFqdn(ctx, fqdn, util.RecursiveNameservers)
+}
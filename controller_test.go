@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	cmacme "github.com/cert-manager/cert-manager/pkg/apis/acme/v1"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	cmlisters "github.com/cert-manager/cert-manager/pkg/client/listers/acme/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+// recordingSolver records the requests it is called with.
+type recordingSolver struct {
+	presented []*v1alpha1.ChallengeRequest
+	cleanedUp []*v1alpha1.ChallengeRequest
+}
+
+func (s *recordingSolver) Name() string { return "bunny-net" }
+
+func (s *recordingSolver) Present(ch *v1alpha1.ChallengeRequest) error {
+	s.presented = append(s.presented, ch)
+	return nil
+}
+
+func (s *recordingSolver) CleanUp(ch *v1alpha1.ChallengeRequest) error {
+	s.cleanedUp = append(s.cleanedUp, ch)
+	return nil
+}
+
+func (s *recordingSolver) Initialize(*rest.Config, <-chan struct{}) error { return nil }
+
+func newTestController(t *testing.T, challenges ...*cmacme.Challenge) (*challengeController, cache.Indexer, *recordingSolver) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, ch := range challenges {
+		require.NoError(t, indexer.Add(ch))
+	}
+	s := &recordingSolver{}
+	c := newChallengeController("acme.example.com", "cert-manager", s, cmlisters.NewChallengeLister(indexer))
+	c.findZone = func(ctx context.Context, fqdn string) (string, error) { return "example.com.", nil }
+	t.Cleanup(c.queue.ShutDown)
+	return c, indexer, s
+}
+
+func testChallenge(state cmacme.State) *cmacme.Challenge {
+	return &cmacme.Challenge{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "www", UID: "uid-1"},
+		Spec: cmacme.ChallengeSpec{
+			Type:    cmacme.ACMEChallengeTypeDNS01,
+			DNSName: "www.example.com",
+			Key:     "token",
+			Solver: cmacme.ACMEChallengeSolver{
+				DNS01: &cmacme.ACMEChallengeSolverDNS01{
+					Webhook: &cmacme.ACMEIssuerDNS01ProviderWebhook{
+						GroupName:  "acme.example.com",
+						SolverName: "bunny-net",
+						Config:     &apiextensionsv1.JSON{Raw: []byte(`{"ttl":60}`)},
+					},
+				},
+			},
+		},
+		Status: cmacme.ChallengeStatus{State: state},
+	}
+}
+
+func TestChallengeController_PresentsOnce(t *testing.T) {
+	c, _, s := newTestController(t, testChallenge(cmacme.Pending))
+
+	require.NoError(t, c.sync(context.Background(), "team-a/www"))
+	require.NoError(t, c.sync(context.Background(), "team-a/www"))
+
+	require.Len(t, s.presented, 1, "resyncs don't present again")
+	assert.Equal(t, &v1alpha1.ChallengeRequest{
+		UID:               "uid-1",
+		Action:            v1alpha1.ChallengeActionPresent,
+		Type:              "DNS-01",
+		DNSName:           "www.example.com",
+		Key:               "token",
+		ResourceNamespace: "team-a",
+		ResolvedFQDN:      "_acme-challenge.www.example.com.",
+		ResolvedZone:      "example.com.",
+		Config:            &apiextensionsv1.JSON{Raw: []byte(`{"ttl":60}`)},
+	}, s.presented[0])
+	assert.Empty(t, s.cleanedUp)
+}
+
+func TestChallengeController_IssuerScope(t *testing.T) {
+	issuer := testChallenge(cmacme.Pending)
+	issuer.Spec.IssuerRef = cmmeta.ObjectReference{Name: "letsencrypt", Kind: cmapi.IssuerKind}
+	clusterIssuer := testChallenge(cmacme.Pending)
+	clusterIssuer.Name, clusterIssuer.UID = "cluster", "uid-2"
+	clusterIssuer.Spec.IssuerRef = cmmeta.ObjectReference{Name: "letsencrypt", Kind: cmapi.ClusterIssuerKind}
+	c, _, s := newTestController(t, issuer, clusterIssuer)
+
+	require.NoError(t, c.sync(context.Background(), "team-a/www"))
+	require.NoError(t, c.sync(context.Background(), "team-a/cluster"))
+
+	require.Len(t, s.presented, 2)
+	assert.Equal(t, "team-a", s.presented[0].ResourceNamespace)
+	assert.False(t, s.presented[0].AllowAmbientCredentials)
+	assert.Equal(t, "cert-manager", s.presented[1].ResourceNamespace)
+	assert.True(t, s.presented[1].AllowAmbientCredentials)
+}
+
+func TestChallengeController_CleansUpAfterRestart(t *testing.T) {
+	// A fresh controller never presented the Challenge, as after a restart
+	// or a change of leader.
+	c, _, s := newTestController(t, testChallenge(cmacme.Valid))
+
+	require.NoError(t, c.sync(context.Background(), "team-a/www"))
+	require.NoError(t, c.sync(context.Background(), "team-a/www"))
+
+	assert.Empty(t, s.presented)
+	require.Len(t, s.cleanedUp, 1, "resyncs don't clean up again")
+	assert.Equal(t, v1alpha1.ChallengeActionCleanUp, s.cleanedUp[0].Action)
+	assert.Equal(t, "_acme-challenge.www.example.com.", s.cleanedUp[0].ResolvedFQDN)
+	assert.Equal(t, "token", s.cleanedUp[0].Key)
+}
+
+func TestChallengeController_CleansUpDeleted(t *testing.T) {
+	ch := testChallenge(cmacme.Pending)
+	c, indexer, s := newTestController(t, ch)
+	require.NoError(t, c.sync(context.Background(), "team-a/www"))
+
+	require.NoError(t, indexer.Delete(ch))
+	c.rememberDeleted(cache.DeletedFinalStateUnknown{Key: "team-a/www", Obj: ch})
+	require.NoError(t, c.sync(context.Background(), "team-a/www"))
+	require.NoError(t, c.sync(context.Background(), "team-a/www"))
+
+	require.Len(t, s.cleanedUp, 1)
+	assert.Equal(t, "token", s.cleanedUp[0].Key)
+	assert.Empty(t, c.deleted)
+	assert.Empty(t, c.presented)
+	assert.Empty(t, c.cleanedUp)
+}
+
+func TestChallengeController_IgnoresOtherSolvers(t *testing.T) {
+	other := testChallenge(cmacme.Pending)
+	other.Spec.Solver.DNS01.Webhook.SolverName = "other"
+	http01 := testChallenge(cmacme.Pending)
+	http01.Name, http01.Spec.Type = "http", cmacme.ACMEChallengeTypeHTTP01
+	c, _, s := newTestController(t, other, http01)
+
+	require.NoError(t, c.sync(context.Background(), "team-a/www"))
+	require.NoError(t, c.sync(context.Background(), "team-a/http"))
+	require.NoError(t, c.sync(context.Background(), "team-a/missing"))
+
+	assert.Empty(t, s.presented)
+	assert.Empty(t, s.cleanedUp)
+}
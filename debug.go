@@ -0,0 +1,90 @@
+package main
+
+import (
+	"crypto/subtle"
+	_ "embed"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/cert-manager/webhook-example/pkg/solver"
+)
+
+// openAPIDocument describes the endpoints served by newDebugMux.
+//
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// newDebugMux returns the mux of the debug server. The admin and inventory
+// endpoints use the webhook-wide key of s.
+func newDebugMux(s *solver.Solver) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(openAPIDocument)
+	})
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/readyz", readyzHandler)
+	if options.EnableProfiling {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+	if options.AdminToken != "" && options.hasAPIKey() {
+		mux.Handle("/admin/", requireBearerToken(options.AdminToken, solver.NewAdminHandler(s)))
+	}
+	if options.InventoryToken != "" && options.hasAPIKey() {
+		mux.Handle("/inventory", requireBearerToken(options.InventoryToken, solver.NewInventoryHandler(s)))
+	}
+	return mux
+}
+
+// requireBearerToken only passes requests carrying token as a bearer token
+// on to next.
+func requireBearerToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// startDebugServer serves the metrics and health endpoints on addr, along
+// with the pprof endpoints if enabled and the admin endpoints with an admin
+// token.
+// They are kept off the aggregated API port so they can be scraped from
+// inside the cluster without going through the apiserver. An addr of "0"
+// disables the listener.
+func startDebugServer(addr string, s *solver.Solver) {
+	if addr == "" || addr == "0" {
+		return
+	}
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           newDebugMux(s),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		slog.Info("Serving metrics and health endpoints", "addr", addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("metrics server failed", "error", err)
+		}
+	}()
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cert-manager/webhook-example/pkg/solver"
+)
+
+func TestRequireBearerToken(t *testing.T) {
+	h := requireBearerToken("s3cret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	for header, want := range map[string]int{
+		"":              http.StatusUnauthorized,
+		"Bearer wrong":  http.StatusUnauthorized,
+		"s3cret":        http.StatusUnauthorized,
+		"Bearer s3cret": http.StatusTeapot,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/admin/records", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.Equal(t, want, rec.Code, header)
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	defer func(o Options) { options = o }(options)
+	options = Options{AdminToken: "s3cret", InventoryToken: "s3cret", APIKey: "key", EnableProfiling: true}
+	mux := newDebugMux(solver.New(solver.Options{APIKey: "key"}))
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var doc struct {
+		Paths map[string]map[string]interface{} `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+	require.NotEmpty(t, doc.Paths)
+
+	// Every documented path must be routed by the mux.
+	for path := range doc.Paths {
+		_, pattern := mux.Handler(httptest.NewRequest(http.MethodGet, path, nil))
+		assert.NotEmpty(t, pattern, path)
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newDebugMux(nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "bunny_webhook_operations_in_flight")
+	assert.Contains(t, rec.Body.String(), "bunny_webhook_api_requests_in_flight")
+}
+
+func TestProfilingEndpoints(t *testing.T) {
+	defer func(o Options) { options = o }(options)
+
+	for enabled, want := range map[bool]int{false: http.StatusNotFound, true: http.StatusOK} {
+		options = Options{EnableProfiling: enabled}
+		rec := httptest.NewRecorder()
+		newDebugMux(nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+		assert.Equal(t, want, rec.Code, "enableProfiling=%v", enabled)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/cert-manager/webhook-example/pkg/solver"
+)
+
+// startExternalDNSServer serves the external-dns webhook provider API on
+// addr with the webhook-wide key of s. external-dns expects it on
+// localhost:8888, so external-dns is run as a sidecar of the webhook. The API can change any record of the served
+// zones, so it requires the external-dns token if one is set.
+func startExternalDNSServer(addr string, s *solver.Solver) {
+	var handler http.Handler = solver.NewExternalDNSHandler(s, options.ExternalDNSDomainFilter)
+	if options.ExternalDNSToken != "" {
+		handler = requireBearerToken(options.ExternalDNSToken, handler)
+	}
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		slog.Info("Serving the external-dns webhook provider API", "addr", addr, "domainFilter", options.ExternalDNSDomainFilter)
+		if err := srv.ListenAndServe(); err != nil {
+			slog.Error("external-dns server failed", "error", err)
+		}
+	}()
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// flagAliases are extra flag names for options, kept short for the options
+// set most often on the command line.
+var flagAliases = map[string]string{
+	"timeout": "OPERATION_TIMEOUT",
+}
+
+// flagName returns the command-line flag of an environment variable, e.g.
+// --group-name for GROUP_NAME.
+func flagName(env string) string {
+	return strings.ToLower(strings.ReplaceAll(env, "_", "-"))
+}
+
+// optionValue sets an option through its environment variable parser.
+type optionValue struct {
+	opts   *Options
+	option envOption
+	value  string
+}
+
+func (v *optionValue) String() string { return v.value }
+
+func (v *optionValue) Set(s string) error {
+	if err := v.option.set(v.opts, s); err != nil {
+		return err
+	}
+	v.value = s
+	return nil
+}
+
+func (v *optionValue) Type() string {
+	if boolOptions[v.option.name] {
+		return "bool"
+	}
+	return "string"
+}
+
+// newFlagSet returns a flag for every option settable from the environment.
+// Flags set opts when parsed.
+func newFlagSet(opts *Options) *pflag.FlagSet {
+	fs := pflag.NewFlagSet("webhook", pflag.ContinueOnError)
+	fs.SortFlags = true
+	fs.String(configFileFlag, "", "Path of the YAML config file. Same as CONFIG_FILE.")
+	add := func(name string, e envOption, usage string) {
+		f := fs.VarPF(&optionValue{opts: opts, option: e}, name, "", usage)
+		if boolOptions[e.name] {
+			f.NoOptDefVal = "true"
+		}
+	}
+	for _, e := range envOptions {
+		add(flagName(e.name), e, fmt.Sprintf("Same as %s.", e.name))
+	}
+	for alias, env := range flagAliases {
+		for _, e := range envOptions {
+			if e.name == env {
+				add(alias, e, fmt.Sprintf("Alias of --%s.", flagName(env)))
+			}
+		}
+	}
+	return fs
+}
+
+// applyFlags sets opts from the flags in args naming options and returns
+// the other arguments, which belong to the webhook server.
+func applyFlags(opts *Options, args []string) ([]string, error) {
+	fs := newFlagSet(opts)
+	var own, rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		dashes := len(arg) - len(strings.TrimLeft(arg, "-"))
+		f := fs.Lookup(name)
+		if dashes == 0 || dashes > 2 || f == nil || name == configFileFlag {
+			rest = append(rest, arg)
+			continue
+		}
+		switch {
+		case hasValue:
+			own = append(own, "--"+name+"="+value)
+		case f.NoOptDefVal != "":
+			own = append(own, "--"+name)
+		case i+1 < len(args):
+			own = append(own, "--"+name+"="+args[i+1])
+			i++
+		default:
+			return nil, fmt.Errorf("flag needs an argument: --%s", name)
+		}
+	}
+	if err := fs.Parse(own); err != nil {
+		return nil, err
+	}
+	return rest, nil
+}
+
+// helpRequested reports whether args ask for usage information.
+func helpRequested(args []string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if arg == "--help" || arg == "-h" || arg == "-help" {
+			return true
+		}
+	}
+	return false
+}
+
+// printUsage lists the flags of the webhook.
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: webhook [flags] [server flags]\n\nFlags:\n%s", newFlagSet(&Options{}).FlagUsages())
+	fmt.Fprintf(w, "\nEvery flag falls back to the environment variable it names, then to the config file.\n"+
+		"Other flags, such as --secure-port and --tls-cert-file, are passed to the webhook server.\n")
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook"
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/cmd/server"
+	logf "github.com/cert-manager/cert-manager/pkg/logs"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/cert-manager/webhook-example/pkg/solver"
+)
+
+// GroupOptions configures one aggregated API group served by the process.
+// Each group gets its own API server on SecurePort, so each needs its own
+// APIService and Service pointing at that port.
+type GroupOptions struct {
+	GroupName  string `json:"groupName"`
+	SecurePort int    `json:"securePort"`
+}
+
+func validateGroups(groups []GroupOptions) error {
+	names := make(map[string]bool, len(groups))
+	ports := make(map[int]bool, len(groups))
+	for i, g := range groups {
+		if g.GroupName == "" {
+			return fmt.Errorf("groups[%d].groupName must be set", i)
+		}
+		if g.SecurePort <= 0 || g.SecurePort > 65535 {
+			return fmt.Errorf("groups[%d].securePort must be a valid port", i)
+		}
+		if names[g.GroupName] {
+			return fmt.Errorf("groups[%d].groupName %q is listed more than once", i, g.GroupName)
+		}
+		if ports[g.SecurePort] {
+			return fmt.Errorf("groups[%d].securePort %d is used by another group", i, g.SecurePort)
+		}
+		names[g.GroupName] = true
+		ports[g.SecurePort] = true
+	}
+	return nil
+}
+
+// runWebhookServer serves solvers under groupName like
+// cmd.RunWebhookServer, but returns once the server stopped instead of
+// exiting the process, so in-flight challenges can be drained afterwards.
+// args are the remaining command-line arguments for the server.
+func runWebhookServer(groupName string, args []string, solvers []*solver.Solver) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	logf.InitLogs()
+	defer logf.FlushLogs()
+
+	cmd := server.NewCommandStartWebhookServer(ctx, groupName, webhookSolvers(solvers)...)
+	cmd.Flags().AddGoFlagSet(flag.CommandLine)
+	cmd.SetArgs(args)
+	if err := cmd.ExecuteContext(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	return nil
+}
+
+// runWebhookServers starts one webhook API server per group instead of the
+// single server runWebhookServer would start. args are the remaining
+// command-line arguments (TLS files etc.) shared by every server; only the
+// secure port differs. The servers share solvers, so informers, rate
+// limits, leader election and the cleanup queue exist once per process.
+func runWebhookServers(groups []GroupOptions, args []string, solvers []*solver.Solver) error {
+	if err := validateGroups(groups); err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	logf.InitLogs()
+	defer logf.FlushLogs()
+
+	hooks := webhookSolvers(solvers)
+	g, ctx := errgroup.WithContext(ctx)
+	for _, group := range groups {
+		cmd := server.NewCommandStartWebhookServer(ctx, group.GroupName, hooks...)
+		cmd.Flags().AddGoFlagSet(flag.CommandLine)
+		cmd.SetArgs(append(append([]string{}, args...), fmt.Sprintf("--secure-port=%d", group.SecurePort)))
+
+		groupName := group.GroupName
+		g.Go(func() error {
+			if err := cmd.ExecuteContext(ctx); err != nil {
+				return fmt.Errorf("webhook server for group %s failed: %w", groupName, err)
+			}
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	return nil
+}
+
+// webhookSolvers returns solvers as the solvers of a webhook server.
+func webhookSolvers(solvers []*solver.Solver) []webhook.Solver {
+	hooks := make([]webhook.Solver, len(solvers))
+	for i, s := range solvers {
+		hooks[i] = s
+	}
+	return hooks
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateGroups(t *testing.T) {
+	assert.NoError(t, validateGroups([]GroupOptions{
+		{GroupName: "a.example.com", SecurePort: 8443},
+		{GroupName: "b.example.com", SecurePort: 8444},
+	}))
+
+	for _, tc := range []struct {
+		groups []GroupOptions
+		err    string
+	}{
+		{[]GroupOptions{{SecurePort: 8443}}, "groups[0].groupName must be set"},
+		{[]GroupOptions{{GroupName: "a.example.com"}}, "groups[0].securePort must be a valid port"},
+		{[]GroupOptions{{GroupName: "a.example.com", SecurePort: 70000}}, "groups[0].securePort must be a valid port"},
+		{
+			[]GroupOptions{{GroupName: "a.example.com", SecurePort: 8443}, {GroupName: "a.example.com", SecurePort: 8444}},
+			`groups[1].groupName "a.example.com" is listed more than once`,
+		},
+		{
+			[]GroupOptions{{GroupName: "a.example.com", SecurePort: 8443}, {GroupName: "b.example.com", SecurePort: 8443}},
+			"groups[1].securePort 8443 is used by another group",
+		},
+	} {
+		assert.EqualError(t, validateGroups(tc.groups), tc.err)
+	}
+}
+
+func TestLoadOptions_Groups(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(`groups:
+- groupName: a.example.com
+  securePort: 8443
+- groupName: b.example.com
+  securePort: 8444
+`), 0o600))
+
+	opts, _, err := loadOptions([]string{"--config", file}, envFrom(nil))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.example.com", "b.example.com"}, opts.servedGroups())
+
+	// The config check accepts groups in place of groupName, and reports
+	// invalid ones.
+	defer func(saved Options) { options = saved }(options)
+	options = opts
+	assert.NoError(t, checkConfig(context.Background()))
+	options.Groups[1].SecurePort = 8443
+	assert.ErrorContains(t, checkConfig(context.Background()), "is used by another group")
+}
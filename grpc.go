@@ -0,0 +1,62 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"net"
+	"os"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/cert-manager/webhook-example/pkg/solver"
+)
+
+// startGRPCServer serves the gRPC solver API for s on addr, over TLS with
+// verified client certificates.
+func startGRPCServer(addr string, s *solver.Solver) {
+	tlsConfig, err := grpcTLSConfig(options.GRPCCertFile, options.GRPCKeyFile, options.GRPCClientCAFile)
+	if err != nil {
+		log.Fatalf("failed to configure gRPC TLS: %v", err)
+	}
+
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatalf("failed to listen for gRPC on %s: %v", addr, err)
+	}
+
+	srv := solver.NewGRPCServer(s, options.GRPCAllowedNamespaces, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	go func() {
+		slog.Info("Serving the gRPC solver API", "addr", addr, "allowedNamespaces", options.GRPCAllowedNamespaces)
+		if err := srv.Serve(lis); err != nil {
+			slog.Error("gRPC server failed", "error", err)
+		}
+	}()
+}
+
+// grpcTLSConfig returns the TLS config of the gRPC server, which requires
+// clients to present a certificate signed by a CA in clientCAFile.
+func grpcTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate: %w", err)
+	}
+	caPEM, err := os.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA: %w", err)
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(caPEM) {
+		return nil, errors.New("no certificates found in client CA file " + clientCAFile)
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cert-manager/webhook-example/pkg/solver"
+)
+
+const (
+	statusOK    = "ok"
+	statusError = "error"
+
+	healthCheckTimeout = 10 * time.Second
+
+	// bunnyAPICheckTTL is how long the result of the Bunny API check is
+	// reused. The readiness probe runs every few seconds; the API key is
+	// still checked often enough to catch a revocation within a minute.
+	bunnyAPICheckTTL = 30 * time.Second
+)
+
+type healthCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+type checkResult struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+type healthReport struct {
+	Status string        `json:"status"`
+	Time   time.Time     `json:"time"`
+	Checks []checkResult `json:"checks"`
+}
+
+// readinessChecks are shared by /readyz and the selfcheck subcommand so both
+// report the same view of the webhook's health.
+func readinessChecks() []healthCheck {
+	return []healthCheck{
+		{name: "config", check: checkConfig},
+		{name: "bunny-api", check: bunnyAPICheck},
+	}
+}
+
+// podReadinessChecks gate the pod's readiness during rollouts: the solver
+// has to be initialized, the credentials validated and, if enabled, the
+// APIService reachable before the pod receives challenges.
+func podReadinessChecks() []healthCheck {
+	checks := append([]healthCheck{{name: "solver-initialized", check: checkSolverInitialized}}, readinessChecks()...)
+	return append(checks, healthCheck{name: "apiservice", check: checkAPIService})
+}
+
+// checkSolverInitialized fails until every solver's informers have synced,
+// and again once shutdown began.
+// The aggregated API server starts serving TLS before Initialize completes,
+// so readiness is what holds traffic back from a replica that can't yet
+// resolve credentials.
+func checkSolverInitialized(_ context.Context) error {
+	solvers := registeredSolvers()
+	if len(solvers) == 0 {
+		return errors.New("solver is not initialized yet")
+	}
+	for _, s := range solvers {
+		if !s.Initialized() {
+			return errors.New("solver is not initialized yet")
+		}
+		if s.ShuttingDown() {
+			return errors.New("solver is shutting down")
+		}
+	}
+	return nil
+}
+
+func checkAPIService(ctx context.Context) error {
+	for _, s := range registeredSolvers() {
+		if err := s.CheckAPIService(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runChecks(ctx context.Context, checks []healthCheck) healthReport {
+	report := healthReport{Status: statusOK, Time: time.Now().UTC()}
+	for _, c := range checks {
+		start := time.Now()
+		err := c.check(ctx)
+		res := checkResult{
+			Name:     c.name,
+			Status:   statusOK,
+			Duration: time.Since(start).String(),
+		}
+		if err != nil {
+			res.Status = statusError
+			res.Error = err.Error()
+			report.Status = statusError
+		}
+		report.Checks = append(report.Checks, res)
+	}
+	return report
+}
+
+func checkConfig(_ context.Context) error {
+	if len(options.Groups) > 0 {
+		return validateGroups(options.Groups)
+	}
+	if options.GroupName == "" {
+		return errors.New(errMissingGroupName)
+	}
+	return nil
+}
+
+var bunnyAPICheck = cachedCheck(bunnyAPICheckTTL, checkBunnyAPI)
+
+// checkBunnyAPI verifies the webhook-wide API keys of every solver with an
+// authenticated call, so a revoked key or blocked egress marks the pod not
+// ready.
+func checkBunnyAPI(ctx context.Context) error {
+	if err := checkMainAPIKey(ctx); err != nil {
+		return err
+	}
+	// The additional solvers have keys and API endpoints of their own.
+	if solvers := registeredSolvers(); len(solvers) > 1 {
+		for _, s := range solvers[1:] {
+			if err := s.CheckAPI(ctx); err != nil {
+				return fmt.Errorf("solver %s: %w", s.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkMainAPIKey verifies the keys of the main solver. Without a key the
+// credentials come from each Issuer and there is nothing to verify up
+// front. A fallback key still accepted by Bunny keeps the pod ready while
+// the primary key is rotated.
+func checkMainAPIKey(ctx context.Context) error {
+	apiKey := apiKeys.APIKey()
+	if apiKey == "" {
+		return nil
+	}
+	err := solver.CheckAPIKey(ctx, apiKey, options.AuthScheme)
+	for _, key := range options.FallbackAPIKeys {
+		if err == nil {
+			break
+		}
+		if solver.CheckAPIKey(ctx, key, options.AuthScheme) == nil {
+			err = nil
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if options.SecondaryAPIKey != "" {
+		if err := solver.CheckAPIKey(ctx, options.SecondaryAPIKey, options.AuthScheme); err != nil {
+			return fmt.Errorf("secondary API key: %w", err)
+		}
+	}
+	return nil
+}
+
+// cachedCheck reuses the result of check for ttl, so frequent probes don't
+// turn into a steady stream of Bunny API calls.
+func cachedCheck(ttl time.Duration, check func(ctx context.Context) error) func(ctx context.Context) error {
+	var (
+		mu      sync.Mutex
+		checked time.Time
+		lastErr error
+	)
+	return func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if !checked.IsZero() && time.Since(checked) < ttl {
+			return lastErr
+		}
+		lastErr = check(ctx)
+		checked = time.Now()
+		return lastErr
+	}
+}
+
+func readyzHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+	defer cancel()
+
+	report := runChecks(ctx, podReadinessChecks())
+
+	w.Header().Set("Content-Type", "application/json")
+	if report.Status != statusOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// runSelfCheck runs the readiness checks from the command line and prints
+// the report as JSON. It returns the process exit code.
+func runSelfCheck(out io.Writer) int {
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+
+	report := runChecks(ctx, readinessChecks())
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to encode report: %v\n", err)
+		return 1
+	}
+	if report.Status != statusOK {
+		return 1
+	}
+	return 0
+}
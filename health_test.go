@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cert-manager/webhook-example/pkg/bunny/bunnytest"
+	"github.com/cert-manager/webhook-example/pkg/solver"
+)
+
+func TestRunChecks(t *testing.T) {
+	report := runChecks(context.Background(), []healthCheck{
+		{name: "passing", check: func(context.Context) error { return nil }},
+		{name: "failing", check: func(context.Context) error { return errors.New("boom") }},
+	})
+
+	assert.Equal(t, statusError, report.Status)
+	require.Len(t, report.Checks, 2)
+	assert.Equal(t, statusOK, report.Checks[0].Status)
+	assert.Equal(t, statusError, report.Checks[1].Status)
+	assert.Equal(t, "boom", report.Checks[1].Error)
+}
+
+func TestRunSelfCheck_MissingGroupName(t *testing.T) {
+	saved := options
+	t.Cleanup(func() { options = saved })
+	options = Options{}
+
+	var out bytes.Buffer
+	code := runSelfCheck(&out)
+	assert.Equal(t, 1, code)
+
+	var report healthReport
+	require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+	assert.Equal(t, statusError, report.Status)
+	assert.Equal(t, "config", report.Checks[0].Name)
+	assert.Equal(t, errMissingGroupName, report.Checks[0].Error)
+}
+
+func TestCachedCheck(t *testing.T) {
+	calls := 0
+	check := cachedCheck(time.Hour, func(context.Context) error {
+		calls++
+		return errors.New("revoked")
+	})
+
+	assert.EqualError(t, check(context.Background()), "revoked")
+	assert.EqualError(t, check(context.Background()), "revoked")
+	assert.Equal(t, 1, calls)
+}
+
+func TestCheckBunnyAPI(t *testing.T) {
+	api := bunnytest.NewServer()
+	defer api.Close()
+	api.RequireAPIKey("good")
+	require.NoError(t, solver.SetAPIBase(api.URL))
+	defer solver.SetAPIBase(solver.DefaultAPIBase)
+	savedOptions, savedKeys := options, apiKeys
+	t.Cleanup(func() { options, apiKeys = savedOptions, savedKeys })
+
+	for _, tc := range []struct {
+		name              string
+		apiKey, secondary string
+		fallbacks         []string
+		err               string
+	}{
+		{name: "no webhook-wide key"},
+		{name: "valid key", apiKey: "good"},
+		{name: "revoked key", apiKey: "revoked", err: "401"},
+		{name: "valid fallback key", apiKey: "revoked", fallbacks: []string{"old", "good"}},
+		{name: "revoked fallback keys", apiKey: "revoked", fallbacks: []string{"old"}, err: "401"},
+		{name: "revoked secondary key", apiKey: "good", secondary: "revoked", err: "secondary API key"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			apiKeys = solver.StaticAPIKey(tc.apiKey)
+			options = Options{FallbackAPIKeys: tc.fallbacks, SecondaryAPIKey: tc.secondary}
+			err := checkBunnyAPI(context.Background())
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestCheckBunnyAPI_Solvers(t *testing.T) {
+	staging := bunnytest.NewServer()
+	defer staging.Close()
+	staging.RequireAPIKey("staging")
+	savedOptions, savedKeys := options, apiKeys
+	solversMu.Lock()
+	savedSolvers := solvers
+	solversMu.Unlock()
+	t.Cleanup(func() {
+		options, apiKeys = savedOptions, savedKeys
+		solversMu.Lock()
+		solvers = savedSolvers
+		solversMu.Unlock()
+	})
+	options, apiKeys = Options{}, solver.StaticAPIKey("")
+
+	setSolvers := func(key string) {
+		solversMu.Lock()
+		defer solversMu.Unlock()
+		solvers = []*solver.Solver{
+			solver.New(solver.Options{}),
+			solver.New(solver.Options{Name: "bunny-net-staging", APIKey: key, APIBase: staging.URL}),
+		}
+	}
+	setSolvers("staging")
+	assert.NoError(t, checkBunnyAPI(context.Background()))
+	setSolvers("revoked")
+	assert.ErrorContains(t, checkBunnyAPI(context.Background()), "solver bunny-net-staging")
+}
+
+func TestReadyz_SolverNotInitialized(t *testing.T) {
+	savedOptions := options
+	solversMu.Lock()
+	savedSolvers := solvers
+	solvers = nil
+	solversMu.Unlock()
+	t.Cleanup(func() {
+		options = savedOptions
+		solversMu.Lock()
+		solvers = savedSolvers
+		solversMu.Unlock()
+	})
+	options = Options{GroupName: "acme.example.com"}
+
+	assert.EqualError(t, checkSolverInitialized(context.Background()), "solver is not initialized yet", "no solver created yet")
+
+	solversMu.Lock()
+	solvers = []*solver.Solver{solver.New(solver.Options{})}
+	solversMu.Unlock()
+	assert.EqualError(t, checkSolverInitialized(context.Background()), "solver is not initialized yet")
+
+	rec := httptest.NewRecorder()
+	readyzHandler(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	var report healthReport
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	require.NotEmpty(t, report.Checks)
+	assert.Equal(t, checkResult{Name: "solver-initialized", Status: statusError, Error: "solver is not initialized yet", Duration: report.Checks[0].Duration}, report.Checks[0])
+}
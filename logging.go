@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"github.com/cert-manager/webhook-example/pkg/solver"
+)
+
+// Log formats accepted by Options.LogFormat.
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+const (
+	logLevelFlag  = "log-level"
+	logFormatFlag = "log-format"
+
+	// klogDebugVerbosity is the -v level from which klog conventionally logs
+	// debug messages.
+	klogDebugVerbosity = 4
+)
+
+// configureLogging makes structured logs in the configured format and level
+// the default, for the standard log package as well. API keys are redacted
+// from every record. The pod identity from
+// the Downward API is attached to every record, so logs from several
+// replicas can be told apart once they are aggregated.
+func configureLogging(opts Options) {
+	slog.SetDefault(slog.New(newLogHandler(os.Stderr, opts)))
+}
+
+func newLogHandler(w io.Writer, opts Options) slog.Handler {
+	level, _ := parseLogLevel(opts.LogLevel)
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler
+	if opts.LogFormat == logFormatJSON {
+		h = slog.NewJSONHandler(w, handlerOpts)
+	} else {
+		h = slog.NewTextHandler(w, handlerOpts)
+	}
+	return solver.NewRedactingHandler(h.WithAttrs(podIdentityAttrs(opts)))
+}
+
+func podIdentityAttrs(opts Options) []slog.Attr {
+	var attrs []slog.Attr
+	for _, f := range []struct{ key, value string }{
+		{"pod", opts.PodName},
+		{"node", opts.NodeName},
+		{"namespace", opts.Namespace},
+	} {
+		if f.value != "" {
+			attrs = append(attrs, slog.String(f.key, f.value))
+		}
+	}
+	return attrs
+}
+
+// parseLogLevel parses debug, info, warn or error; empty is info.
+func parseLogLevel(v string) (slog.Level, error) {
+	var level slog.Level
+	if v == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := level.UnmarshalText([]byte(v)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q, must be debug, info, warn or error", v)
+	}
+	return level, nil
+}
+
+// validateLogging checks the log level and format of opts.
+func validateLogging(opts Options) error {
+	if _, err := parseLogLevel(opts.LogLevel); err != nil {
+		return err
+	}
+	switch opts.LogFormat {
+	case "", logFormatText, logFormatJSON:
+		return nil
+	default:
+		return fmt.Errorf("unknown log format %q, must be %q or %q", opts.LogFormat, logFormatText, logFormatJSON)
+	}
+}
+
+// applyLoggingFlags sets the log level and format from --log-level and
+// --log-format, which are removed from args, or from klog's -v, which is
+// left for the webhook server.
+func applyLoggingFlags(opts *Options, args []string) []string {
+	level, args := extractFlag(args, logLevelFlag)
+	format, args := extractFlag(args, logFormatFlag)
+	if level == "" {
+		if v, err := strconv.Atoi(peekFlag(args, "v")); err == nil && v >= klogDebugVerbosity {
+			level = "debug"
+		}
+	}
+	if level != "" {
+		opts.LogLevel = level
+	}
+	if format != "" {
+		opts.LogFormat = format
+	}
+	return args
+}
+
+// peekFlag returns the value of -name or --name in args without removing it.
+func peekFlag(args []string, name string) string {
+	value, _ := extractFlag(args, name)
+	return value
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPodIdentityAttrs(t *testing.T) {
+	assert.Empty(t, podIdentityAttrs(Options{}))
+	assert.Equal(t, []slog.Attr{slog.String("pod", "webhook-0"), slog.String("namespace", "cert-manager")}, podIdentityAttrs(Options{
+		PodName:   "webhook-0",
+		Namespace: "cert-manager",
+	}))
+	assert.Equal(t, []slog.Attr{slog.String("pod", "webhook-0"), slog.String("node", "node-a"), slog.String("namespace", "cert-manager")}, podIdentityAttrs(Options{
+		PodName:   "webhook-0",
+		NodeName:  "node-a",
+		Namespace: "cert-manager",
+	}))
+}
+
+func TestLogHandlerJSON(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(newLogHandler(&buf, Options{LogFormat: logFormatJSON, LogLevel: "warn", PodName: "webhook-0"}))
+	logger.Info("dropped")
+	logger.Warn("kept", "fqdn", "_acme-challenge.example.com.")
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "kept", record["msg"])
+	assert.Equal(t, "WARN", record["level"])
+	assert.Equal(t, "webhook-0", record["pod"])
+	assert.Equal(t, "_acme-challenge.example.com.", record["fqdn"])
+}
+
+func TestLoadOptions_Logging(t *testing.T) {
+	env := map[string]string{"LOG_LEVEL": "error", "LOG_FORMAT": "json"}
+	opts, args, err := loadOptions([]string{"--log-level=debug", "--secure-port=443"}, func(k string) string { return env[k] })
+	require.NoError(t, err)
+	assert.Equal(t, "debug", opts.LogLevel)
+	assert.Equal(t, logFormatJSON, opts.LogFormat)
+	assert.Equal(t, []string{"--secure-port=443"}, args)
+
+	// klog's -v is left for the webhook server.
+	opts, args, err = loadOptions([]string{"-v", "4"}, func(string) string { return "" })
+	require.NoError(t, err)
+	assert.Equal(t, "debug", opts.LogLevel)
+	assert.Equal(t, []string{"-v", "4"}, args)
+
+	_, _, err = loadOptions([]string{"--log-format", "xml"}, func(string) string { return "" })
+	assert.Error(t, err)
+	_, _, err = loadOptions(nil, func(k string) string { return map[string]string{"LOG_LEVEL": "loud"}[k] })
+	assert.Error(t, err)
+}
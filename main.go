@@ -1,234 +1,212 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
+	"log/slog"
 	"os"
-	"strings"
+	"sync"
 	"time"
 
-	"k8s.io/client-go/rest"
-
-	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
-	"github.com/cert-manager/cert-manager/pkg/acme/webhook/cmd"
+	"github.com/cert-manager/webhook-example/pkg/solver"
+	"github.com/cert-manager/webhook-example/pkg/version"
 )
 
-var GroupName = os.Getenv("GROUP_NAME")
-var ApiKey = os.Getenv("API_KEY")
+// options is the resolved process configuration, populated by main.
+var options Options
 
-const (
-	bunnyAPIBase = "https://api.bunny.net"
-	recordTTL    = 10
-	recordType   = 3 // TXT record type
+const errMissingGroupName = "GROUP_NAME must be specified"
 
-	errMissingGroupName = "GROUP_NAME must be specified"
-	errMissingAPIKey    = "API_KEY must be specified"
+var (
+	solversMu sync.Mutex
+	solvers   []*solver.Solver
 )
 
-var httpClient = &http.Client{
-	Timeout: 30 * time.Second,
+// newSolver creates the main solver from the process options.
+func newSolver() *solver.Solver {
+	return newSolverWith(options.solverOptions(), apiKeys)
 }
 
-func main() {
-	if GroupName == "" {
-		panic(errMissingGroupName)
-	}
-	if ApiKey == "" {
-		panic(errMissingAPIKey)
+// newSolvers creates the main solver followed by the additional solvers in
+// options.Solvers, to be served by the same API group.
+func newSolvers() []*solver.Solver {
+	hooks := []*solver.Solver{newSolver()}
+	for _, so := range options.Solvers {
+		keys, err := configureAPIKey(context.Background(), Options{APIKey: so.APIKey, APIKeyFile: so.APIKeyFile})
+		if err != nil {
+			log.Fatalf("failed to configure API key of solver %s: %v", so.Name, err)
+		}
+		hooks = append(hooks, newSolverWith(so.apply(options.solverOptions()), keys))
 	}
-
-	cmd.RunWebhookServer(GroupName,
-		&bunnyNetDNSSolver{},
-	)
-}
-
-type bunnyNetDNSSolver struct{}
-type bunnyNetDNSConfig struct {
-	APIKey string
-}
-
-func (c *bunnyNetDNSSolver) Name() string {
-	return "bunny-net"
+	return hooks
 }
 
-func (c *bunnyNetDNSSolver) Present(ch *v1alpha1.ChallengeRequest) error {
-	if ch == nil {
-		return fmt.Errorf("challenge request cannot be nil")
+// newSolverWith creates a solver with the webhook-wide key from keys and
+// registers it with the readiness checks. The first solver also serves the
+// gRPC API, if enabled.
+func newSolverWith(opts solver.Options, keys solver.APIKeyProvider) *solver.Solver {
+	if options.Mode != modeWebhook {
+		// Only the aggregated API server has an APIService to check.
+		opts.CheckAPIService = false
+	}
+	if options.ACMEDNSURL != "" {
+		fallback, err := solver.NewACMEDNS(options.ACMEDNSURL, options.ACMEDNSAccountsFile)
+		if err != nil {
+			log.Fatalf("failed to configure acme-dns fallback: %v", err)
+		}
+		opts.Fallback = fallback
 	}
-
-	cfg, err := loadConfig()
-	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+	opts.APIKeyProvider = keys
+	opts.Instrumentation = solverInstrumentation()
+	var notifiers solver.Notifiers
+	if options.NotifyWebhookURL != "" {
+		notifiers = append(notifiers, solver.WebhookNotifier{URL: options.NotifyWebhookURL})
 	}
-
-	zoneID, err := GetZoneID(ch.ResolvedZone, cfg)
-	if err != nil {
-		return fmt.Errorf("failed to get zone ID: %w", err)
-	}
-
-	url := fmt.Sprintf("%s/dnszone/%d/records", bunnyAPIBase, zoneID)
-
-	hostname := strings.TrimSuffix(ch.ResolvedFQDN, ch.ResolvedZone)
-	hostname = strings.TrimSuffix(hostname, ".")
-
-	record := Record{
-		Type:     recordType,
-		Ttl:      recordTTL,
-		Value:    ch.Key,
-		Name:     hostname,
-		Disabled: false,
+	if options.NotifySlackWebhookURL != "" {
+		notifiers = append(notifiers, solver.SlackNotifier{URL: options.NotifySlackWebhookURL})
 	}
-
-	payload, err := json.Marshal(record)
-	if err != nil {
-		return fmt.Errorf("failed to marshal record: %w", err)
+	if len(notifiers) > 0 {
+		opts.Notifier = notifiers
 	}
-
-	req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(payload))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+	if options.CloudEventsURL != "" {
+		opts.EventSink = solver.CloudEventsSink{URL: options.CloudEventsURL, Source: options.GroupName}
 	}
+	s := solver.New(opts)
 
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("AccessKey", ApiKey)
+	solversMu.Lock()
+	solvers = append(solvers, s)
+	first := len(solvers) == 1
+	solversMu.Unlock()
 
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+	if first && options.GRPCBindAddress != "" {
+		startGRPCServer(options.GRPCBindAddress, s)
 	}
-	defer resp.Body.Close()
+	return s
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read response: %w", err)
+// drainSolvers waits until the initialized solvers finished their in-flight
+// challenges after the stop signal, bounded by their shutdown grace period.
+func drainSolvers() {
+	for _, s := range registeredSolvers() {
+		if s.Initialized() {
+			<-s.Done()
+		}
 	}
+}
 
-	if resp.StatusCode >= 400 {
-		return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+// flushTraces exports the spans still buffered before the process exits.
+func flushTraces(shutdown func(context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdown(ctx); err != nil {
+		slog.Error("failed to flush traces", "error", err)
 	}
+}
 
-	log.Printf("Successfully created DNS record for %s", ch.ResolvedFQDN)
-	return nil
+// registeredSolvers returns the solvers created so far.
+func registeredSolvers() []*solver.Solver {
+	solversMu.Lock()
+	defer solversMu.Unlock()
+	return append([]*solver.Solver(nil), solvers...)
 }
 
-func GetZone(zone string, cfg bunnyNetDNSConfig) (ZoneResponse, error) {
-	if zone[len(zone)-1] == '.' {
-		zone = zone[:len(zone)-1]
+// printVersionRequested reports whether args ask for the version only, via
+// the version command or --version.
+func printVersionRequested(args []string) bool {
+	if len(args) > 0 && args[0] == "version" {
+		return true
 	}
-	url := fmt.Sprintf(`%s/dnszone?page=1&perPage=1&search=%s`, bunnyAPIBase, zone)
-
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return ZoneResponse{}, fmt.Errorf("failed to create request: %w", err)
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if arg == "--version" || arg == "-version" {
+			return true
+		}
 	}
+	return false
+}
 
-	req.Header.Add("Accept", "application/json")
-	req.Header.Add("AccessKey", cfg.APIKey)
-
-	res, err := httpClient.Do(req)
-	if err != nil {
-		return ZoneResponse{}, fmt.Errorf("failed to execute request: %w", err)
+func main() {
+	if printVersionRequested(os.Args[1:]) {
+		fmt.Println(version.Get())
+		return
+	}
+	if helpRequested(os.Args[1:]) {
+		printUsage(os.Stdout)
+		return
 	}
-	defer res.Body.Close()
 
-	body, err := io.ReadAll(res.Body)
+	opts, args, err := loadOptions(os.Args[1:], os.Getenv)
 	if err != nil {
-		return ZoneResponse{}, fmt.Errorf("failed to read response body: %w", err)
+		panic(err)
+	}
+	os.Args = append(os.Args[:1], args...)
+	options = opts
+	configureLogging(options)
+	v := version.Get()
+	slog.Info("Starting cert-manager-webhook-bunny", "version", v.Version, "commit", v.Commit, "buildDate", v.Date, "goVersion", v.GoVersion)
+	shutdownTracing, err := configureTracing(context.Background(), os.Getenv)
+	if err != nil {
+		log.Fatalf("failed to configure tracing: %v", err)
 	}
-
-	var data ZoneResponse
-	if err := json.Unmarshal(body, &data); err != nil {
-		return ZoneResponse{}, fmt.Errorf("failed to unmarshal response: %w", err)
+	if options.APIBase != "" {
+		if err := solver.SetAPIBase(options.APIBase); err != nil {
+			log.Fatalf("invalid apiBase: %v", err)
+		}
 	}
-
-	if len(data.Items) == 0 {
-		return ZoneResponse{}, fmt.Errorf("no DNS zone found for %s", zone)
+	if apiKeys, err = configureAPIKey(context.Background(), options); err != nil {
+		log.Fatalf("failed to configure API key: %v", err)
 	}
 
-	return data, nil
-}
-
-func GetZoneID(zone string, cfg bunnyNetDNSConfig) (int64, error) {
-	data, err := GetZone(zone, cfg)
-	if err != nil {
-		return 0, err
+	if len(args) > 0 && args[0] == "selfcheck" {
+		os.Exit(runSelfCheck(os.Stdout))
 	}
-	return int64(data.Items[0].ID), nil
-}
-
-func (c *bunnyNetDNSSolver) CleanUp(ch *v1alpha1.ChallengeRequest) error {
-	cfg, err := loadConfig()
-	if err != nil {
-		return err
+	if len(args) > 0 && args[0] == "plan" {
+		os.Exit(runPlan(args[1:], os.Stdout))
 	}
-
-	zoneData, err := GetZone(ch.ResolvedZone, cfg)
-	if err != nil {
-		return fmt.Errorf("failed to get zone ID: %w", err)
+	if len(args) > 0 && (args[0] == "present" || args[0] == "cleanup") {
+		os.Exit(runStandalone(args[0], args[1:], os.Stderr))
 	}
-
-	recordID := 0
-	hostname := strings.TrimSuffix(strings.TrimSuffix(ch.ResolvedFQDN, ch.ResolvedZone), ".")
-
-	for _, record := range zoneData.Items[0].Records {
-		if record.Type == 3 && record.Name == hostname && record.Value == ch.Key {
-			recordID = record.ID
-			break
-		}
-	}
-	if recordID == 0 {
-		// Nothing to delete
-		return nil
+	if len(args) > 0 && (args[0] == "certbot-auth" || args[0] == "certbot-cleanup") {
+		os.Exit(runCertbotHook(args[0], args[1:], os.Getenv, os.Stderr))
 	}
 
-	url := fmt.Sprintf("%s/dnszone/%d/records/%d", bunnyAPIBase, zoneData.Items[0].ID, recordID)
-
-	req, err := http.NewRequest("DELETE", url, nil)
-	if err != nil {
-		return err
+	if options.GroupName == "" && len(options.Groups) == 0 {
+		panic(errMissingGroupName)
 	}
-	req.Header.Add("accept", "application/json")
-	req.Header.Add("AccessKey", cfg.APIKey)
-	_, err = httpClient.Do(req)
-	return err
-}
-
-func (c *bunnyNetDNSSolver) Initialize(kubeClientConfig *rest.Config, stopCh <-chan struct{}) error {
-	return nil
-}
 
-func loadConfig() (bunnyNetDNSConfig, error) {
-	if ApiKey == "" {
-		panic(errMissingAPIKey)
-	}
-	cfg := bunnyNetDNSConfig{
-		APIKey: ApiKey,
+	// The solvers are created once; the debug and external-dns servers use
+	// the main one's API key, rate limits and breakers.
+	hooks := newSolvers()
+	startDebugServer(options.MetricsBindAddress, hooks[0])
+	if options.ExternalDNSBindAddress != "" {
+		startExternalDNSServer(options.ExternalDNSBindAddress, hooks[0])
 	}
 
-	return cfg, nil
-}
-
-type ZoneResponse struct {
-	Items        []Item `json:"Items"`
-	CurrentPage  int    `json:"CurrentPage"`
-	TotalItems   int    `json:"TotalItems"`
-	HasMoreItems bool   `json:"HasMoreItems"`
-}
-type Item struct {
-	ID      int      `json:"Id"`
-	Domain  string   `json:"Domain"`
-	Records []Record `json:"Records"`
-}
-type Record struct {
-	ID       int    `json:"Id,omitempty"`
-	Type     int    `json:"Type,omitempty"`
-	Ttl      int    `json:"Ttl,omitempty"`
-	Value    string `json:"Value,omitempty"`
-	Name     string `json:"Name,omitempty"`
-	Disabled bool   `json:"Disabled,omitempty"`
+	switch options.Mode {
+	case modeWebhook:
+		if len(options.Groups) > 0 {
+			if err := runWebhookServers(options.Groups, args, hooks); err != nil {
+				log.Fatalf("webhook servers failed: %v", err)
+			}
+			drainSolvers()
+			flushTraces(shutdownTracing)
+			return
+		}
+		if err := runWebhookServer(options.GroupName, args, hooks); err != nil {
+			log.Fatalf("webhook server failed: %v", err)
+		}
+		drainSolvers()
+		flushTraces(shutdownTracing)
+	case modeController:
+		if err := runController(options.GroupName, hooks); err != nil {
+			log.Fatalf("controller failed: %v", err)
+		}
+		drainSolvers()
+		flushTraces(shutdownTracing)
+	default:
+		panic(fmt.Sprintf("unknown mode %q, must be %q or %q", options.Mode, modeWebhook, modeController))
+	}
 }
@@ -1,24 +1,46 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	acmetest "github.com/cert-manager/cert-manager/test/acme"
+
+	"github.com/cert-manager/webhook-example/pkg/bunny/bunnytest"
+	"github.com/cert-manager/webhook-example/pkg/solver"
 )
 
 var (
 	zone = os.Getenv("TEST_ZONE_NAME")
 )
 
+func TestMain(m *testing.M) {
+	var err error
+	if options, _, err = loadOptions(nil, os.Getenv); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
 func TestRunsSuite(t *testing.T) {
+	if zone == "" {
+		t.Skip("TEST_ZONE_NAME is not set; see TestRunsSuiteAgainstFakeAPI for a run without a Bunny account")
+	}
 	// The manifest path should contain a file named config.json that is a
 	// snippet of valid configuration that should be included on the
 	// ChallengeRequest passed as part of the test cases.
 	//
 
+	// The test control plane has no cert-manager CRDs to read the version
+	// from.
+	solverOptions := options.solverOptions()
+	solverOptions.CheckCertManagerVersion = false
+
 	// Uncomment the below fixture when implementing your custom DNS provider
-	fixture := acmetest.NewFixture(&bunnyNetDNSSolver{},
+	fixture := acmetest.NewFixture(solver.New(solverOptions),
 		acmetest.SetResolvedZone(zone),
 		acmetest.SetAllowAmbientCredentials(false),
 		acmetest.SetManifestPath("testdata/my-custom-solver"),
@@ -39,3 +61,34 @@ func TestRunsSuite(t *testing.T) {
 	fixture.RunExtended(t)
 
 }
+
+// TestRunsSuiteAgainstFakeAPI runs the conformance suite against an
+// in-memory Bunny API, whose records are also served over DNS for the
+// propagation checks. It needs the envtest binaries but no Bunny account.
+func TestRunsSuiteAgainstFakeAPI(t *testing.T) {
+	api := bunnytest.NewServer()
+	defer api.Close()
+	api.RequireAPIKey("conformance")
+	api.AddZone("example.com")
+	nameserver, err := api.StartDNS()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fixture := acmetest.NewFixture(solver.New(solver.Options{
+		APIKey:                  "conformance",
+		PropagationNameservers:  []string{nameserver},
+		PropagationPollInterval: 100 * time.Millisecond,
+	}),
+		acmetest.SetResolvedZone("example.com."),
+		acmetest.SetAllowAmbientCredentials(true),
+		acmetest.SetConfig(map[string]string{"apiURL": api.URL}),
+		acmetest.SetDNSServer(nameserver),
+		acmetest.SetUseAuthoritative(false),
+		acmetest.SetPollInterval(100*time.Millisecond),
+		acmetest.SetPropagationLimit(10*time.Second),
+		acmetest.SetStrict(true),
+	)
+	fixture.RunBasic(t)
+	fixture.RunExtended(t)
+}
@@ -0,0 +1,675 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/cert-manager/webhook-example/pkg/solver"
+)
+
+// Options holds the process-wide configuration of the webhook.
+//
+// Every option can be set from a single YAML file (--config or CONFIG_FILE),
+// and those in envOptions from an environment variable and the matching
+// flag, e.g. GROUP_NAME and --group-name. Values are resolved with the
+// following precedence, highest first:
+//
+//	command-line flags > environment variables > config file > defaults
+type Options struct {
+	GroupName          string `json:"groupName,omitempty"`
+	APIKey             string `json:"apiKey,omitempty"`
+	Mode               string `json:"mode,omitempty"`
+	MetricsBindAddress string `json:"metricsBindAddress,omitempty"`
+
+	// EnableProfiling serves the pprof endpoints on the metrics port. They
+	// are unauthenticated, so they are off unless asked for.
+	EnableProfiling bool `json:"enableProfiling,omitempty"`
+
+	// APIKeyFile is a file holding APIKey, typically a mounted Secret. It is
+	// re-read periodically so the key can be rotated without a restart.
+	APIKeyFile string `json:"apiKeyFile,omitempty"`
+
+	// VaultAddress reads APIKey from a Vault KV v2 secret instead, at
+	// VaultSecretPath in the engine mounted at VaultKVMount, field
+	// VaultSecretKey. It logs in with VaultToken or through the Kubernetes
+	// auth method as VaultKubernetesRole, and reads the key again every
+	// VaultRefreshInterval.
+	VaultAddress         string          `json:"vaultAddress,omitempty"`
+	VaultNamespace       string          `json:"vaultNamespace,omitempty"`
+	VaultCACertFile      string          `json:"vaultCACertFile,omitempty"`
+	VaultKVMount         string          `json:"vaultKVMount,omitempty"`
+	VaultSecretPath      string          `json:"vaultSecretPath,omitempty"`
+	VaultSecretKey       string          `json:"vaultSecretKey,omitempty"`
+	VaultToken           string          `json:"vaultToken,omitempty"`
+	VaultKubernetesRole  string          `json:"vaultKubernetesRole,omitempty"`
+	VaultRefreshInterval metav1.Duration `json:"vaultRefreshInterval,omitempty"`
+
+	// FallbackAPIKeys are tried in order when Bunny rejects the webhook-wide
+	// key with 401, so keys can be rotated with an overlap window.
+	FallbackAPIKeys []string `json:"fallbackAPIKeys,omitempty"`
+
+	// SecondaryAPIKey mirrors challenge records to a second Bunny account
+	// hosting the same zones.
+	SecondaryAPIKey string `json:"secondaryAPIKey,omitempty"`
+
+	// APIBase is the Bunny API base URL, for testing against a mock server
+	// or routing through an internal API gateway. Issuers and their zone
+	// endpoints can override it.
+	APIBase string `json:"apiBase,omitempty"`
+
+	// AllowedAPIURLs are API endpoints Issuers may send the webhook-wide
+	// key or a zone binding's key to with apiURL or zoneEndpoints. Other
+	// endpoints only get the Issuer's own apiKeySecretRef.
+	AllowedAPIURLs []string `json:"allowedAPIURLs,omitempty"`
+
+	// AuthScheme is how API keys are sent to Bunny: AccessKey or Bearer.
+	// Issuers can override it in their config.
+	AuthScheme string `json:"authScheme,omitempty"`
+
+	// Namespace is the namespace the webhook runs in. It holds the leader
+	// election Lease and the defaults ConfigMap.
+	Namespace string `json:"namespace,omitempty"`
+
+	// PodName and NodeName identify this replica in logs and are normally
+	// injected through the Downward API.
+	PodName  string `json:"podName,omitempty"`
+	NodeName string `json:"nodeName,omitempty"`
+
+	// LogLevel is debug, info (the default), warn or error; LogFormat is
+	// text (the default) or json. The --log-level and --log-format flags
+	// override them.
+	LogLevel  string `json:"logLevel,omitempty"`
+	LogFormat string `json:"logFormat,omitempty"`
+
+	// KubeAPIQPS and KubeAPIBurst rate limit requests to the Kubernetes API
+	// server. Zero keeps the client-go defaults.
+	KubeAPIQPS   float32 `json:"kubeAPIQPS,omitempty"`
+	KubeAPIBurst int     `json:"kubeAPIBurst,omitempty"`
+
+	// LeaderElection restricts background jobs to a single replica.
+	LeaderElection   bool   `json:"leaderElection,omitempty"`
+	LeaderElectionID string `json:"leaderElectionID,omitempty"`
+
+	// ClusterResourceNamespace is where cert-manager reads Secrets for
+	// ClusterIssuers. It must match cert-manager's --cluster-resource-namespace.
+	ClusterResourceNamespace string `json:"clusterResourceNamespace,omitempty"`
+
+	// AdmissionBindAddress enables the Issuer validating webhook when set.
+	AdmissionBindAddress string `json:"admissionBindAddress,omitempty"`
+	AdmissionCertFile    string `json:"admissionCertFile,omitempty"`
+	AdmissionKeyFile     string `json:"admissionKeyFile,omitempty"`
+
+	// ServedNamespaces restricts the webhook to challenges whose resource
+	// namespace is listed, for per-tenant deployments. Empty serves all.
+	ServedNamespaces []string `json:"servedNamespaces,omitempty"`
+
+	// ZoneBindings enables BunnyZoneBinding resources as a source of
+	// credentials for zones.
+	ZoneBindings bool `json:"zoneBindings,omitempty"`
+
+	// WatchIssuers validates Issuers referencing this webhook in the
+	// background and reports problems through metrics and Events.
+	WatchIssuers bool `json:"watchIssuers,omitempty"`
+
+	// CheckCertManagerVersion refuses to start against cert-manager
+	// releases older than the oldest supported one.
+	CheckCertManagerVersion bool `json:"checkCertManagerVersion,omitempty"`
+
+	// CheckAPIService holds readiness back until the webhook's APIService is
+	// Available and reachable through the apiserver.
+	CheckAPIService bool `json:"checkAPIService,omitempty"`
+
+	// ClusterProxy routes Bunny API requests through the cluster-wide
+	// egress proxy published by the OpenShift Proxy resource.
+	ClusterProxy bool `json:"clusterProxy,omitempty"`
+
+	// CleanupQueueConfigMap names a ConfigMap in Namespace used to persist
+	// pending record deletions. Setting it makes CleanUp asynchronous.
+	CleanupQueueConfigMap string `json:"cleanupQueueConfigMap,omitempty"`
+
+	// AdminToken enables the admin endpoints on the metrics port, which
+	// require it as a bearer token. They use the webhook-wide APIKey.
+	AdminToken string `json:"adminToken,omitempty"`
+
+	// InventoryToken enables the read-only inventory endpoint on the metrics
+	// port, which requires it as a bearer token.
+	InventoryToken string `json:"inventoryToken,omitempty"`
+
+	// ACMEDNSURL enables an acme-dns server as the fallback used while the
+	// Bunny API keeps failing. ACMEDNSAccountsFile holds its accounts.
+	ACMEDNSURL            string `json:"acmeDNSURL,omitempty"`
+	ACMEDNSAccountsFile   string `json:"acmeDNSAccountsFile,omitempty"`
+	FallbackAfterFailures int    `json:"fallbackAfterFailures,omitempty"`
+
+	// RecordTTL is the TTL in seconds of challenge records for Issuers that
+	// don't set one.
+	RecordTTL int `json:"recordTTL,omitempty"`
+
+	// KeepRecordsOnCleanup leaves challenge records in place on CleanUp.
+	// Debugging only.
+	KeepRecordsOnCleanup bool `json:"keepRecordsOnCleanup,omitempty"`
+
+	// OrphanRecordMaxAge, when set, deletes _acme-challenge TXT records in
+	// the zones of APIKey that have been around for that long, scanning
+	// every OrphanRecordGCInterval.
+	OrphanRecordMaxAge     metav1.Duration `json:"orphanRecordMaxAge,omitempty"`
+	OrphanRecordGCInterval metav1.Duration `json:"orphanRecordGCInterval,omitempty"`
+
+	// OperationTimeout bounds the Bunny API calls of a single Present or
+	// CleanUp, retries included.
+	OperationTimeout metav1.Duration `json:"operationTimeout,omitempty"`
+
+	// ShutdownGracePeriod is how long in-flight challenges may take to finish
+	// on shutdown.
+	ShutdownGracePeriod metav1.Duration `json:"shutdownGracePeriod,omitempty"`
+
+	// ZoneCacheTTL is how long Bunny zone IDs are cached. Zero disables the
+	// cache.
+	ZoneCacheTTL metav1.Duration `json:"zoneCacheTTL,omitempty"`
+
+	// APIRateLimit caps the Bunny API requests per second across all
+	// challenges, allowing bursts of APIRateBurst. Zero means no limit.
+	APIRateLimit float64 `json:"apiRateLimit,omitempty"`
+	APIRateBurst int     `json:"apiRateBurst,omitempty"`
+
+	// APICircuitBreakerFailures consecutive failed Bunny API requests make
+	// further requests fail fast for APICircuitBreakerCooldown. Zero
+	// disables the circuit breaker.
+	APICircuitBreakerFailures int             `json:"apiCircuitBreakerFailures,omitempty"`
+	APICircuitBreakerCooldown metav1.Duration `json:"apiCircuitBreakerCooldown,omitempty"`
+
+	// APIRetryAttempts, APIRetryBaseDelay and APIRetryMaxDelay control how
+	// Bunny API requests failing with a transient error are retried.
+	APIRetryAttempts  int             `json:"apiRetryAttempts,omitempty"`
+	APIRetryBaseDelay metav1.Duration `json:"apiRetryBaseDelay,omitempty"`
+	APIRetryMaxDelay  metav1.Duration `json:"apiRetryMaxDelay,omitempty"`
+
+	// DoHResolvers are DNS-over-HTTPS resolver URLs that must return a new
+	// record before Present returns. PropagationTimeout bounds the wait.
+	DoHResolvers       []string        `json:"dohResolvers,omitempty"`
+	PropagationTimeout metav1.Duration `json:"propagationTimeout,omitempty"`
+
+	// PropagationPollInterval is how often propagation is checked.
+	PropagationPollInterval metav1.Duration `json:"propagationPollInterval,omitempty"`
+
+	// VerifyPropagation makes Present wait until Bunny's authoritative
+	// nameservers serve the new record.
+	VerifyPropagation bool `json:"verifyPropagation,omitempty"`
+
+	// PropagationNameservers are nameservers, host or host:port, Present
+	// waits on instead of Bunny's for clusters that cannot reach them.
+	PropagationNameservers []string `json:"propagationNameservers,omitempty"`
+
+	// GRPCBindAddress serves Present and CleanUp over gRPC. It requires
+	// mutual TLS: GRPCCertFile and GRPCKeyFile are the server certificate,
+	// and clients must present a certificate signed by GRPCClientCAFile.
+	// Requests may only name a resource namespace in GRPCAllowedNamespaces.
+	GRPCBindAddress       string   `json:"grpcBindAddress,omitempty"`
+	GRPCCertFile          string   `json:"grpcCertFile,omitempty"`
+	GRPCKeyFile           string   `json:"grpcKeyFile,omitempty"`
+	GRPCClientCAFile      string   `json:"grpcClientCAFile,omitempty"`
+	GRPCAllowedNamespaces []string `json:"grpcAllowedNamespaces,omitempty"`
+
+	// ExternalDNSBindAddress serves the external-dns webhook provider API for
+	// the zones of the webhook-wide API key, restricted to
+	// ExternalDNSDomainFilter if set. It should be a loopback address unless
+	// ExternalDNSToken is set, which the API then requires as a bearer token.
+	ExternalDNSBindAddress  string   `json:"externalDNSBindAddress,omitempty"`
+	ExternalDNSDomainFilter []string `json:"externalDNSDomainFilter,omitempty"`
+	ExternalDNSToken        string   `json:"externalDNSToken,omitempty"`
+
+	// StorageZone, StoragePassword and StorageEndpoint select the Bunny Edge
+	// Storage zone HTTP01 challenges are uploaded to by the certbot hooks,
+	// and for Issuers whose http01.ingress solver uses HTTP01IngressClass.
+	StorageZone        string `json:"storageZone,omitempty"`
+	StoragePassword    string `json:"storagePassword,omitempty"`
+	StorageEndpoint    string `json:"storageEndpoint,omitempty"`
+	HTTP01IngressClass string `json:"http01IngressClass,omitempty"`
+
+	// DogStatsDAddress sends operation and API call metrics to a Datadog
+	// agent. NewRelicAccountID and NewRelicInsertKey send them to New Relic
+	// as custom events.
+	DogStatsDAddress  string `json:"dogStatsDAddress,omitempty"`
+	NewRelicAccountID string `json:"newRelicAccountID,omitempty"`
+	NewRelicInsertKey string `json:"newRelicInsertKey,omitempty"`
+	NewRelicRegion    string `json:"newRelicRegion,omitempty"`
+
+	// NotifyWebhookURL and NotifySlackWebhookURL receive a notification when
+	// Present fails NotifyAfterFailures times in a row for a domain.
+	NotifyWebhookURL      string `json:"notifyWebhookURL,omitempty"`
+	NotifySlackWebhookURL string `json:"notifySlackWebhookURL,omitempty"`
+	NotifyAfterFailures   int    `json:"notifyAfterFailures,omitempty"`
+
+	// CloudEventsURL receives challenge lifecycle events as CloudEvents.
+	CloudEventsURL string `json:"cloudEventsURL,omitempty"`
+
+	// DelegationZone is a Bunny zone all challenge records are written to,
+	// with each domain's _acme-challenge name a CNAME into it.
+	DelegationZone string `json:"delegationZone,omitempty"`
+
+	// DefaultsConfigMap names a ConfigMap in Namespace whose solver config
+	// is merged under every Issuer's config.
+	DefaultsConfigMap string `json:"defaultsConfigMap,omitempty"`
+
+	// Groups serves several API groups from one process. It can only be set
+	// from the config file and replaces GroupName when non-empty.
+	Groups []GroupOptions `json:"groups,omitempty"`
+
+	// SolverName is the solverName Issuers select the main solver by.
+	// Solvers adds more solvers with their own name, key and API base to
+	// every served group; it can only be set from the config file.
+	SolverName string          `json:"solverName,omitempty"`
+	Solvers    []SolverOptions `json:"solvers,omitempty"`
+}
+
+const configFileFlag = "config"
+
+// envOption is an option settable from an environment variable and the
+// command-line flag derived from its name.
+type envOption struct {
+	name string
+	set  func(o *Options, v string) error
+}
+
+// envOptions maps environment variables onto the option they override.
+var envOptions = []envOption{
+	{"GROUP_NAME", func(o *Options, v string) error { o.GroupName = v; return nil }},
+	{"API_KEY", func(o *Options, v string) error { o.APIKey = v; return nil }},
+	{"API_KEY_FILE", func(o *Options, v string) error { o.APIKeyFile = v; return nil }},
+	{"VAULT_ADDR", func(o *Options, v string) error { o.VaultAddress = v; return nil }},
+	{"VAULT_NAMESPACE", func(o *Options, v string) error { o.VaultNamespace = v; return nil }},
+	{"VAULT_CACERT", func(o *Options, v string) error { o.VaultCACertFile = v; return nil }},
+	{"VAULT_KV_MOUNT", func(o *Options, v string) error { o.VaultKVMount = v; return nil }},
+	{"VAULT_SECRET_PATH", func(o *Options, v string) error { o.VaultSecretPath = v; return nil }},
+	{"VAULT_SECRET_KEY", func(o *Options, v string) error { o.VaultSecretKey = v; return nil }},
+	{"VAULT_TOKEN", func(o *Options, v string) error { o.VaultToken = v; return nil }},
+	{"VAULT_KUBERNETES_ROLE", func(o *Options, v string) error { o.VaultKubernetesRole = v; return nil }},
+	{"VAULT_REFRESH_INTERVAL", func(o *Options, v string) (err error) {
+		o.VaultRefreshInterval.Duration, err = time.ParseDuration(v)
+		return err
+	}},
+	{"FALLBACK_API_KEYS", func(o *Options, v string) error { o.FallbackAPIKeys = splitList(v); return nil }},
+	{"SECONDARY_API_KEY", func(o *Options, v string) error { o.SecondaryAPIKey = v; return nil }},
+	{"API_BASE", func(o *Options, v string) error { o.APIBase = v; return nil }},
+	{"ALLOWED_API_URLS", func(o *Options, v string) error { o.AllowedAPIURLs = splitList(v); return nil }},
+	{"BUNNY_AUTH_SCHEME", func(o *Options, v string) error {
+		if v != solver.AuthSchemeAccessKey && v != solver.AuthSchemeBearer {
+			return fmt.Errorf("must be %s or %s", solver.AuthSchemeAccessKey, solver.AuthSchemeBearer)
+		}
+		o.AuthScheme = v
+		return nil
+	}},
+	{"MODE", func(o *Options, v string) error { o.Mode = v; return nil }},
+	{"METRICS_BIND_ADDRESS", func(o *Options, v string) error { o.MetricsBindAddress = v; return nil }},
+	{"ENABLE_PROFILING", func(o *Options, v string) (err error) {
+		o.EnableProfiling, err = strconv.ParseBool(v)
+		return err
+	}},
+	{"KUBE_API_QPS", func(o *Options, v string) error {
+		qps, err := strconv.ParseFloat(v, 32)
+		o.KubeAPIQPS = float32(qps)
+		return err
+	}},
+	{"KUBE_API_BURST", func(o *Options, v string) (err error) { o.KubeAPIBurst, err = strconv.Atoi(v); return err }},
+	{"LEADER_ELECT", func(o *Options, v string) (err error) {
+		o.LeaderElection, err = strconv.ParseBool(v)
+		return err
+	}},
+	{"LEADER_ELECTION_ID", func(o *Options, v string) error { o.LeaderElectionID = v; return nil }},
+	{"POD_NAMESPACE", func(o *Options, v string) error { o.Namespace = v; return nil }},
+	{"POD_NAME", func(o *Options, v string) error { o.PodName = v; return nil }},
+	{"NODE_NAME", func(o *Options, v string) error { o.NodeName = v; return nil }},
+	{"LOG_LEVEL", func(o *Options, v string) error { o.LogLevel = v; return nil }},
+	{"LOG_FORMAT", func(o *Options, v string) error { o.LogFormat = v; return nil }},
+	{"SERVED_NAMESPACES", func(o *Options, v string) error { o.ServedNamespaces = splitList(v); return nil }},
+	{"ZONE_BINDINGS", func(o *Options, v string) (err error) {
+		o.ZoneBindings, err = strconv.ParseBool(v)
+		return err
+	}},
+	{"WATCH_ISSUERS", func(o *Options, v string) (err error) {
+		o.WatchIssuers, err = strconv.ParseBool(v)
+		return err
+	}},
+	{"CHECK_CERT_MANAGER_VERSION", func(o *Options, v string) (err error) {
+		o.CheckCertManagerVersion, err = strconv.ParseBool(v)
+		return err
+	}},
+	{"CHECK_APISERVICE", func(o *Options, v string) (err error) {
+		o.CheckAPIService, err = strconv.ParseBool(v)
+		return err
+	}},
+	{"CLUSTER_PROXY", func(o *Options, v string) (err error) {
+		o.ClusterProxy, err = strconv.ParseBool(v)
+		return err
+	}},
+	{"CLEANUP_QUEUE_CONFIGMAP", func(o *Options, v string) error { o.CleanupQueueConfigMap = v; return nil }},
+	{"ADMIN_TOKEN", func(o *Options, v string) error { o.AdminToken = v; return nil }},
+	{"INVENTORY_TOKEN", func(o *Options, v string) error { o.InventoryToken = v; return nil }},
+	{"ACME_DNS_URL", func(o *Options, v string) error { o.ACMEDNSURL = v; return nil }},
+	{"ACME_DNS_ACCOUNTS_FILE", func(o *Options, v string) error { o.ACMEDNSAccountsFile = v; return nil }},
+	{"FALLBACK_AFTER_FAILURES", func(o *Options, v string) (err error) { o.FallbackAfterFailures, err = strconv.Atoi(v); return err }},
+	{"RECORD_TTL", func(o *Options, v string) (err error) { o.RecordTTL, err = strconv.Atoi(v); return err }},
+	{"KEEP_RECORDS_ON_CLEANUP", func(o *Options, v string) (err error) {
+		o.KeepRecordsOnCleanup, err = strconv.ParseBool(v)
+		return err
+	}},
+	{"ORPHAN_RECORD_MAX_AGE", func(o *Options, v string) (err error) {
+		o.OrphanRecordMaxAge.Duration, err = time.ParseDuration(v)
+		return err
+	}},
+	{"ORPHAN_RECORD_GC_INTERVAL", func(o *Options, v string) (err error) {
+		o.OrphanRecordGCInterval.Duration, err = time.ParseDuration(v)
+		return err
+	}},
+	{"OPERATION_TIMEOUT", func(o *Options, v string) (err error) {
+		o.OperationTimeout.Duration, err = time.ParseDuration(v)
+		return err
+	}},
+	{"SHUTDOWN_GRACE_PERIOD", func(o *Options, v string) (err error) {
+		o.ShutdownGracePeriod.Duration, err = time.ParseDuration(v)
+		return err
+	}},
+	{"ZONE_CACHE_TTL", func(o *Options, v string) (err error) {
+		o.ZoneCacheTTL.Duration, err = time.ParseDuration(v)
+		return err
+	}},
+	{"API_RATE_LIMIT", func(o *Options, v string) (err error) { o.APIRateLimit, err = strconv.ParseFloat(v, 64); return err }},
+	{"API_RATE_BURST", func(o *Options, v string) (err error) { o.APIRateBurst, err = strconv.Atoi(v); return err }},
+	{"API_CIRCUIT_BREAKER_FAILURES", func(o *Options, v string) (err error) {
+		o.APICircuitBreakerFailures, err = strconv.Atoi(v)
+		return err
+	}},
+	{"API_CIRCUIT_BREAKER_COOLDOWN", func(o *Options, v string) (err error) {
+		o.APICircuitBreakerCooldown.Duration, err = time.ParseDuration(v)
+		return err
+	}},
+	{"API_RETRY_ATTEMPTS", func(o *Options, v string) (err error) { o.APIRetryAttempts, err = strconv.Atoi(v); return err }},
+	{"API_RETRY_BASE_DELAY", func(o *Options, v string) (err error) {
+		o.APIRetryBaseDelay.Duration, err = time.ParseDuration(v)
+		return err
+	}},
+	{"API_RETRY_MAX_DELAY", func(o *Options, v string) (err error) {
+		o.APIRetryMaxDelay.Duration, err = time.ParseDuration(v)
+		return err
+	}},
+	{"DOH_RESOLVERS", func(o *Options, v string) error { o.DoHResolvers = splitList(v); return nil }},
+	{"PROPAGATION_TIMEOUT", func(o *Options, v string) (err error) {
+		o.PropagationTimeout.Duration, err = time.ParseDuration(v)
+		return err
+	}},
+	{"PROPAGATION_POLL_INTERVAL", func(o *Options, v string) (err error) {
+		o.PropagationPollInterval.Duration, err = time.ParseDuration(v)
+		return err
+	}},
+	{"PROPAGATION_NAMESERVERS", func(o *Options, v string) error { o.PropagationNameservers = splitList(v); return nil }},
+	{"VERIFY_PROPAGATION", func(o *Options, v string) (err error) {
+		o.VerifyPropagation, err = strconv.ParseBool(v)
+		return err
+	}},
+	{"GRPC_BIND_ADDRESS", func(o *Options, v string) error { o.GRPCBindAddress = v; return nil }},
+	{"GRPC_CERT_FILE", func(o *Options, v string) error { o.GRPCCertFile = v; return nil }},
+	{"GRPC_KEY_FILE", func(o *Options, v string) error { o.GRPCKeyFile = v; return nil }},
+	{"GRPC_CLIENT_CA_FILE", func(o *Options, v string) error { o.GRPCClientCAFile = v; return nil }},
+	{"GRPC_ALLOWED_NAMESPACES", func(o *Options, v string) error { o.GRPCAllowedNamespaces = splitList(v); return nil }},
+	{"SOLVER_NAME", func(o *Options, v string) error { o.SolverName = v; return nil }},
+	{"EXTERNAL_DNS_BIND_ADDRESS", func(o *Options, v string) error { o.ExternalDNSBindAddress = v; return nil }},
+	{"EXTERNAL_DNS_DOMAIN_FILTER", func(o *Options, v string) error { o.ExternalDNSDomainFilter = splitList(v); return nil }},
+	{"EXTERNAL_DNS_TOKEN", func(o *Options, v string) error { o.ExternalDNSToken = v; return nil }},
+	{"STORAGE_ZONE", func(o *Options, v string) error { o.StorageZone = v; return nil }},
+	{"STORAGE_PASSWORD", func(o *Options, v string) error { o.StoragePassword = v; return nil }},
+	{"STORAGE_ENDPOINT", func(o *Options, v string) error { o.StorageEndpoint = v; return nil }},
+	{"HTTP01_INGRESS_CLASS", func(o *Options, v string) error { o.HTTP01IngressClass = v; return nil }},
+	{"DOGSTATSD_ADDRESS", func(o *Options, v string) error { o.DogStatsDAddress = v; return nil }},
+	{"NEW_RELIC_ACCOUNT_ID", func(o *Options, v string) error { o.NewRelicAccountID = v; return nil }},
+	{"NEW_RELIC_INSERT_KEY", func(o *Options, v string) error { o.NewRelicInsertKey = v; return nil }},
+	{"NEW_RELIC_REGION", func(o *Options, v string) error { o.NewRelicRegion = v; return nil }},
+	{"NOTIFY_WEBHOOK_URL", func(o *Options, v string) error { o.NotifyWebhookURL = v; return nil }},
+	{"NOTIFY_SLACK_WEBHOOK_URL", func(o *Options, v string) error { o.NotifySlackWebhookURL = v; return nil }},
+	{"NOTIFY_AFTER_FAILURES", func(o *Options, v string) (err error) { o.NotifyAfterFailures, err = strconv.Atoi(v); return err }},
+	{"CLOUDEVENTS_URL", func(o *Options, v string) error { o.CloudEventsURL = v; return nil }},
+	{"DELEGATION_ZONE", func(o *Options, v string) error { o.DelegationZone = v; return nil }},
+	{"DEFAULTS_CONFIGMAP", func(o *Options, v string) error { o.DefaultsConfigMap = v; return nil }},
+	{"CLUSTER_RESOURCE_NAMESPACE", func(o *Options, v string) error { o.ClusterResourceNamespace = v; return nil }},
+	{"ADMISSION_BIND_ADDRESS", func(o *Options, v string) error { o.AdmissionBindAddress = v; return nil }},
+	{"ADMISSION_CERT_FILE", func(o *Options, v string) error { o.AdmissionCertFile = v; return nil }},
+	{"ADMISSION_KEY_FILE", func(o *Options, v string) error { o.AdmissionKeyFile = v; return nil }},
+}
+
+// boolOptions are the envOptions whose flag doesn't need a value.
+var boolOptions = map[string]bool{
+	"ENABLE_PROFILING":           true,
+	"LEADER_ELECT":               true,
+	"ZONE_BINDINGS":              true,
+	"WATCH_ISSUERS":              true,
+	"CHECK_CERT_MANAGER_VERSION": true,
+	"CHECK_APISERVICE":           true,
+	"CLUSTER_PROXY":              true,
+	"KEEP_RECORDS_ON_CLEANUP":    true,
+	"VERIFY_PROPAGATION":         true,
+}
+
+func defaultOptions() Options {
+	return Options{
+		Mode:               modeWebhook,
+		MetricsBindAddress: ":8080",
+		Namespace:          "default",
+		LeaderElectionID:   "cert-manager-webhook-bunny",
+
+		ClusterResourceNamespace: "cert-manager",
+		AdmissionCertFile:        "/tls/tls.crt",
+		AdmissionKeyFile:         "/tls/tls.key",
+
+		CheckCertManagerVersion:   true,
+		APICircuitBreakerFailures: 5,
+		ZoneCacheTTL:              metav1.Duration{Duration: 5 * time.Minute},
+	}
+}
+
+// loadOptions resolves Options from args, the environment and the config
+// file. Flags it consumes are removed from the returned args so the rest can
+// be handed to the webhook server unchanged.
+func loadOptions(args []string, getenv func(string) string) (Options, []string, error) {
+	opts := defaultOptions()
+
+	configFile, args := extractFlag(args, configFileFlag)
+	if configFile == "" {
+		configFile = getenv("CONFIG_FILE")
+	}
+	if configFile != "" {
+		data, err := os.ReadFile(configFile)
+		if err != nil {
+			return Options{}, nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		if err := yaml.UnmarshalStrict(data, &opts); err != nil {
+			return Options{}, nil, fmt.Errorf("failed to parse config file %s: %w", configFile, err)
+		}
+	}
+
+	for _, e := range envOptions {
+		v := getenv(e.name)
+		if v == "" {
+			continue
+		}
+		if err := e.set(&opts, v); err != nil {
+			return Options{}, nil, fmt.Errorf("invalid value for %s: %w", e.name, err)
+		}
+	}
+
+	args = applyLoggingFlags(&opts, args)
+	args, err := applyFlags(&opts, args)
+	if err != nil {
+		return Options{}, nil, err
+	}
+	if err := validateLogging(opts); err != nil {
+		return Options{}, nil, err
+	}
+	if sources := countSet(opts.APIKey, opts.APIKeyFile, opts.VaultAddress); sources > 1 {
+		return Options{}, nil, errors.New("only one of apiKey, apiKeyFile and vaultAddress may be set")
+	}
+	if err := validateSolvers(opts.SolverName, opts.Solvers); err != nil {
+		return Options{}, nil, err
+	}
+	if err := validateGRPC(opts); err != nil {
+		return Options{}, nil, err
+	}
+	for i, u := range opts.AllowedAPIURLs {
+		if err := solver.ValidateAPIBase(u); err != nil {
+			return Options{}, nil, fmt.Errorf("allowedAPIURLs[%d]: %w", i, err)
+		}
+	}
+	if opts.HTTP01IngressClass != "" && (opts.StorageZone == "" || opts.StoragePassword == "") {
+		return Options{}, nil, errors.New("http01IngressClass requires storageZone and storagePassword")
+	}
+	if opts.ExternalDNSBindAddress != "" && !opts.hasAPIKey() {
+		return Options{}, nil, errors.New("externalDNSBindAddress requires a webhook-wide API key")
+	}
+	if err := solver.ValidateRecordTTL(opts.RecordTTL); err != nil {
+		return Options{}, nil, fmt.Errorf("invalid recordTTL: %w", err)
+	}
+	return opts, args, nil
+}
+
+// validateGRPC refuses to serve the gRPC API without mutual TLS and a
+// namespace allow-list: any client reaching it could otherwise use the
+// webhook's RBAC to read API key Secrets from every namespace.
+func validateGRPC(o Options) error {
+	if o.GRPCBindAddress == "" {
+		return nil
+	}
+	if countSet(o.GRPCCertFile, o.GRPCKeyFile, o.GRPCClientCAFile) != 3 {
+		return errors.New("grpcBindAddress requires grpcCertFile, grpcKeyFile and grpcClientCAFile")
+	}
+	if len(o.GRPCAllowedNamespaces) == 0 {
+		return errors.New("grpcBindAddress requires grpcAllowedNamespaces")
+	}
+	return nil
+}
+
+// splitList splits a comma separated environment variable value.
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// extractFlag removes --name=value or --name value from args and returns the
+// value along with the remaining arguments.
+func extractFlag(args []string, name string) (string, []string) {
+	var value string
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		trimmed := strings.TrimLeft(arg, "-")
+		if len(arg)-len(trimmed) == 0 || len(arg)-len(trimmed) > 2 {
+			rest = append(rest, arg)
+			continue
+		}
+		switch {
+		case trimmed == name && i+1 < len(args):
+			value = args[i+1]
+			i++
+		case strings.HasPrefix(trimmed, name+"="):
+			value = strings.TrimPrefix(trimmed, name+"=")
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return value, rest
+}
+
+// hasAPIKey reports whether a webhook-wide API key is configured.
+func (o Options) hasAPIKey() bool {
+	return countSet(o.APIKey, o.APIKeyFile, o.VaultAddress) > 0
+}
+
+// countSet returns how many of values are non-empty.
+func countSet(values ...string) int {
+	n := 0
+	for _, v := range values {
+		if v != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// servedGroups returns every API group this process serves.
+func (o Options) servedGroups() []string {
+	if len(o.Groups) == 0 {
+		return []string{o.GroupName}
+	}
+	groups := make([]string, 0, len(o.Groups))
+	for _, g := range o.Groups {
+		groups = append(groups, g.GroupName)
+	}
+	return groups
+}
+
+// solverOptions returns the subset of the options used by the solver.
+func (o Options) solverOptions() solver.Options {
+	return solver.Options{
+		Name:                     o.SolverName,
+		APIKey:                   o.APIKey,
+		FallbackAPIKeys:          o.FallbackAPIKeys,
+		SecondaryAPIKey:          o.SecondaryAPIKey,
+		AuthScheme:               o.AuthScheme,
+		AllowedAPIURLs:           o.AllowedAPIURLs,
+		Groups:                   o.servedGroups(),
+		Namespace:                o.Namespace,
+		ClusterResourceNamespace: o.ClusterResourceNamespace,
+		KubeAPIQPS:               o.KubeAPIQPS,
+		KubeAPIBurst:             o.KubeAPIBurst,
+		LeaderElection:           o.LeaderElection,
+		LeaderElectionID:         o.LeaderElectionID,
+		ServedNamespaces:         o.ServedNamespaces,
+		AdmissionBindAddress:     o.AdmissionBindAddress,
+		AdmissionCertFile:        o.AdmissionCertFile,
+		AdmissionKeyFile:         o.AdmissionKeyFile,
+		ZoneBindings:             o.ZoneBindings,
+		WatchIssuers:             o.WatchIssuers,
+		DefaultsConfigMap:        o.DefaultsConfigMap,
+		CleanupQueueConfigMap:    o.CleanupQueueConfigMap,
+		HTTP01IngressClass:       o.HTTP01IngressClass,
+		StorageZone:              o.StorageZone,
+		StoragePassword:          o.StoragePassword,
+		StorageEndpoint:          o.StorageEndpoint,
+		ClusterProxy:             o.ClusterProxy,
+		CheckAPIService:          o.CheckAPIService,
+		CheckCertManagerVersion:  o.CheckCertManagerVersion,
+		FallbackAfterFailures:    o.FallbackAfterFailures,
+		DoHResolvers:             o.DoHResolvers,
+		ZoneCacheTTL:             o.ZoneCacheTTL.Duration,
+		OperationTimeout:         o.OperationTimeout.Duration,
+		ShutdownGracePeriod:      o.ShutdownGracePeriod.Duration,
+		RecordTTL:                o.RecordTTL,
+		KeepRecordsOnCleanup:     o.KeepRecordsOnCleanup,
+		OrphanRecordMaxAge:       o.OrphanRecordMaxAge.Duration,
+		OrphanRecordGCInterval:   o.OrphanRecordGCInterval.Duration,
+		APIRateLimit:             o.APIRateLimit,
+		APIRateBurst:             o.APIRateBurst,
+		CircuitBreakerFailures:   o.APICircuitBreakerFailures,
+		CircuitBreakerCooldown:   o.APICircuitBreakerCooldown.Duration,
+		Retry: solver.RetryPolicy{
+			MaxAttempts: o.APIRetryAttempts,
+			BaseDelay:   o.APIRetryBaseDelay.Duration,
+			MaxDelay:    o.APIRetryMaxDelay.Duration,
+		},
+		PropagationTimeout:      o.PropagationTimeout.Duration,
+		PropagationPollInterval: o.PropagationPollInterval.Duration,
+		VerifyPropagation:       o.VerifyPropagation,
+		PropagationNameservers:  o.PropagationNameservers,
+		DelegationZone:          o.DelegationZone,
+		NotifyAfterFailures:     o.NotifyAfterFailures,
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func envFrom(env map[string]string) func(string) string {
+	return func(key string) string { return env[key] }
+}
+
+func TestLoadOptions_Defaults(t *testing.T) {
+	opts, args, err := loadOptions([]string{"--secure-port=443"}, envFrom(nil))
+	require.NoError(t, err)
+	assert.Equal(t, defaultOptions(), opts)
+	assert.Equal(t, []string{"--secure-port=443"}, args)
+}
+
+func TestLoadOptions_Precedence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(file, []byte("groupName: from-file\napiKey: file-key\nmode: controller\n"), 0o600))
+
+	opts, args, err := loadOptions(
+		[]string{"--tls-cert-file=/tls/tls.crt", "--config", file},
+		envFrom(map[string]string{"API_KEY": "env-key"}),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"--tls-cert-file=/tls/tls.crt"}, args)
+	assert.Equal(t, "from-file", opts.GroupName)
+	assert.Equal(t, "env-key", opts.APIKey)
+	assert.Equal(t, modeController, opts.Mode)
+	assert.Equal(t, ":8080", opts.MetricsBindAddress)
+}
+
+func TestLoadOptions_ConfigFileFromEnv(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(file, []byte("groupName: acme.example.com\n"), 0o600))
+
+	opts, _, err := loadOptions(nil, envFrom(map[string]string{"CONFIG_FILE": file}))
+	require.NoError(t, err)
+	assert.Equal(t, "acme.example.com", opts.GroupName)
+}
+
+func TestLoadOptions_UnknownField(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(file, []byte("groupNmae: typo\n"), 0o600))
+
+	_, _, err := loadOptions([]string{"--config=" + file}, envFrom(nil))
+	assert.Error(t, err)
+}
+
+func TestLoadOptions_InvalidEnv(t *testing.T) {
+	_, _, err := loadOptions(nil, envFrom(map[string]string{"KUBE_API_BURST": "lots"}))
+	assert.Error(t, err)
+
+	opts, _, err := loadOptions(nil, envFrom(map[string]string{"KUBE_API_QPS": "12.5", "KUBE_API_BURST": "40"}))
+	require.NoError(t, err)
+	assert.Equal(t, float32(12.5), opts.KubeAPIQPS)
+	assert.Equal(t, 40, opts.KubeAPIBurst)
+
+	_, _, err = loadOptions(nil, envFrom(map[string]string{"RECORD_TTL": "604800"}))
+	assert.Error(t, err, "TTLs beyond a day are rejected")
+}
+
+func TestSplitList(t *testing.T) {
+	assert.Equal(t, []string{"a", "b"}, splitList(" a, ,b,"))
+	assert.Nil(t, splitList(""))
+}
+
+func TestSolverOptions_Groups(t *testing.T) {
+	assert.Equal(t, []string{"acme.example.com"}, Options{GroupName: "acme.example.com"}.solverOptions().Groups)
+
+	opts := Options{Groups: []GroupOptions{{GroupName: "a.example.com"}, {GroupName: "b.example.com"}}}
+	assert.Equal(t, []string{"a.example.com", "b.example.com"}, opts.solverOptions().Groups)
+}
+
+func TestLoadOptions_Flags(t *testing.T) {
+	opts, args, err := loadOptions(
+		[]string{"--group-name", "acme.example.com", "--secure-port=443", "--api-key=flag-key", "--leader-elect", "--timeout=45s", "-v=2"},
+		envFrom(map[string]string{"API_KEY": "env-key", "GROUP_NAME": "env-group", "MODE": "controller"}),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"--secure-port=443", "-v=2"}, args)
+	assert.Equal(t, "acme.example.com", opts.GroupName)
+	assert.Equal(t, "flag-key", opts.APIKey)
+	assert.Equal(t, modeController, opts.Mode, "env applies to options without a flag")
+	assert.True(t, opts.LeaderElection)
+	assert.Equal(t, 45*time.Second, opts.OperationTimeout.Duration)
+
+	_, _, err = loadOptions([]string{"--kube-api-burst=lots"}, envFrom(nil))
+	assert.Error(t, err)
+	_, _, err = loadOptions([]string{"--api-key"}, envFrom(nil))
+	assert.Error(t, err)
+}
+
+func TestBoolOptions(t *testing.T) {
+	found := 0
+	for _, e := range envOptions {
+		if boolOptions[e.name] {
+			assert.NoError(t, e.set(&Options{}, "true"), e.name)
+			found++
+		}
+	}
+	assert.Equal(t, len(boolOptions), found, "every bool option names an envOption")
+}
+
+func TestLoadOptions_APIKeyFile(t *testing.T) {
+	opts, _, err := loadOptions([]string{"--api-key-file=/etc/bunny/api-key"}, envFrom(nil))
+	require.NoError(t, err)
+	assert.Equal(t, "/etc/bunny/api-key", opts.APIKeyFile)
+	assert.True(t, opts.hasAPIKey())
+
+	_, _, err = loadOptions(nil, envFrom(map[string]string{"API_KEY": "key", "API_KEY_FILE": "/etc/bunny/api-key"}))
+	assert.Error(t, err)
+	_, _, err = loadOptions(nil, envFrom(map[string]string{"API_KEY_FILE": "/etc/bunny/api-key", "VAULT_ADDR": "https://vault:8200"}))
+	assert.Error(t, err)
+}
+
+func TestLoadOptions_ExternalDNS(t *testing.T) {
+	opts, _, err := loadOptions(nil, envFrom(map[string]string{
+		"API_KEY":                    "key",
+		"EXTERNAL_DNS_BIND_ADDRESS":  "localhost:8888",
+		"EXTERNAL_DNS_DOMAIN_FILTER": "example.com, example.org",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "localhost:8888", opts.ExternalDNSBindAddress)
+	assert.Equal(t, []string{"example.com", "example.org"}, opts.ExternalDNSDomainFilter)
+
+	_, _, err = loadOptions([]string{"--external-dns-bind-address=localhost:8888"}, envFrom(nil))
+	assert.ErrorContains(t, err, "requires a webhook-wide API key")
+}
+
+func TestLoadOptions_GRPC(t *testing.T) {
+	env := map[string]string{
+		"GRPC_BIND_ADDRESS":       ":9090",
+		"GRPC_CERT_FILE":          "/tls/tls.crt",
+		"GRPC_KEY_FILE":           "/tls/tls.key",
+		"GRPC_CLIENT_CA_FILE":     "/tls/ca.crt",
+		"GRPC_ALLOWED_NAMESPACES": "team-a, team-b",
+	}
+	opts, _, err := loadOptions(nil, envFrom(env))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"team-a", "team-b"}, opts.GRPCAllowedNamespaces)
+
+	delete(env, "GRPC_CLIENT_CA_FILE")
+	_, _, err = loadOptions(nil, envFrom(env))
+	assert.ErrorContains(t, err, "grpcClientCAFile")
+
+	env["GRPC_CLIENT_CA_FILE"] = "/tls/ca.crt"
+	delete(env, "GRPC_ALLOWED_NAMESPACES")
+	_, _, err = loadOptions(nil, envFrom(env))
+	assert.ErrorContains(t, err, "grpcAllowedNamespaces")
+}
+
+func TestLoadOptions_Solvers(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(`solvers:
+- name: bunny-net-staging
+  apiKeyFile: /etc/bunny-staging/api-key
+  apiBase: https://staging.api.example.com
+`), 0o600))
+
+	opts, _, err := loadOptions([]string{"--config", file, "--leader-election-id=bunny"}, envFrom(nil))
+	require.NoError(t, err)
+	require.Len(t, opts.Solvers, 1)
+	staging := opts.Solvers[0].apply(opts.solverOptions())
+	assert.Equal(t, "bunny-net-staging", staging.Name)
+	assert.Equal(t, "https://staging.api.example.com", staging.APIBase)
+	assert.Equal(t, "bunny-bunny-net-staging", staging.LeaderElectionID)
+
+	for _, tc := range []struct {
+		config, err string
+	}{
+		{"solvers: [{name: bunny-net}]", `"bunny-net" is used by another solver`},
+		{"solverName: prod\nsolvers: [{name: prod}]", `"prod" is used by another solver`},
+		{"solvers: [{apiKey: key}]", "solvers[0].name must be set"},
+		{"solvers: [{name: staging, apiBase: ftp://example.com}]", "solvers[0].apiBase"},
+	} {
+		require.NoError(t, os.WriteFile(file, []byte(tc.config), 0o600))
+		_, _, err := loadOptions([]string{"--config", file}, envFrom(nil))
+		assert.ErrorContains(t, err, tc.err, tc.config)
+	}
+}
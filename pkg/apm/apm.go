@@ -0,0 +1,45 @@
+// Package apm adapts the solver's Instrumentation hooks to commercial APM
+// products. The adapters speak the products' plain protocols, so no vendor
+// SDK is needed.
+package apm
+
+import (
+	"context"
+	"time"
+
+	"github.com/cert-manager/webhook-example/pkg/solver"
+)
+
+// Multi reports to every instrumentation in order.
+func Multi(instrumentations ...solver.Instrumentation) solver.Instrumentation {
+	return multi(instrumentations)
+}
+
+type multi []solver.Instrumentation
+
+func (m multi) StartOperation(ctx context.Context, operation string, attrs map[string]string) (context.Context, func(error)) {
+	dones := make([]func(error), 0, len(m))
+	for _, i := range m {
+		var done func(error)
+		ctx, done = i.StartOperation(ctx, operation, attrs)
+		dones = append(dones, done)
+	}
+	return ctx, func(err error) {
+		for j := len(dones) - 1; j >= 0; j-- {
+			dones[j](err)
+		}
+	}
+}
+
+func (m multi) APICall(ctx context.Context, method, endpoint string, status int, duration time.Duration, err error) {
+	for _, i := range m {
+		i.APICall(ctx, method, endpoint, status, duration, err)
+	}
+}
+
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
@@ -0,0 +1,56 @@
+package apm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatadog(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pc.Close()
+
+	d, err := NewDatadog(pc.LocalAddr().String(), "env:test")
+	require.NoError(t, err)
+	defer d.Close()
+
+	d.APICall(context.Background(), http.MethodGet, "/dnszone/{id}", 404, 1500*time.Microsecond, errors.New("not found"))
+
+	buf := make([]byte, 512)
+	require.NoError(t, pc.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := pc.ReadFrom(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "bunny_webhook.api.request.duration:1.500|ms|#method:GET,endpoint:/dnszone/{id},status_code:404,result:error,env:test", string(buf[:n]))
+}
+
+func TestNewRelic(t *testing.T) {
+	var events []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "key", r.Header.Get("X-Insert-Key"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&events))
+	}))
+	defer srv.Close()
+
+	n, err := NewNewRelic("1", "key", "")
+	require.NoError(t, err)
+	n.url = srv.URL
+
+	_, done := Multi(n).StartOperation(context.Background(), "present", map[string]string{"zone": "example.com."})
+	done(nil)
+	require.NoError(t, n.Close())
+
+	require.Len(t, events, 1)
+	assert.Equal(t, "BunnyWebhookOperation", events[0]["eventType"])
+	assert.Equal(t, "present", events[0]["operation"])
+	assert.Equal(t, "example.com.", events[0]["zone"])
+	assert.Equal(t, "ok", events[0]["result"])
+}
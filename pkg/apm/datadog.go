@@ -0,0 +1,66 @@
+package apm
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const datadogMetricPrefix = "bunny_webhook."
+
+// Datadog sends operation and API call metrics to a Datadog agent over
+// DogStatsD.
+type Datadog struct {
+	conn net.Conn
+	tags []string
+}
+
+// NewDatadog returns a Datadog adapter sending to the DogStatsD listener at
+// addr (host:port, usually the node's agent on port 8125). tags are added to
+// every metric.
+func NewDatadog(addr string, tags ...string) (*Datadog, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to DogStatsD at %s: %w", addr, err)
+	}
+	return &Datadog{conn: conn, tags: tags}, nil
+}
+
+// Close closes the DogStatsD connection.
+func (d *Datadog) Close() error {
+	return d.conn.Close()
+}
+
+func (d *Datadog) StartOperation(ctx context.Context, operation string, attrs map[string]string) (context.Context, func(error)) {
+	start := time.Now()
+	return ctx, func(err error) {
+		tags := []string{"operation:" + operation, "result:" + result(err)}
+		if zone := attrs["zone"]; zone != "" {
+			tags = append(tags, "zone:"+strings.TrimSuffix(zone, "."))
+		}
+		d.send("operation.duration", time.Since(start), tags)
+	}
+}
+
+func (d *Datadog) APICall(_ context.Context, method, endpoint string, status int, duration time.Duration, err error) {
+	d.send("api.request.duration", duration, []string{
+		"method:" + method,
+		"endpoint:" + endpoint,
+		"status_code:" + strconv.Itoa(status),
+		"result:" + result(err),
+	})
+}
+
+// send reports a timing, from which the agent also derives a count.
+// DogStatsD is fire and forget, so errors are dropped.
+func (d *Datadog) send(name string, duration time.Duration, tags []string) {
+	_, _ = d.conn.Write([]byte(datadogPacket(name, duration, append(tags, d.tags...))))
+}
+
+func datadogPacket(name string, duration time.Duration, tags []string) string {
+	ms := strconv.FormatFloat(float64(duration)/float64(time.Millisecond), 'f', 3, 64)
+	return datadogMetricPrefix + name + ":" + ms + "|ms|#" + strings.Join(tags, ",")
+}
@@ -0,0 +1,158 @@
+package apm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	newRelicFlushInterval = 10 * time.Second
+	newRelicMaxBatch      = 1000
+)
+
+// NewRelic records operations and API calls as New Relic custom events
+// (BunnyWebhookOperation and BunnyWebhookAPICall) through the Event API.
+type NewRelic struct {
+	url       string
+	insertKey string
+	client    *http.Client
+
+	mu     sync.Mutex
+	events []map[string]interface{}
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewNewRelic returns a New Relic adapter for accountID using an insert key.
+// region is "us" (the default) or "eu". Events are sent in batches every ten
+// seconds until Close.
+func NewNewRelic(accountID, insertKey, region string) (*NewRelic, error) {
+	if accountID == "" || insertKey == "" {
+		return nil, fmt.Errorf("a New Relic account ID and insert key are required")
+	}
+	host := "insights-collector.newrelic.com"
+	switch strings.ToLower(region) {
+	case "", "us":
+	case "eu":
+		host = "insights-collector.eu01.nr-data.net"
+	default:
+		return nil, fmt.Errorf("unknown New Relic region %q", region)
+	}
+
+	n := &NewRelic{
+		url:       fmt.Sprintf("https://%s/v1/accounts/%s/events", host, accountID),
+		insertKey: insertKey,
+		client:    &http.Client{Timeout: 30 * time.Second},
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go n.run()
+	return n, nil
+}
+
+func (n *NewRelic) StartOperation(ctx context.Context, operation string, attrs map[string]string) (context.Context, func(error)) {
+	start := time.Now()
+	return ctx, func(err error) {
+		event := map[string]interface{}{
+			"eventType":  "BunnyWebhookOperation",
+			"operation":  operation,
+			"result":     result(err),
+			"durationMs": time.Since(start).Milliseconds(),
+		}
+		for k, v := range attrs {
+			event[k] = v
+		}
+		if err != nil {
+			event["error"] = err.Error()
+		}
+		n.record(event)
+	}
+}
+
+func (n *NewRelic) APICall(_ context.Context, method, endpoint string, status int, duration time.Duration, err error) {
+	event := map[string]interface{}{
+		"eventType":  "BunnyWebhookAPICall",
+		"method":     method,
+		"endpoint":   endpoint,
+		"statusCode": status,
+		"result":     result(err),
+		"durationMs": duration.Milliseconds(),
+	}
+	if err != nil {
+		event["error"] = err.Error()
+	}
+	n.record(event)
+}
+
+func (n *NewRelic) record(event map[string]interface{}) {
+	event["timestamp"] = time.Now().Unix()
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if len(n.events) < newRelicMaxBatch {
+		n.events = append(n.events, event)
+	}
+}
+
+// Close sends the remaining events and stops the background sender.
+func (n *NewRelic) Close() error {
+	close(n.stop)
+	<-n.done
+	return n.flush(context.Background())
+}
+
+func (n *NewRelic) run() {
+	defer close(n.done)
+	ticker := time.NewTicker(newRelicFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-n.stop:
+			return
+		case <-ticker.C:
+			if err := n.flush(context.Background()); err != nil {
+				slog.Error("failed to send events to New Relic", "error", err)
+			}
+		}
+	}
+}
+
+func (n *NewRelic) flush(ctx context.Context) error {
+	n.mu.Lock()
+	events := n.events
+	n.events = nil
+	n.mu.Unlock()
+	if len(events) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(events)
+	if err != nil {
+		return fmt.Errorf("failed to marshal events: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Insert-Key", n.insertKey)
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("event API returned status %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+// Package bunny is a client for the zone and record operations of the
+// Bunny DNS API.
+package bunny
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// DefaultBaseURL is the public Bunny API.
+const DefaultBaseURL = "https://api.bunny.net"
+
+// Record types, as numbered by the Bunny API.
+const (
+	RecordTypeA     = 0
+	RecordTypeAAAA  = 1
+	RecordTypeCNAME = 2
+	RecordTypeTXT   = 3
+)
+
+// Ways of sending the API key.
+const (
+	// AuthSchemeAccessKey sends the key in the AccessKey header, as the
+	// current Bunny API expects.
+	AuthSchemeAccessKey = "AccessKey"
+	// AuthSchemeBearer sends the key as an Authorization bearer token, for
+	// token-based Bunny APIs and gateways in front of the API.
+	AuthSchemeBearer = "Bearer"
+)
+
+// ZoneList is a page of zones.
+type ZoneList struct {
+	Items        []Zone `json:"Items"`
+	CurrentPage  int    `json:"CurrentPage"`
+	TotalItems   int    `json:"TotalItems"`
+	HasMoreItems bool   `json:"HasMoreItems"`
+}
+
+// Zone is a DNS zone. Records are only filled in by GetZone.
+type Zone struct {
+	ID      int      `json:"Id"`
+	Domain  string   `json:"Domain"`
+	Records []Record `json:"Records"`
+}
+
+// Record is a DNS record. Name is relative to the zone, empty for the apex.
+// Comment is a free-form note Bunny keeps with the record.
+type Record struct {
+	ID       int    `json:"Id,omitempty"`
+	Type     int    `json:"Type,omitempty"`
+	Ttl      int    `json:"Ttl,omitempty"`
+	Value    string `json:"Value,omitempty"`
+	Name     string `json:"Name"`
+	Disabled bool   `json:"Disabled,omitempty"`
+	Comment  string `json:"Comment,omitempty"`
+}
+
+// Client performs zone and record operations. Failed API calls return an
+// *APIError.
+type Client interface {
+	// ListZones returns one page of the account's zones, filtered by search
+	// if it isn't empty. Bunny matches search against substrings of the
+	// zone names.
+	ListZones(ctx context.Context, search string, page, perPage int) (ZoneList, error)
+	// GetZone returns the zone with the given ID, including its records.
+	GetZone(ctx context.Context, zoneID int64) (Zone, error)
+	// ListRecords returns the records of the zone with the given ID.
+	ListRecords(ctx context.Context, zoneID int64) ([]Record, error)
+	// CreateRecord adds record to the zone and returns it as created.
+	CreateRecord(ctx context.Context, zoneID int64, record Record) (Record, error)
+	// UpdateRecord overwrites the record with record.ID.
+	UpdateRecord(ctx context.Context, zoneID int64, record Record) error
+	// DeleteRecord deletes a record.
+	DeleteRecord(ctx context.Context, zoneID int64, recordID int) error
+}
+
+// ErrMalformedResponse is returned, wrapped, when a successful response
+// can't be decoded. For CreateRecord the record was created regardless.
+var ErrMalformedResponse = errors.New("malformed API response")
+
+// Doer sends HTTP requests. *http.Client is one.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// DoerFunc adapts a function to Doer.
+type DoerFunc func(req *http.Request) (*http.Response, error)
+
+func (f DoerFunc) Do(req *http.Request) (*http.Response, error) { return f(req) }
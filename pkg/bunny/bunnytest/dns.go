@@ -0,0 +1,92 @@
+package bunnytest
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/miekg/dns"
+
+	"github.com/cert-manager/webhook-example/pkg/bunny"
+)
+
+// StartDNS serves the fake's TXT records over DNS on a local UDP port and
+// returns its address, so that propagation checks can run against it. The
+// server is stopped by Close.
+func (s *Server) StartDNS() (string, error) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("failed to listen for DNS: %w", err)
+	}
+	started := make(chan struct{})
+	srv := &dns.Server{PacketConn: conn, Handler: dns.HandlerFunc(s.serveDNS), NotifyStartedFunc: func() { close(started) }}
+	go func() { _ = srv.ActivateAndServe() }()
+	<-started
+
+	s.mu.Lock()
+	s.dns = append(s.dns, srv)
+	s.mu.Unlock()
+	return conn.LocalAddr().String(), nil
+}
+
+func (s *Server) serveDNS(w dns.ResponseWriter, req *dns.Msg) {
+	msg := new(dns.Msg)
+	msg.SetReply(req)
+	msg.Authoritative = true
+	if len(req.Question) != 1 {
+		msg.Rcode = dns.RcodeFormatError
+		_ = w.WriteMsg(msg)
+		return
+	}
+	q := req.Question[0]
+
+	s.mu.Lock()
+	zone := s.zoneFor(q.Name)
+	var records []bunny.Record
+	if zone != nil {
+		records = append(records, zone.Records...)
+	}
+	s.mu.Unlock()
+
+	if zone == nil {
+		msg.Rcode = dns.RcodeRefused
+		_ = w.WriteMsg(msg)
+		return
+	}
+	name := strings.TrimSuffix(strings.TrimSuffix(strings.ToLower(q.Name), "."), zone.Domain)
+	name = strings.TrimSuffix(name, ".")
+
+	found := false
+	for _, r := range records {
+		if !strings.EqualFold(r.Name, name) || r.Disabled {
+			continue
+		}
+		found = true
+		if r.Type == bunny.RecordTypeTXT && q.Qtype == dns.TypeTXT {
+			msg.Answer = append(msg.Answer, &dns.TXT{
+				Hdr: dns.RR_Header{Name: q.Name, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: uint32(r.Ttl)},
+				Txt: []string{r.Value},
+			})
+		}
+	}
+	if !found && name != "" {
+		msg.Rcode = dns.RcodeNameError
+	}
+	_ = w.WriteMsg(msg)
+}
+
+// zoneFor returns the most specific zone containing fqdn. s.mu must be held.
+func (s *Server) zoneFor(fqdn string) *bunny.Zone {
+	fqdn = strings.TrimSuffix(strings.ToLower(fqdn), ".")
+	var best *bunny.Zone
+	for _, zone := range s.zones {
+		domain := strings.ToLower(zone.Domain)
+		if fqdn != domain && !strings.HasSuffix(fqdn, "."+domain) {
+			continue
+		}
+		if best == nil || len(domain) > len(best.Domain) {
+			best = zone
+		}
+	}
+	return best
+}
@@ -0,0 +1,36 @@
+package bunnytest
+
+import (
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cert-manager/webhook-example/pkg/bunny"
+)
+
+func TestStartDNS(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	zone := s.AddZone("example.com")
+	s.AddRecord(zone, bunny.Record{Type: bunny.RecordTypeTXT, Name: "_acme-challenge", Value: "token", Ttl: 60})
+
+	addr, err := s.StartDNS()
+	require.NoError(t, err)
+
+	query := func(name string) *dns.Msg {
+		msg := new(dns.Msg)
+		msg.SetQuestion(name, dns.TypeTXT)
+		resp, _, err := new(dns.Client).Exchange(msg, addr)
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := query("_acme-challenge.EXAMPLE.com.")
+	require.Len(t, resp.Answer, 1)
+	assert.Equal(t, []string{"token"}, resp.Answer[0].(*dns.TXT).Txt)
+
+	assert.Equal(t, dns.RcodeNameError, query("other.example.com.").Rcode)
+	assert.Equal(t, dns.RcodeRefused, query("example.org.").Rcode)
+}
@@ -0,0 +1,282 @@
+// Package bunnytest provides an in-memory fake of the Bunny DNS API for
+// tests. It implements the zone and record endpoints used by package bunny,
+// including search pagination and Bunny's JSON error responses.
+package bunnytest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/miekg/dns"
+
+	"github.com/cert-manager/webhook-example/pkg/bunny"
+)
+
+// Server is a fake Bunny API. It is safe for concurrent use.
+type Server struct {
+	// URL is the base URL of the fake, to be used as the API base.
+	URL string
+
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	apiKey   string
+	zones    map[int]*bunny.Zone
+	nextID   int
+	failures []failure
+	requests int
+	dns      []*dns.Server
+}
+
+type failure struct {
+	status int
+	body   bunnyError
+}
+
+type bunnyError struct {
+	ErrorKey string `json:"ErrorKey"`
+	Field    string `json:"Field"`
+	Message  string `json:"Message"`
+}
+
+// NewServer starts a fake with no zones. Close it when done.
+func NewServer() *Server {
+	s := &Server{zones: map[int]*bunny.Zone{}, nextID: 1}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /dnszone", s.listZones)
+	mux.HandleFunc("GET /dnszone/{zone}", s.getZone)
+	mux.HandleFunc("PUT /dnszone/{zone}/records", s.createRecord)
+	mux.HandleFunc("POST /dnszone/{zone}/records/{record}", s.updateRecord)
+	mux.HandleFunc("DELETE /dnszone/{zone}/records/{record}", s.deleteRecord)
+	s.srv = httptest.NewServer(s.middleware(mux))
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close shuts the fake and any DNS servers started with StartDNS down.
+func (s *Server) Close() {
+	s.srv.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, srv := range s.dns {
+		_ = srv.Shutdown()
+	}
+}
+
+// RequireAPIKey makes requests without key in the AccessKey header, or as a
+// bearer token, fail with 401.
+func (s *Server) RequireAPIKey(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.apiKey = key
+}
+
+// AddZone adds a zone and returns its ID.
+func (s *Server) AddZone(domain string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.nextID
+	s.nextID++
+	s.zones[id] = &bunny.Zone{ID: id, Domain: strings.TrimSuffix(domain, ".")}
+	return id
+}
+
+// AddRecord adds a record to a zone as if created through the API.
+func (s *Server) AddRecord(zoneID int, record bunny.Record) bunny.Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addRecord(s.zones[zoneID], record)
+}
+
+func (s *Server) addRecord(zone *bunny.Zone, record bunny.Record) bunny.Record {
+	record.ID = s.nextID
+	s.nextID++
+	zone.Records = append(zone.Records, record)
+	return record
+}
+
+// Records returns the records of a zone.
+func (s *Server) Records(zoneID int) []bunny.Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	zone, ok := s.zones[zoneID]
+	if !ok {
+		return nil
+	}
+	return append([]bunny.Record(nil), zone.Records...)
+}
+
+// FailNext makes the next n requests fail with status and a Bunny error
+// body with errorKey.
+func (s *Server) FailNext(n, status int, errorKey string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i < n; i++ {
+		s.failures = append(s.failures, failure{status: status, body: bunnyError{ErrorKey: errorKey, Message: "injected failure"}})
+	}
+}
+
+// Requests returns the number of requests received.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func (s *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests++
+		apiKey := s.apiKey
+		var fail *failure
+		if len(s.failures) > 0 {
+			fail = &s.failures[0]
+			s.failures = s.failures[1:]
+		}
+		s.mu.Unlock()
+
+		if apiKey != "" && r.Header.Get("AccessKey") != apiKey && r.Header.Get("Authorization") != "Bearer "+apiKey {
+			writeError(w, http.StatusUnauthorized, bunnyError{ErrorKey: "authorization.invalid", Message: "The request authorization failed."})
+			return
+		}
+		if fail != nil {
+			writeError(w, fail.status, fail.body)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) listZones(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	perPage, _ := strconv.Atoi(query.Get("perPage"))
+	if perPage < 1 || perPage > 1000 {
+		perPage = 1000
+	}
+	search := strings.ToLower(query.Get("search"))
+
+	s.mu.Lock()
+	var matches []bunny.Zone
+	for _, zone := range s.zones {
+		if strings.Contains(strings.ToLower(zone.Domain), search) {
+			matches = append(matches, bunny.Zone{ID: zone.ID, Domain: zone.Domain})
+		}
+	}
+	s.mu.Unlock()
+	sort.Slice(matches, func(i, j int) bool { return matches[i].ID < matches[j].ID })
+
+	list := bunny.ZoneList{CurrentPage: page, TotalItems: len(matches), Items: []bunny.Zone{}}
+	if start := (page - 1) * perPage; start < len(matches) {
+		end := min(start+perPage, len(matches))
+		list.Items = matches[start:end]
+		list.HasMoreItems = end < len(matches)
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Server) getZone(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	zone, ok := s.zone(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, zone)
+}
+
+func (s *Server) createRecord(w http.ResponseWriter, r *http.Request) {
+	var record bunny.Record
+	if err := json.NewDecoder(r.Body).Decode(&record); err != nil {
+		writeError(w, http.StatusBadRequest, bunnyError{ErrorKey: "dnszone.record.invalid", Message: "The request body is invalid."})
+		return
+	}
+	if record.Type == bunny.RecordTypeTXT && record.Value == "" {
+		writeError(w, http.StatusBadRequest, bunnyError{ErrorKey: "dnszone.record.invalid", Field: "Value", Message: "The value is required."})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	zone, ok := s.zone(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusCreated, s.addRecord(zone, record))
+}
+
+func (s *Server) updateRecord(w http.ResponseWriter, r *http.Request) {
+	var record bunny.Record
+	if err := json.NewDecoder(r.Body).Decode(&record); err != nil {
+		writeError(w, http.StatusBadRequest, bunnyError{ErrorKey: "dnszone.record.invalid", Message: "The request body is invalid."})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	zone, ok := s.zone(w, r)
+	if !ok {
+		return
+	}
+	i, ok := recordIndex(w, r, zone)
+	if !ok {
+		return
+	}
+	record.ID = zone.Records[i].ID
+	zone.Records[i] = record
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) deleteRecord(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	zone, ok := s.zone(w, r)
+	if !ok {
+		return
+	}
+	i, ok := recordIndex(w, r, zone)
+	if !ok {
+		return
+	}
+	zone.Records = append(zone.Records[:i], zone.Records[i+1:]...)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// zone returns the zone named in the request path. s.mu must be held.
+func (s *Server) zone(w http.ResponseWriter, r *http.Request) (*bunny.Zone, bool) {
+	id, _ := strconv.Atoi(r.PathValue("zone"))
+	zone, ok := s.zones[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, bunnyError{ErrorKey: "dnszone.not_found", Message: "The requested DNS zone was not found."})
+	}
+	return zone, ok
+}
+
+func recordIndex(w http.ResponseWriter, r *http.Request, zone *bunny.Zone) (int, bool) {
+	id, _ := strconv.Atoi(r.PathValue("record"))
+	for i, record := range zone.Records {
+		if record.ID == id {
+			return i, true
+		}
+	}
+	writeError(w, http.StatusNotFound, bunnyError{ErrorKey: "dnszone.record.not_found", Message: "The requested DNS record was not found."})
+	return 0, false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, e bunnyError) {
+	writeJSON(w, status, e)
+}
@@ -0,0 +1,142 @@
+package bunny
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options configures an HTTP client.
+type Options struct {
+	// BaseURL is the API endpoint, DefaultBaseURL when empty.
+	BaseURL string
+
+	// APIKey is sent with AuthScheme, AuthSchemeAccessKey when empty. Leave
+	// it empty when the Doer authenticates requests itself.
+	APIKey     string
+	AuthScheme string
+
+	// UserAgent, if set, is sent with every request.
+	UserAgent string
+
+	// Doer sends the requests, an http.Client with a 30s timeout when nil.
+	Doer Doer
+}
+
+type httpClient struct {
+	opts Options
+}
+
+// New returns a Client talking to the Bunny API over HTTP.
+func New(opts Options) Client {
+	if opts.BaseURL == "" {
+		opts.BaseURL = DefaultBaseURL
+	}
+	opts.BaseURL = strings.TrimSuffix(opts.BaseURL, "/")
+	if opts.Doer == nil {
+		opts.Doer = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &httpClient{opts: opts}
+}
+
+func (c *httpClient) ListZones(ctx context.Context, search string, page, perPage int) (ZoneList, error) {
+	query := url.Values{}
+	query.Set("page", strconv.Itoa(page))
+	query.Set("perPage", strconv.Itoa(perPage))
+	if search != "" {
+		query.Set("search", search)
+	}
+	var zones ZoneList
+	err := c.call(ctx, http.MethodGet, "/dnszone?"+query.Encode(), nil, &zones)
+	return zones, err
+}
+
+func (c *httpClient) GetZone(ctx context.Context, zoneID int64) (Zone, error) {
+	var zone Zone
+	err := c.call(ctx, http.MethodGet, fmt.Sprintf("/dnszone/%d", zoneID), nil, &zone)
+	return zone, err
+}
+
+func (c *httpClient) ListRecords(ctx context.Context, zoneID int64) ([]Record, error) {
+	zone, err := c.GetZone(ctx, zoneID)
+	return zone.Records, err
+}
+
+func (c *httpClient) CreateRecord(ctx context.Context, zoneID int64, record Record) (Record, error) {
+	var created Record
+	err := c.call(ctx, http.MethodPut, fmt.Sprintf("/dnszone/%d/records", zoneID), record, &created)
+	return created, err
+}
+
+func (c *httpClient) UpdateRecord(ctx context.Context, zoneID int64, record Record) error {
+	return c.call(ctx, http.MethodPost, fmt.Sprintf("/dnszone/%d/records/%d", zoneID, record.ID), record, nil)
+}
+
+func (c *httpClient) DeleteRecord(ctx context.Context, zoneID int64, recordID int) error {
+	return c.call(ctx, http.MethodDelete, fmt.Sprintf("/dnszone/%d/records/%d", zoneID, recordID), nil, nil)
+}
+
+// call sends a request with in as the JSON body, if not nil, and decodes
+// the response into out, if not nil.
+func (c *httpClient) call(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.opts.BaseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.opts.UserAgent != "" {
+		req.Header.Set("User-Agent", c.opts.UserAgent)
+	}
+	if c.opts.APIKey != "" {
+		Authorize(req, c.opts.APIKey, c.opts.AuthScheme)
+	}
+
+	resp, err := c.opts.Doer.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		return newAPIError(resp, data)
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("%w: %v", ErrMalformedResponse, err)
+		}
+	}
+	return nil
+}
+
+// Authorize adds apiKey to req according to scheme, AuthSchemeAccessKey
+// when empty.
+func Authorize(req *http.Request, apiKey, scheme string) {
+	switch scheme {
+	case AuthSchemeBearer:
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	default:
+		req.Header.Set("AccessKey", apiKey)
+	}
+}
@@ -0,0 +1,70 @@
+package bunny
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	records := map[int]Record{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /dnszone", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "example.com", r.URL.Query().Get("search"))
+		json.NewEncoder(w).Encode(ZoneList{Items: []Zone{{ID: 7, Domain: "example.com"}}, TotalItems: 1})
+	})
+	mux.HandleFunc("GET /dnszone/7", func(w http.ResponseWriter, r *http.Request) {
+		zone := Zone{ID: 7, Domain: "example.com"}
+		for _, rec := range records {
+			zone.Records = append(zone.Records, rec)
+		}
+		json.NewEncoder(w).Encode(zone)
+	})
+	mux.HandleFunc("PUT /dnszone/7/records", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret-key", r.Header.Get("AccessKey"))
+		assert.Equal(t, "test-agent", r.Header.Get("User-Agent"))
+		var rec Record
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&rec))
+		rec.ID = len(records) + 1
+		records[rec.ID] = rec
+		json.NewEncoder(w).Encode(rec)
+	})
+	mux.HandleFunc("DELETE /dnszone/7/records/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := records[1]; !ok || r.PathValue("id") != "1" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(records, 1)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := New(Options{BaseURL: srv.URL + "/", APIKey: "secret-key", UserAgent: "test-agent"})
+
+	zones, err := c.ListZones(ctx, "example.com", 1, 10)
+	require.NoError(t, err)
+	require.Len(t, zones.Items, 1)
+	assert.Equal(t, 7, zones.Items[0].ID)
+
+	created, err := c.CreateRecord(ctx, 7, Record{Type: RecordTypeTXT, Name: "_acme-challenge", Value: "token", Ttl: 60})
+	require.NoError(t, err)
+	assert.Equal(t, 1, created.ID)
+
+	recs, err := c.ListRecords(ctx, 7)
+	require.NoError(t, err)
+	assert.Equal(t, []Record{created}, recs)
+
+	require.NoError(t, c.DeleteRecord(ctx, 7, created.ID))
+	err = c.DeleteRecord(ctx, 7, created.ID)
+	assert.True(t, IsNotFound(err), "got %v", err)
+
+	_, err = c.GetZone(ctx, 8)
+	assert.True(t, IsNotFound(err), "got %v", err)
+}
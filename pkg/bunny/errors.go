@@ -0,0 +1,70 @@
+package bunny
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// maxErrorBody bounds the raw body kept in an APIError, in case a proxy in
+// front of Bunny answers with a whole HTML page.
+const maxErrorBody = 512
+
+// APIError is an error response of the Bunny API. Bunny describes errors in
+// a JSON body with ErrorKey, Field and Message; responses that aren't in
+// that format, e.g. from a proxy, only keep their raw Body.
+type APIError struct {
+	StatusCode int
+
+	// ErrorKey identifies the error, e.g. "dnszone.record.limit_reached".
+	ErrorKey string `json:"ErrorKey"`
+	// Field is the request field the error is about, if any.
+	Field   string `json:"Field"`
+	Message string `json:"Message"`
+
+	// Body is the response body when it isn't a Bunny error.
+	Body string `json:"-"`
+}
+
+// IsNotFound reports whether err is an APIError for a missing zone or
+// record.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// newAPIError parses the error response resp with the already read body.
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	e := &APIError{StatusCode: resp.StatusCode}
+	if err := json.Unmarshal(body, e); err != nil || (e.ErrorKey == "" && e.Message == "") {
+		e.ErrorKey, e.Field, e.Message = "", "", ""
+		e.Body = strings.TrimSpace(string(body))
+		if len(e.Body) > maxErrorBody {
+			e.Body = e.Body[:maxErrorBody] + "..."
+		}
+	}
+	return e
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("API request failed with status %d", e.StatusCode)
+	switch {
+	case e.Message != "" || e.ErrorKey != "":
+		msg += ": " + e.Message
+		var details []string
+		if e.ErrorKey != "" {
+			details = append(details, e.ErrorKey)
+		}
+		if e.Field != "" {
+			details = append(details, "field "+e.Field)
+		}
+		if len(details) > 0 {
+			msg += " (" + strings.Join(details, ", ") + ")"
+		}
+	case e.Body != "":
+		msg += ": " + e.Body
+	}
+	return msg
+}
@@ -0,0 +1,31 @@
+package bunny
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAPIError(t *testing.T) {
+	resp := &http.Response{StatusCode: http.StatusBadRequest}
+	err := newAPIError(resp, []byte(`{"ErrorKey":"dnszone.record.limit_reached","Field":"Value","Message":"The record limit was reached."}`))
+	assert.Equal(t, "dnszone.record.limit_reached", err.ErrorKey)
+	assert.Equal(t, "Value", err.Field)
+	assert.Equal(t, "API request failed with status 400: The record limit was reached. (dnszone.record.limit_reached, field Value)", err.Error())
+
+	var apiErr *APIError
+	require.True(t, errors.As(fmt.Errorf("failed to update record 1: %w", err), &apiErr))
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+
+	err = newAPIError(&http.Response{StatusCode: http.StatusBadGateway}, []byte("<html>"+strings.Repeat("x", 1000)+"</html>"))
+	assert.Empty(t, err.ErrorKey)
+	assert.Len(t, err.Body, maxErrorBody+len("..."))
+
+	assert.True(t, IsNotFound(fmt.Errorf("failed to get zone: %w", newAPIError(&http.Response{StatusCode: http.StatusNotFound}, nil))))
+	assert.False(t, IsNotFound(err))
+}
@@ -0,0 +1,105 @@
+package bunny
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultStorageEndpoint is the Edge Storage API of the main (Falkenstein)
+// region. Storage zones in other regions use their regional endpoint, e.g.
+// https://ny.storage.bunnycdn.com.
+const DefaultStorageEndpoint = "https://storage.bunnycdn.com"
+
+// StorageOptions configures a Storage client.
+type StorageOptions struct {
+	// Endpoint is the storage region's API, DefaultStorageEndpoint when
+	// empty.
+	Endpoint string
+	// Zone is the name of the storage zone and Password its password, which
+	// is not the account API key.
+	Zone     string
+	Password string
+
+	// UserAgent, if set, is sent with every request.
+	UserAgent string
+
+	// Doer sends the requests, an http.Client with a 30s timeout when nil.
+	Doer Doer
+}
+
+// Storage uploads and deletes files in a Bunny Edge Storage zone.
+type Storage struct {
+	opts StorageOptions
+}
+
+// NewStorage returns a Storage client for the zone in opts.
+func NewStorage(opts StorageOptions) *Storage {
+	if opts.Endpoint == "" {
+		opts.Endpoint = DefaultStorageEndpoint
+	}
+	opts.Endpoint = strings.TrimSuffix(opts.Endpoint, "/")
+	if opts.Doer == nil {
+		opts.Doer = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &Storage{opts: opts}
+}
+
+// Upload stores content at path, relative to the root of the zone,
+// replacing any existing file.
+func (s *Storage) Upload(ctx context.Context, path string, content []byte) error {
+	return s.call(ctx, http.MethodPut, path, content)
+}
+
+// Delete removes the file at path. Missing files return an *APIError for
+// which IsNotFound is true.
+func (s *Storage) Delete(ctx context.Context, path string) error {
+	return s.call(ctx, http.MethodDelete, path, nil)
+}
+
+func (s *Storage) call(ctx context.Context, method, path string, content []byte) error {
+	u := s.opts.Endpoint + "/" + url.PathEscape(s.opts.Zone) + "/" + escapePath(strings.TrimPrefix(path, "/"))
+	var body io.Reader
+	if content != nil {
+		body = bytes.NewReader(content)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("AccessKey", s.opts.Password)
+	if content != nil {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	if s.opts.UserAgent != "" {
+		req.Header.Set("User-Agent", s.opts.UserAgent)
+	}
+
+	resp, err := s.opts.Doer.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		return newAPIError(resp, data)
+	}
+	return nil
+}
+
+// escapePath escapes each segment of path.
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
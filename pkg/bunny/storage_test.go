@@ -0,0 +1,43 @@
+package bunny
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorage(t *testing.T) {
+	files := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "zone-password", r.Header.Get("AccessKey"))
+		switch r.Method {
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			files[r.URL.Path] = string(body)
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodDelete:
+			if _, ok := files[r.URL.Path]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"HttpCode":404,"Message":"Object Not Found"}`))
+				return
+			}
+			delete(files, r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	s := NewStorage(StorageOptions{Endpoint: srv.URL + "/", Zone: "acme", Password: "zone-password"})
+	require.NoError(t, s.Upload(ctx, "/.well-known/acme-challenge/token", []byte("token.thumbprint")))
+	assert.Equal(t, map[string]string{"/acme/.well-known/acme-challenge/token": "token.thumbprint"}, files)
+
+	require.NoError(t, s.Delete(ctx, ".well-known/acme-challenge/token"))
+	err := s.Delete(ctx, ".well-known/acme-challenge/token")
+	assert.True(t, IsNotFound(err))
+	assert.ErrorContains(t, err, "Object Not Found")
+}
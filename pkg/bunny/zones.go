@@ -0,0 +1,103 @@
+package bunny
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// ZoneSearchPageSize is the page size FindZone paginates searches with.
+const ZoneSearchPageSize = 100
+
+// maxSimilarZones bounds the near-miss zones named when a zone isn't found.
+const maxSimilarZones = 5
+
+// ErrZoneNotFound is returned, wrapped, for zones not in the account.
+var ErrZoneNotFound = errors.New("no DNS zone found")
+
+// FindZone returns the zone named domain. Bunny matches searches against
+// substrings, so the zone may be on any page of the results, behind zones
+// that merely contain its name. Only an exact, case-insensitive match is
+// returned; records must never end up in myexample.com for example.com.
+// The error for a missing zone wraps ErrZoneNotFound and names a few of
+// the similar zones.
+func FindZone(ctx context.Context, c Client, domain string) (Zone, error) {
+	domain, err := ToASCII(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+	if err != nil {
+		return Zone{}, err
+	}
+
+	var similar []string
+	for page := 1; ; page++ {
+		list, err := c.ListZones(ctx, domain, page, ZoneSearchPageSize)
+		if err != nil {
+			return Zone{}, err
+		}
+		for _, zone := range list.Items {
+			if fold(zone.Domain) == domain {
+				return zone, nil
+			}
+			if len(similar) < maxSimilarZones {
+				similar = append(similar, zone.Domain)
+			}
+		}
+		// An empty page ends the search even if the API claims there are
+		// more, rather than asking for pages forever.
+		if !list.HasMoreItems || len(list.Items) == 0 {
+			break
+		}
+	}
+	if len(similar) > 0 {
+		return Zone{}, fmt.Errorf("%w for %s, only similarly named zones: %s", ErrZoneNotFound, domain, strings.Join(similar, ", "))
+	}
+	return Zone{}, fmt.Errorf("%w for %s", ErrZoneNotFound, domain)
+}
+
+// ToASCII returns name lowercased and with its internationalized labels in
+// punycode, the form Bunny stores zones and records in.
+func ToASCII(name string) (string, error) {
+	ascii, err := idna.Punycode.ToASCII(strings.ToLower(name))
+	if err != nil {
+		return "", fmt.Errorf("failed to convert %s to punycode: %w", name, err)
+	}
+	return ascii, nil
+}
+
+// RecordName returns the name of the record for fqdn relative to zone, as
+// Bunny expects it. Both must already be in punycode. Bunny names the zone
+// apex with an empty name, which challenges for a delegated _acme-challenge
+// zone end up at.
+func RecordName(zone, fqdn string) string {
+	fqdn, zone = fold(fqdn), fold(zone)
+	if fqdn == zone {
+		return ""
+	}
+	return strings.TrimSuffix(fqdn, "."+zone)
+}
+
+// SameRecordName reports whether the record name Bunny returned is name.
+// The apex is listed as "@" by some API versions.
+func SameRecordName(got, name string) bool {
+	return strings.EqualFold(got, name) || name == "" && got == "@"
+}
+
+// FindTXTRecord returns the TXT record for fqdn with the given value among
+// the records of zone, if there is one.
+func FindTXTRecord(records []Record, zone, fqdn, value string) (Record, bool) {
+	name := RecordName(zone, fqdn)
+	for _, record := range records {
+		if record.Type == RecordTypeTXT && SameRecordName(record.Name, name) && record.Value == value {
+			return record, true
+		}
+	}
+	return Record{}, false
+}
+
+// fold returns name lowercased and without surrounding whitespace or the
+// trailing dot, for comparisons.
+func fold(name string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(name), "."))
+}
@@ -0,0 +1,80 @@
+package bunny
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// zonePages serves ListZones from fixed pages and counts the requests.
+type zonePages struct {
+	Client
+	pages    []ZoneList
+	searches []string
+}
+
+func (z *zonePages) ListZones(ctx context.Context, search string, page, perPage int) (ZoneList, error) {
+	z.searches = append(z.searches, search)
+	if page > len(z.pages) {
+		return ZoneList{}, fmt.Errorf("page %d requested", page)
+	}
+	return z.pages[page-1], nil
+}
+
+func TestFindZone(t *testing.T) {
+	c := &zonePages{pages: []ZoneList{
+		{Items: []Zone{{ID: 1, Domain: "myexample.com"}, {ID: 2, Domain: "example.com.au"}}, HasMoreItems: true},
+		{Items: []Zone{{ID: 3, Domain: "Example.com"}}},
+	}}
+	zone, err := FindZone(context.Background(), c, "example.COM.")
+	require.NoError(t, err)
+	assert.Equal(t, 3, zone.ID)
+	assert.Equal(t, []string{"example.com", "example.com"}, c.searches)
+
+	c = &zonePages{pages: []ZoneList{{Items: []Zone{{ID: 1, Domain: "xn--bcher-kva.example"}}}}}
+	zone, err = FindZone(context.Background(), c, "bücher.example")
+	require.NoError(t, err)
+	assert.Equal(t, 1, zone.ID)
+	assert.Equal(t, []string{"xn--bcher-kva.example"}, c.searches, "searches are in punycode")
+}
+
+func TestFindZone_NotFound(t *testing.T) {
+	c := &zonePages{pages: []ZoneList{{Items: []Zone{{ID: 1, Domain: "myexample.com"}}}}}
+	_, err := FindZone(context.Background(), c, "example.com")
+	assert.ErrorIs(t, err, ErrZoneNotFound)
+	assert.EqualError(t, err, "no DNS zone found for example.com, only similarly named zones: myexample.com")
+
+	// An empty page ends the search although more items are promised.
+	c = &zonePages{pages: []ZoneList{{Items: []Zone{}, HasMoreItems: true}}}
+	_, err = FindZone(context.Background(), c, "example.com")
+	assert.EqualError(t, err, "no DNS zone found for example.com")
+	assert.Len(t, c.searches, 1)
+}
+
+func TestRecordName(t *testing.T) {
+	assert.Equal(t, "_acme-challenge.www", RecordName("example.com.", "_acme-challenge.www.example.com."))
+	assert.Equal(t, "_acme-challenge.www", RecordName("Example.com.", "_acme-challenge.www.example.COM"))
+	assert.Equal(t, "", RecordName("_acme-challenge.example.com.", "_acme-challenge.example.com."))
+	assert.Equal(t, "", RecordName("_acme-challenge.example.com.", "_acme-challenge.example.com"))
+}
+
+func TestFindTXTRecord(t *testing.T) {
+	records := []Record{
+		{ID: 1, Type: RecordTypeA, Name: "_acme-challenge", Value: "token"},
+		{ID: 2, Type: RecordTypeTXT, Name: "_ACME-Challenge", Value: "token"},
+		{ID: 3, Type: RecordTypeTXT, Name: "@", Value: "token"},
+	}
+	record, ok := FindTXTRecord(records, "example.com.", "_acme-challenge.example.com.", "token")
+	require.True(t, ok)
+	assert.Equal(t, 2, record.ID)
+
+	record, ok = FindTXTRecord(records, "_acme-challenge.example.com.", "_acme-challenge.example.com.", "token")
+	require.True(t, ok)
+	assert.Equal(t, 3, record.ID, "the apex may be listed as @")
+
+	_, ok = FindTXTRecord(records, "example.com.", "_acme-challenge.example.com.", "other")
+	assert.False(t, ok)
+}
@@ -0,0 +1,88 @@
+package bunnylego
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/cert-manager/webhook-example/pkg/bunny"
+)
+
+// ChallengePath returns the path, relative to the web root, at which HTTP01
+// challenges for token are validated, as defined by RFC 8555 section 8.3.
+func ChallengePath(token string) string {
+	return "/.well-known/acme-challenge/" + token
+}
+
+// HTTPConfig configures an HTTPProvider.
+type HTTPConfig struct {
+	// StorageZone and StoragePassword select the Edge Storage zone serving
+	// the domains, through a pull zone with the storage zone as its origin.
+	StorageZone     string
+	StoragePassword string
+	// StorageEndpoint is the storage region's API,
+	// bunny.DefaultStorageEndpoint when empty.
+	StorageEndpoint string
+	// Doer sends the requests, see bunny.StorageOptions.
+	Doer bunny.Doer
+}
+
+// NewDefaultHTTPConfig returns an HTTPConfig read from BUNNY_STORAGE_ZONE,
+// BUNNY_STORAGE_PASSWORD and BUNNY_STORAGE_ENDPOINT.
+func NewDefaultHTTPConfig() *HTTPConfig {
+	return &HTTPConfig{
+		StorageZone:     os.Getenv("BUNNY_STORAGE_ZONE"),
+		StoragePassword: os.Getenv("BUNNY_STORAGE_PASSWORD"),
+		StorageEndpoint: os.Getenv("BUNNY_STORAGE_ENDPOINT"),
+	}
+}
+
+// HTTPProvider solves HTTP01 challenges by uploading the key authorization
+// to a Bunny Edge Storage zone, for domains served by Bunny CDN whose DNS
+// can't be changed:
+//
+//	provider, err := bunnylego.NewHTTPProvider()
+//	...
+//	client.Challenge.SetHTTP01Provider(provider)
+type HTTPProvider struct {
+	storage *bunny.Storage
+}
+
+// NewHTTPProvider returns an HTTPProvider configured by
+// NewDefaultHTTPConfig.
+func NewHTTPProvider() (*HTTPProvider, error) {
+	return NewHTTPProviderConfig(NewDefaultHTTPConfig())
+}
+
+// NewHTTPProviderConfig returns an HTTPProvider using config.
+func NewHTTPProviderConfig(config *HTTPConfig) (*HTTPProvider, error) {
+	if config == nil {
+		return nil, errors.New("bunny: the configuration of the HTTP provider is nil")
+	}
+	if config.StorageZone == "" || config.StoragePassword == "" {
+		return nil, errors.New("bunny: storage zone and password are required")
+	}
+	return &HTTPProvider{storage: bunny.NewStorage(bunny.StorageOptions{
+		Endpoint: config.StorageEndpoint,
+		Zone:     config.StorageZone,
+		Password: config.StoragePassword,
+		Doer:     config.Doer,
+	})}, nil
+}
+
+// Present uploads the key authorization for token.
+func (p *HTTPProvider) Present(domain, token, keyAuth string) error {
+	if err := p.storage.Upload(context.Background(), ChallengePath(token), []byte(keyAuth)); err != nil {
+		return fmt.Errorf("bunny: failed to upload challenge for %s: %w", domain, err)
+	}
+	return nil
+}
+
+// CleanUp deletes the file uploaded by Present.
+func (p *HTTPProvider) CleanUp(domain, token, keyAuth string) error {
+	if err := p.storage.Delete(context.Background(), ChallengePath(token)); err != nil && !bunny.IsNotFound(err) {
+		return fmt.Errorf("bunny: failed to delete challenge for %s: %w", domain, err)
+	}
+	return nil
+}
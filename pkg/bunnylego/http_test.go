@@ -0,0 +1,42 @@
+package bunnylego
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPProvider(t *testing.T) {
+	files := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			files[r.URL.Path] = string(body)
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodDelete:
+			if _, ok := files[r.URL.Path]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			delete(files, r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	p, err := NewHTTPProviderConfig(&HTTPConfig{StorageZone: "site", StoragePassword: "password", StorageEndpoint: srv.URL})
+	require.NoError(t, err)
+
+	require.NoError(t, p.Present("example.com", "token", "token.thumbprint"))
+	assert.Equal(t, map[string]string{"/site/.well-known/acme-challenge/token": "token.thumbprint"}, files)
+	require.NoError(t, p.CleanUp("example.com", "token", "token.thumbprint"))
+	assert.Empty(t, files)
+	require.NoError(t, p.CleanUp("example.com", "token", "token.thumbprint"), "cleaning up twice must not fail")
+
+	_, err = NewHTTPProviderConfig(&HTTPConfig{StorageZone: "site"})
+	assert.ErrorContains(t, err, "storage zone and password are required")
+}
@@ -0,0 +1,173 @@
+// Package bunnylego implements the go-acme/lego challenge.Provider and
+// challenge.ProviderTimeout interfaces on top of package bunny, so Go
+// programs using lego can solve DNS01 challenges for Bunny zones:
+//
+//	provider, err := bunnylego.NewDNSProvider()
+//	...
+//	client.Challenge.SetDNS01Provider(provider)
+//
+// HTTPProvider solves HTTP01 challenges through Bunny Edge Storage instead.
+//
+// It only depends on package bunny, not on the webhook's Kubernetes
+// dependencies.
+package bunnylego
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cert-manager/webhook-example/pkg/bunny"
+)
+
+// Defaults of Config.
+const (
+	DefaultTTL                = 60
+	DefaultPropagationTimeout = 2 * time.Minute
+	DefaultPollingInterval    = 2 * time.Second
+)
+
+// Config configures a DNSProvider.
+type Config struct {
+	// APIKey authenticates with the Bunny API.
+	APIKey string
+	// BaseURL is the API endpoint, bunny.DefaultBaseURL when empty.
+	BaseURL string
+	// TTL of the challenge records in seconds.
+	TTL int
+	// PropagationTimeout and PollingInterval are returned to lego by
+	// Timeout.
+	PropagationTimeout time.Duration
+	PollingInterval    time.Duration
+	// Doer sends the requests, see bunny.Options.
+	Doer bunny.Doer
+}
+
+// NewDefaultConfig returns a Config with the API key in BUNNY_API_KEY and
+// the default timings.
+func NewDefaultConfig() *Config {
+	return &Config{
+		APIKey:             os.Getenv("BUNNY_API_KEY"),
+		TTL:                DefaultTTL,
+		PropagationTimeout: DefaultPropagationTimeout,
+		PollingInterval:    DefaultPollingInterval,
+	}
+}
+
+// DNSProvider solves DNS01 challenges with Bunny DNS.
+type DNSProvider struct {
+	config *Config
+	client bunny.Client
+}
+
+// NewDNSProvider returns a DNSProvider configured by NewDefaultConfig.
+func NewDNSProvider() (*DNSProvider, error) {
+	return NewDNSProviderConfig(NewDefaultConfig())
+}
+
+// NewDNSProviderConfig returns a DNSProvider using config.
+func NewDNSProviderConfig(config *Config) (*DNSProvider, error) {
+	if config == nil {
+		return nil, errors.New("bunny: the configuration of the DNS provider is nil")
+	}
+	if config.APIKey == "" {
+		return nil, errors.New("bunny: API key is missing")
+	}
+	return &DNSProvider{
+		config: config,
+		client: bunny.New(bunny.Options{BaseURL: config.BaseURL, APIKey: config.APIKey, Doer: config.Doer}),
+	}, nil
+}
+
+// Present creates the TXT record for the challenge. Records for other
+// challenges of the same name, e.g. for a domain and its wildcard, are kept.
+func (d *DNSProvider) Present(domain, token, keyAuth string) error {
+	ctx := context.Background()
+	fqdn, value, zone, err := d.challenge(ctx, domain, keyAuth)
+	if err != nil {
+		return fmt.Errorf("bunny: %w", err)
+	}
+
+	records, err := d.client.ListRecords(ctx, int64(zone.ID))
+	if err != nil {
+		return fmt.Errorf("bunny: failed to list records of zone %s: %w", zone.Domain, err)
+	}
+	if _, ok := bunny.FindTXTRecord(records, zone.Domain, fqdn, value); ok {
+		return nil
+	}
+
+	record := bunny.Record{Type: bunny.RecordTypeTXT, Name: bunny.RecordName(zone.Domain, fqdn), Value: value, Ttl: d.config.TTL}
+	if _, err := d.client.CreateRecord(ctx, int64(zone.ID), record); err != nil && !errors.Is(err, bunny.ErrMalformedResponse) {
+		return fmt.Errorf("bunny: failed to create TXT record %s: %w", fqdn, err)
+	}
+	return nil
+}
+
+// CleanUp deletes the TXT record created by Present.
+func (d *DNSProvider) CleanUp(domain, token, keyAuth string) error {
+	ctx := context.Background()
+	fqdn, value, zone, err := d.challenge(ctx, domain, keyAuth)
+	if err != nil {
+		return fmt.Errorf("bunny: %w", err)
+	}
+
+	records, err := d.client.ListRecords(ctx, int64(zone.ID))
+	if err != nil {
+		return fmt.Errorf("bunny: failed to list records of zone %s: %w", zone.Domain, err)
+	}
+	record, ok := bunny.FindTXTRecord(records, zone.Domain, fqdn, value)
+	if !ok {
+		return nil
+	}
+	if err := d.client.DeleteRecord(ctx, int64(zone.ID), record.ID); err != nil && !bunny.IsNotFound(err) {
+		return fmt.Errorf("bunny: failed to delete TXT record %s: %w", fqdn, err)
+	}
+	return nil
+}
+
+// Timeout returns how long lego waits for the record to propagate and how
+// often it checks.
+func (d *DNSProvider) Timeout() (timeout, interval time.Duration) {
+	return d.config.PropagationTimeout, d.config.PollingInterval
+}
+
+// challenge returns the record for the challenge, with the FQDN in
+// punycode, and the zone it belongs in.
+func (d *DNSProvider) challenge(ctx context.Context, domain, keyAuth string) (fqdn, value string, zone bunny.Zone, err error) {
+	fqdn, value = ChallengeRecord(domain, keyAuth)
+	if fqdn, err = bunny.ToASCII(fqdn); err != nil {
+		return "", "", bunny.Zone{}, err
+	}
+	zone, err = d.findZone(ctx, fqdn)
+	return fqdn, value, zone, err
+}
+
+// findZone returns the most specific zone of the account containing fqdn.
+func (d *DNSProvider) findZone(ctx context.Context, fqdn string) (bunny.Zone, error) {
+	labels := strings.Split(strings.TrimSuffix(fqdn, "."), ".")
+	// The first label is _acme-challenge and the last one a TLD, neither
+	// can be a zone.
+	for i := 1; i < len(labels)-1; i++ {
+		zone, err := bunny.FindZone(ctx, d.client, strings.Join(labels[i:], "."))
+		if err == nil {
+			return zone, nil
+		}
+		if !errors.Is(err, bunny.ErrZoneNotFound) {
+			return bunny.Zone{}, fmt.Errorf("failed to search for zone: %w", err)
+		}
+	}
+	return bunny.Zone{}, fmt.Errorf("no zone for %s found in the account", fqdn)
+}
+
+// ChallengeRecord returns the FQDN and value of the DNS01 TXT record for
+// domain, as defined by RFC 8555 section 8.4.
+func ChallengeRecord(domain, keyAuth string) (fqdn, value string) {
+	sum := sha256.Sum256([]byte(keyAuth))
+	domain = strings.TrimSuffix(strings.TrimPrefix(domain, "*."), ".")
+	return "_acme-challenge." + domain + ".", base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,77 @@
+package bunnylego
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cert-manager/webhook-example/pkg/bunny"
+	"github.com/cert-manager/webhook-example/pkg/bunny/bunnytest"
+)
+
+func TestDNSProvider(t *testing.T) {
+	api := bunnytest.NewServer()
+	defer api.Close()
+	api.RequireAPIKey("secret")
+	parent := api.AddZone("example.com")
+	zone := api.AddZone("dev.example.com")
+
+	config := NewDefaultConfig()
+	config.APIKey = "secret"
+	config.BaseURL = api.URL
+	d, err := NewDNSProviderConfig(config)
+	require.NoError(t, err)
+
+	// lego presents a domain and its wildcard before cleaning either up.
+	require.NoError(t, d.Present("www.dev.example.com", "", "one"))
+	require.NoError(t, d.Present("*.www.dev.example.com", "", "two"))
+	require.NoError(t, d.Present("www.dev.example.com", "", "one"))
+	assert.Empty(t, api.Records(parent))
+	records := api.Records(zone)
+	require.Len(t, records, 2)
+	_, value := ChallengeRecord("www.dev.example.com", "one")
+	assert.Equal(t, bunny.Record{ID: records[0].ID, Type: bunny.RecordTypeTXT, Name: "_acme-challenge.www", Value: value, Ttl: DefaultTTL}, records[0])
+
+	require.NoError(t, d.CleanUp("www.dev.example.com", "", "one"))
+	require.Len(t, api.Records(zone), 1)
+	require.NoError(t, d.CleanUp("*.www.dev.example.com", "", "two"))
+	assert.Empty(t, api.Records(zone))
+
+	assert.ErrorContains(t, d.Present("example.org", "", "one"), "no zone for _acme-challenge.example.org. found")
+}
+
+func TestNewDNSProviderConfig_MissingKey(t *testing.T) {
+	t.Setenv("BUNNY_API_KEY", "")
+	_, err := NewDNSProvider()
+	assert.ErrorContains(t, err, "API key is missing")
+}
+
+func TestChallengeRecord(t *testing.T) {
+	fqdn, value := ChallengeRecord("*.example.com", "token.thumbprint")
+	assert.Equal(t, "_acme-challenge.example.com.", fqdn)
+	assert.Len(t, value, 43)
+
+	_, same := ChallengeRecord("example.com.", "token.thumbprint")
+	assert.Equal(t, value, same)
+}
+
+func TestDNSProvider_IDN(t *testing.T) {
+	api := bunnytest.NewServer()
+	defer api.Close()
+	zone := api.AddZone("xn--bcher-kva.example")
+
+	config := NewDefaultConfig()
+	config.APIKey = "secret"
+	config.BaseURL = api.URL
+	d, err := NewDNSProviderConfig(config)
+	require.NoError(t, err)
+
+	require.NoError(t, d.Present("www.Bücher.example", "", "one"))
+	records := api.Records(zone)
+	require.Len(t, records, 1)
+	assert.Equal(t, "_acme-challenge.www", records[0].Name)
+
+	require.NoError(t, d.CleanUp("www.bücher.example", "", "one"))
+	assert.Empty(t, api.Records(zone))
+}
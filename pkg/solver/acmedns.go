@@ -0,0 +1,84 @@
+package solver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// acmeDNSAccount is an acme-dns registration, in the format of the accounts
+// file used by lego and other acme-dns clients.
+type acmeDNSAccount struct {
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+	Subdomain  string `json:"subdomain"`
+	FullDomain string `json:"fulldomain"`
+}
+
+// ACMEDNS is a Fallback publishing records to an acme-dns server. The
+// challenge names must be delegated to the acme-dns accounts (by CNAME) for
+// the ACME server to see them.
+type ACMEDNS struct {
+	url      string
+	accounts map[string]acmeDNSAccount
+}
+
+// NewACMEDNS returns an acme-dns fallback for the server at url. The
+// accounts file maps each domain to its acme-dns registration.
+func NewACMEDNS(url, accountsFile string) (*ACMEDNS, error) {
+	data, err := os.ReadFile(accountsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read acme-dns accounts: %w", err)
+	}
+	accounts := map[string]acmeDNSAccount{}
+	if err := json.Unmarshal(data, &accounts); err != nil {
+		return nil, fmt.Errorf("failed to parse acme-dns accounts %s: %w", accountsFile, err)
+	}
+	return &ACMEDNS{url: strings.TrimSuffix(url, "/"), accounts: accounts}, nil
+}
+
+// Present updates the TXT record of the domain's acme-dns account.
+func (a *ACMEDNS) Present(ctx context.Context, fqdn, value string) error {
+	domain := strings.TrimSuffix(strings.TrimPrefix(fqdn, "_acme-challenge."), ".")
+	account, ok := a.accounts[domain]
+	if !ok {
+		return fmt.Errorf("no acme-dns account for %s", domain)
+	}
+
+	payload, err := json.Marshal(map[string]string{"subdomain": account.Subdomain, "txt": value})
+	if err != nil {
+		return fmt.Errorf("failed to marshal update: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url+"/update", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Api-User", account.Username)
+	req.Header.Set("X-Api-Key", account.Password)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("acme-dns update failed with status %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// CleanUp does nothing: acme-dns has no way to delete a value. It keeps the
+// two most recent values of an account and replaces them on the next update.
+func (a *ACMEDNS) CleanUp(ctx context.Context, fqdn, _ string) error {
+	logger(ctx).Info("acme-dns cannot delete records, leaving the value until the next update", "record", fqdn)
+	return nil
+}
@@ -0,0 +1,119 @@
+package solver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// AdminRecord is a challenge TXT record as exposed by the admin API.
+type AdminRecord struct {
+	ID    int    `json:"id,omitempty"`
+	Zone  string `json:"zone"`
+	FQDN  string `json:"fqdn"`
+	Value string `json:"value"`
+	TTL   int    `json:"ttl,omitempty"`
+}
+
+// NewAdminHandler returns a handler for manual management of challenge TXT
+// records with the webhook-wide API key of s, for operators intervening
+// during incidents:
+//
+//	GET    /admin/records?zone=example.com    list _acme-challenge TXT records
+//	POST   /admin/records                     create a record (AdminRecord body)
+//	DELETE /admin/records?zone=&fqdn=&value=  delete a record
+//
+// The handler does no authentication of its own.
+func NewAdminHandler(s *Solver) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /admin/records", func(w http.ResponseWriter, r *http.Request) {
+		cfg := s.ambientConfig()
+		zone := r.URL.Query().Get("zone")
+		if zone == "" {
+			http.Error(w, "zone is required", http.StatusBadRequest)
+			return
+		}
+		item, err := getZoneRecords(r.Context(), cfg, zone)
+		if err != nil {
+			http.Error(w, redact(err.Error()), http.StatusBadGateway)
+			return
+		}
+
+		writeAdminJSON(w, http.StatusOK, challengeRecords(item))
+	})
+
+	mux.HandleFunc("POST /admin/records", func(w http.ResponseWriter, r *http.Request) {
+		cfg := s.ambientConfig()
+		var rec AdminRecord
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&rec); err != nil {
+			http.Error(w, fmt.Sprintf("invalid record: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := validateAdminRecord(rec); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if rec.TTL <= 0 {
+			rec.TTL = recordTTL
+		}
+
+		zone, fqdn := withTrailingDot(rec.Zone), withTrailingDot(rec.FQDN)
+		zoneID, err := GetZoneID(r.Context(), zone, cfg)
+		if err != nil {
+			http.Error(w, redact(err.Error()), http.StatusBadGateway)
+			return
+		}
+		created, err := createTXTRecord(r.Context(), cfg, zoneID, zone, fqdn, rec.Value, rec.TTL)
+		if err != nil {
+			http.Error(w, redact(err.Error()), http.StatusBadGateway)
+			return
+		}
+		rec.ID = created.ID
+		writeAdminJSON(w, http.StatusCreated, rec)
+	})
+
+	mux.HandleFunc("DELETE /admin/records", func(w http.ResponseWriter, r *http.Request) {
+		cfg := s.ambientConfig()
+		q := r.URL.Query()
+		rec := AdminRecord{Zone: q.Get("zone"), FQDN: q.Get("fqdn"), Value: q.Get("value")}
+		if err := validateAdminRecord(rec); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := deleteTXTRecord(r.Context(), cfg, withTrailingDot(rec.Zone), withTrailingDot(rec.FQDN), rec.Value); err != nil {
+			http.Error(w, redact(err.Error()), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return mux
+}
+
+func validateAdminRecord(rec AdminRecord) error {
+	switch {
+	case rec.Zone == "":
+		return fmt.Errorf("zone is required")
+	case rec.FQDN == "":
+		return fmt.Errorf("fqdn is required")
+	case rec.Value == "":
+		return fmt.Errorf("value is required")
+	case !withinZone(rec.FQDN, rec.Zone):
+		return fmt.Errorf("fqdn %s is not in zone %s", rec.FQDN, rec.Zone)
+	}
+	return nil
+}
+
+// withTrailingDot returns name as a fully qualified name, the form
+// ResolvedZone and ResolvedFQDN have.
+func withTrailingDot(name string) string {
+	return strings.TrimSuffix(name, ".") + "."
+}
+
+func writeAdminJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,28 @@
+package solver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminHandler_Validation(t *testing.T) {
+	h := NewAdminHandler(New(Options{APIKey: "key"}))
+
+	for _, tc := range []struct {
+		method, target, body string
+	}{
+		{http.MethodGet, "/admin/records", ""},
+		{http.MethodPost, "/admin/records", `{"zone": "example.com", "fqdn": "_acme-challenge.example.com"}`},
+		{http.MethodPost, "/admin/records", `{"zone": "example.com", "fqdn": "_acme-challenge.example.org", "value": "v"}`},
+		{http.MethodPost, "/admin/records", `not json`},
+		{http.MethodDelete, "/admin/records?zone=example.com&fqdn=_acme-challenge.example.com", ""},
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body)))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, "%s %s %s", tc.method, tc.target, tc.body)
+	}
+}
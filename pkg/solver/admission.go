@@ -0,0 +1,196 @@
+package solver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	cmacme "github.com/cert-manager/cert-manager/pkg/apis/acme/v1"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const admissionPath = "/validate-issuer"
+
+// admissionLookupTimeout bounds the Bunny API calls of a review, well
+// within the 10s the API server waits for the webhook.
+const admissionLookupTimeout = 5 * time.Second
+
+var admissionServerOnce sync.Once
+
+// startAdmissionServer serves an optional validating webhook for Issuers and
+// ClusterIssuers, so a broken bunny solver config fails `kubectl apply`
+// instead of the first renewal.
+func (c *Solver) startAdmissionServer(stopCh <-chan struct{}) {
+	admissionServerOnce.Do(func() {
+		mux := http.NewServeMux()
+		mux.HandleFunc(admissionPath, c.handleValidateIssuer)
+
+		srv := &http.Server{
+			Addr:              c.opts.AdmissionBindAddress,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		go func() {
+			slog.Info("Serving Issuer admission webhook", "addr", srv.Addr)
+			err := srv.ListenAndServeTLS(c.opts.AdmissionCertFile, c.opts.AdmissionKeyFile)
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("admission server failed", "error", err)
+			}
+		}()
+		go func() {
+			<-stopCh
+			_ = srv.Shutdown(context.Background())
+		}()
+	})
+}
+
+func (c *Solver) handleValidateIssuer(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to read request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var review admissionv1.AdmissionReview
+	if err := json.Unmarshal(body, &review); err != nil || review.Request == nil {
+		http.Error(w, "expected an AdmissionReview request", http.StatusBadRequest)
+		return
+	}
+
+	resp := &admissionv1.AdmissionResponse{UID: review.Request.UID, Allowed: true}
+	problems, warnings := c.reviewIssuer(r.Context(), review.Request)
+	resp.Warnings = warnings
+	if len(problems) > 0 {
+		resp.Allowed = false
+		resp.Result = &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Reason:  metav1.StatusReasonInvalid,
+			Message: "invalid bunny-net solver config: " + strings.Join(problems, "; "),
+		}
+	}
+
+	review.Response = resp
+	review.Request = nil
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(review)
+}
+
+func (c *Solver) reviewIssuer(ctx context.Context, req *admissionv1.AdmissionRequest) (problems, warnings []string) {
+	var issuer struct {
+		metav1.ObjectMeta `json:"metadata"`
+		Spec              cmapi.IssuerSpec `json:"spec"`
+	}
+	if err := json.Unmarshal(req.Object.Raw, &issuer); err != nil {
+		return []string{fmt.Sprintf("failed to decode %s: %v", req.Kind.Kind, err)}, nil
+	}
+
+	// Mirror the namespacing and ambient credential defaults cert-manager
+	// applies when it builds ChallengeRequests for each issuer kind.
+	namespace, allowAmbient := issuer.Namespace, false
+	if req.Kind.Kind == cmapi.ClusterIssuerKind {
+		namespace, allowAmbient = c.opts.ClusterResourceNamespace, true
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, admissionLookupTimeout)
+	defer cancel()
+	return c.validateIssuerSpec(ctx, issuer.Spec, namespace, allowAmbient)
+}
+
+// validateIssuerSpec checks every solver in spec handled by this webhook:
+// the config must decode, the API key must resolve and any zones the solver
+// is restricted to must be hosted in the Bunny account, by themselves or by
+// an enclosing zone, as Present finds them. Zones that couldn't be checked
+// because the Bunny API failed are returned as warnings, not problems.
+func (c *Solver) validateIssuerSpec(ctx context.Context, spec cmapi.IssuerSpec, namespace string, allowAmbient bool) (problems, warnings []string) {
+	if spec.ACME == nil {
+		return nil, nil
+	}
+
+	checkZone := func(field string, err error) {
+		switch {
+		case err == nil:
+		case errors.Is(err, errZoneNotFound):
+			problems = append(problems, fmt.Sprintf("%s: %v", field, err))
+		default:
+			warnings = append(warnings, fmt.Sprintf("%s: could not be checked against the Bunny API: %v", field, err))
+		}
+	}
+	for i, solver := range spec.ACME.Solvers {
+		if !c.handlesSolver(solver) {
+			continue
+		}
+		prefix := fmt.Sprintf("spec.acme.solvers[%d].dns01.webhook.config", i)
+
+		req := v1alpha1.ChallengeRequest{
+			ResourceNamespace:       namespace,
+			AllowAmbientCredentials: allowAmbient,
+			Config:                  solver.DNS01.Webhook.Config,
+		}
+		cfg, err := c.loadConfig(ctx, &req)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", prefix, err))
+			continue
+		}
+
+		if cfg.Zone != "" && cfg.isBunny() {
+			req.ResolvedZone = withTrailingDot(cfg.Zone)
+			if zoneCfg, err := c.loadConfig(ctx, &req); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", prefix, err))
+			} else {
+				_, _, err := hostedZone(ctx, zoneCfg, req.ResolvedZone, req.ResolvedZone)
+				checkZone(prefix+".zone", err)
+			}
+		}
+
+		if cfg.ZoneID != 0 && cfg.isBunny() {
+			_, err := getZoneByID(ctx, cfg, cfg.ZoneID)
+			checkZone(prefix+".zoneID", err)
+		}
+
+		if solver.Selector == nil || !cfg.isBunny() {
+			continue
+		}
+		for _, zone := range solver.Selector.DNSZones {
+			zone = withTrailingDot(zone)
+			zoneCfg := cfg
+			if len(cfg.ZoneEndpoints) > 0 {
+				// The zone may be served by another endpoint or account.
+				req.ResolvedZone = zone
+				if zoneCfg, err = c.loadConfig(ctx, &req); err != nil {
+					problems = append(problems, fmt.Sprintf("%s: %v", prefix, err))
+					continue
+				}
+			}
+			_, _, err := hostedZone(ctx, zoneCfg, zone, zone)
+			checkZone(fmt.Sprintf("spec.acme.solvers[%d].selector.dnsZones", i), err)
+		}
+	}
+	return problems, warnings
+}
+
+func (c *Solver) handlesSolver(solver cmacme.ACMEChallengeSolver) bool {
+	if solver.DNS01 == nil || solver.DNS01.Webhook == nil {
+		return false
+	}
+	wh := solver.DNS01.Webhook
+	if wh.SolverName != c.Name() {
+		return false
+	}
+	for _, group := range c.opts.Groups {
+		if wh.GroupName == group {
+			return true
+		}
+	}
+	return false
+}
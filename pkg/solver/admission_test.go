@@ -0,0 +1,156 @@
+package solver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	cmacme "github.com/cert-manager/cert-manager/pkg/apis/acme/v1"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/cert-manager/webhook-example/pkg/bunny/bunnytest"
+)
+
+// reviewIssuerConfig sends an AdmissionReview for an issuer of the given
+// kind in namespace, whose only solver uses config, to the admission
+// handler of s.
+func reviewIssuerConfig(t *testing.T, s *Solver, kind, namespace, config string) *admissionv1.AdmissionResponse {
+	t.Helper()
+	issuer := cmapi.Issuer{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "letsencrypt"},
+		Spec: cmapi.IssuerSpec{IssuerConfig: cmapi.IssuerConfig{ACME: &cmacme.ACMEIssuer{
+			Solvers: []cmacme.ACMEChallengeSolver{{DNS01: &cmacme.ACMEChallengeSolverDNS01{
+				Webhook: &cmacme.ACMEIssuerDNS01ProviderWebhook{
+					GroupName:  "acme.example.com",
+					SolverName: DefaultName,
+					Config:     &apiextensionsv1.JSON{Raw: []byte(config)},
+				},
+			}}},
+		}}},
+	}
+	raw, err := json.Marshal(issuer)
+	require.NoError(t, err)
+	body, err := json.Marshal(admissionv1.AdmissionReview{
+		Request: &admissionv1.AdmissionRequest{
+			UID:    "review-1",
+			Kind:   metav1.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: kind},
+			Object: runtime.RawExtension{Raw: raw},
+		},
+	})
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	s.handleValidateIssuer(rec, httptest.NewRequest(http.MethodPost, admissionPath, bytes.NewReader(body)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var review admissionv1.AdmissionReview
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &review))
+	require.NotNil(t, review.Response)
+	assert.EqualValues(t, "review-1", review.Response.UID)
+	return review.Response
+}
+
+func TestHandleValidateIssuer(t *testing.T) {
+	api := bunnytest.NewServer()
+	defer api.Close()
+	api.RequireAPIKey("secret")
+	api.AddZone("example.com")
+
+	s := New(Options{Groups: []string{"acme.example.com"}, ClusterResourceNamespace: "cert-manager"})
+	s.client = fake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "bunny"},
+			Data:       map[string][]byte{"api-key": []byte("secret")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "cert-manager", Name: "bunny"},
+			Data:       map[string][]byte{"api-key": []byte("secret")},
+		},
+	)
+	config := func(secret, zone string) string {
+		return fmt.Sprintf(`{"apiURL":%q,"apiKeySecretRef":{"name":%q,"key":"api-key"},"zone":%q}`, api.URL, secret, zone)
+	}
+
+	t.Run("accept", func(t *testing.T) {
+		resp := reviewIssuerConfig(t, s, cmapi.IssuerKind, "team-a", config("bunny", "example.com"))
+		assert.True(t, resp.Allowed, resp.Result)
+
+		// ClusterIssuer Secrets are read from the cluster resource
+		// namespace, not the (empty) namespace of the ClusterIssuer.
+		resp = reviewIssuerConfig(t, s, cmapi.ClusterIssuerKind, "", config("bunny", "example.com"))
+		assert.True(t, resp.Allowed, resp.Result)
+
+		// Records for a subdomain go to the enclosing Bunny zone.
+		resp = reviewIssuerConfig(t, s, cmapi.IssuerKind, "team-a", config("bunny", "sub.example.com"))
+		assert.True(t, resp.Allowed, resp.Result)
+		assert.Empty(t, resp.Warnings)
+	})
+
+	t.Run("Bunny unreachable", func(t *testing.T) {
+		down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer down.Close()
+		s.opts.Retry = RetryPolicy{MaxAttempts: 1}
+		defer func() { s.opts.Retry = RetryPolicy{} }()
+
+		resp := reviewIssuerConfig(t, s, cmapi.IssuerKind, "team-a",
+			fmt.Sprintf(`{"apiURL":%q,"apiKeySecretRef":{"name":"bunny","key":"api-key"},"zone":"example.com"}`, down.URL))
+		assert.True(t, resp.Allowed, "an outage must not block applying Issuers")
+		require.Len(t, resp.Warnings, 1)
+		assert.Contains(t, resp.Warnings[0], "spec.acme.solvers[0].dns01.webhook.config.zone: could not be checked")
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		resp := reviewIssuerConfig(t, s, cmapi.IssuerKind, "team-a", config("bunny", "example.org"))
+		require.False(t, resp.Allowed)
+		assert.Contains(t, resp.Result.Message, "spec.acme.solvers[0].dns01.webhook.config.zone")
+		assert.Contains(t, resp.Result.Message, "no DNS zone found for example.org")
+
+		resp = reviewIssuerConfig(t, s, cmapi.IssuerKind, "team-a", `{"ttl":"ten"}`)
+		assert.False(t, resp.Allowed)
+	})
+
+	t.Run("missing Secret", func(t *testing.T) {
+		resp := reviewIssuerConfig(t, s, cmapi.IssuerKind, "team-b", config("bunny", "example.com"))
+		require.False(t, resp.Allowed)
+		assert.Contains(t, resp.Result.Message, "failed to get secret team-b/bunny")
+
+		// Issuers may not fall back to the webhook's own key.
+		resp = reviewIssuerConfig(t, s, cmapi.IssuerKind, "team-a", fmt.Sprintf(`{"apiURL":%q}`, api.URL))
+		require.False(t, resp.Allowed)
+		assert.Contains(t, resp.Result.Message, "apiKeySecretRef")
+	})
+}
+
+func TestHandleValidateIssuer_BadRequest(t *testing.T) {
+	rec := httptest.NewRecorder()
+	New(Options{}).handleValidateIssuer(rec, httptest.NewRequest(http.MethodPost, admissionPath, bytes.NewReader([]byte(`{}`))))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestHandlesSolver_Groups(t *testing.T) {
+	s := New(Options{Groups: []string{"a.example.com", "b.example.com"}})
+	solver := func(group, name string) cmacme.ACMEChallengeSolver {
+		return cmacme.ACMEChallengeSolver{DNS01: &cmacme.ACMEChallengeSolverDNS01{
+			Webhook: &cmacme.ACMEIssuerDNS01ProviderWebhook{GroupName: group, SolverName: name},
+		}}
+	}
+
+	assert.True(t, s.handlesSolver(solver("a.example.com", DefaultName)))
+	assert.True(t, s.handlesSolver(solver("b.example.com", DefaultName)))
+	assert.False(t, s.handlesSolver(solver("c.example.com", DefaultName)))
+	assert.False(t, s.handlesSolver(solver("a.example.com", "other")))
+	assert.False(t, s.handlesSolver(cmacme.ACMEChallengeSolver{}))
+}
@@ -0,0 +1,136 @@
+package solver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	cmacme "github.com/cert-manager/cert-manager/pkg/apis/acme/v1"
+	cmclient "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned"
+	cminformers "github.com/cert-manager/cert-manager/pkg/client/informers/externalversions"
+	cmacmelisters "github.com/cert-manager/cert-manager/pkg/client/listers/acme/v1"
+	cmlisters "github.com/cert-manager/cert-manager/pkg/client/listers/certmanager/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	annotationZoneID   = "webhook.bunny.net/zone-id"
+	annotationRecordID = "webhook.bunny.net/record-id"
+
+	challengeUIDIndex = "uid"
+	challengeKeyIndex = "key"
+)
+
+// challengeAnnotator records Bunny identifiers on the Challenge a request
+// belongs to, so `kubectl describe challenge` shows which zone and record
+// were touched without correlating logs.
+//
+// ChallengeRequest carries the Challenge UID but not its namespace (the
+// resource namespace is the Issuer's), so Challenges are looked up through
+// a UID index on a shared informer. A second index by key tells which
+// record values belong to Challenges still around.
+//
+// The Orders, CertificateRequests and Certificates linking a Challenge to
+// its Certificate are cached too, for certificateOverrides.
+type challengeAnnotator struct {
+	client  cmclient.Interface
+	indexer cache.Indexer
+
+	orders              cmacmelisters.OrderLister
+	certificateRequests cmlisters.CertificateRequestLister
+	certificates        cmlisters.CertificateLister
+}
+
+func newChallengeAnnotator(cfg *rest.Config, stopCh <-chan struct{}) (*challengeAnnotator, error) {
+	cl, err := cmclient.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cert-manager client: %w", err)
+	}
+
+	factory := cminformers.NewSharedInformerFactory(cl, 0)
+	informer := factory.Acme().V1().Challenges().Informer()
+	if err := informer.AddIndexers(cache.Indexers{challengeUIDIndex: indexChallengeByUID, challengeKeyIndex: indexChallengeByKey}); err != nil {
+		return nil, fmt.Errorf("failed to add challenge index: %w", err)
+	}
+	a := &challengeAnnotator{
+		client:              cl,
+		indexer:             informer.GetIndexer(),
+		orders:              factory.Acme().V1().Orders().Lister(),
+		certificateRequests: factory.Certmanager().V1().CertificateRequests().Lister(),
+		certificates:        factory.Certmanager().V1().Certificates().Lister(),
+	}
+
+	factory.Start(stopCh)
+	for typ, ok := range factory.WaitForCacheSync(stopCh) {
+		if !ok {
+			return nil, fmt.Errorf("failed to sync informer cache for %v", typ)
+		}
+	}
+
+	return a, nil
+}
+
+func indexChallengeByUID(obj interface{}) ([]string, error) {
+	ch, ok := obj.(*cmacme.Challenge)
+	if !ok {
+		return nil, nil
+	}
+	return []string{string(ch.UID)}, nil
+}
+
+func indexChallengeByKey(obj interface{}) ([]string, error) {
+	ch, ok := obj.(*cmacme.Challenge)
+	if !ok || ch.Spec.Key == "" {
+		return nil, nil
+	}
+	return []string{ch.Spec.Key}, nil
+}
+
+// challengeExists reports whether a Challenge in the cluster has the record
+// value key, i.e. some replica may be solving it or about to clean it up.
+func (a *challengeAnnotator) challengeExists(key string) bool {
+	if a == nil {
+		return false
+	}
+	objs, err := a.indexer.ByIndex(challengeKeyIndex, key)
+	return err == nil && len(objs) > 0
+}
+
+// annotate is best-effort: failing to record metadata must not fail the
+// challenge itself.
+func (a *challengeAnnotator) annotate(ctx context.Context, uid types.UID, zoneID int64, recordID int) {
+	if a == nil || uid == "" {
+		return
+	}
+
+	ch := a.challenge(uid)
+	if ch == nil {
+		slog.Warn("Could not find challenge to annotate", "uid", uid)
+		return
+	}
+
+	annotations := map[string]string{
+		annotationZoneID: strconv.FormatInt(zoneID, 10),
+	}
+	if recordID != 0 {
+		annotations[annotationRecordID] = strconv.Itoa(recordID)
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": annotations},
+	})
+	if err != nil {
+		slog.Error("failed to build annotation patch for challenge", "namespace", ch.Namespace, "name", ch.Name, "error", err)
+		return
+	}
+
+	_, err = a.client.AcmeV1().Challenges(ch.Namespace).Patch(ctx, ch.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		slog.Error("failed to annotate challenge", "namespace", ch.Namespace, "name", ch.Name, "error", err)
+	}
+}
@@ -0,0 +1,86 @@
+package solver
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	cmacme "github.com/cert-manager/cert-manager/pkg/apis/acme/v1"
+	cmfake "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/cert-manager/webhook-example/pkg/bunny/bunnytest"
+)
+
+// newTestAnnotator returns an annotator whose informer cache and API
+// server both hold ch.
+func newTestAnnotator(t *testing.T, ch *cmacme.Challenge) *challengeAnnotator {
+	t.Helper()
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{challengeUIDIndex: indexChallengeByUID, challengeKeyIndex: indexChallengeByKey})
+	require.NoError(t, indexer.Add(ch))
+	return &challengeAnnotator{client: cmfake.NewSimpleClientset(ch), indexer: indexer}
+}
+
+func getChallenge(t *testing.T, a *challengeAnnotator, namespace, name string) *cmacme.Challenge {
+	t.Helper()
+	ch, err := a.client.AcmeV1().Challenges(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	require.NoError(t, err)
+	return ch
+}
+
+func TestAnnotate(t *testing.T) {
+	a := newTestAnnotator(t, &cmacme.Challenge{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "ch", UID: "uid-1"}})
+
+	a.annotate(context.Background(), "uid-1", 5, 0)
+	assert.Equal(t, map[string]string{annotationZoneID: "5"}, getChallenge(t, a, "team-a", "ch").Annotations)
+
+	a.annotate(context.Background(), "uid-1", 5, 11)
+	assert.Equal(t, map[string]string{annotationZoneID: "5", annotationRecordID: "11"}, getChallenge(t, a, "team-a", "ch").Annotations)
+
+	// Unknown Challenges and requests without a UID are skipped.
+	client := a.client.(*cmfake.Clientset)
+	client.ClearActions()
+	a.annotate(context.Background(), "unknown", 5, 11)
+	a.annotate(context.Background(), "", 5, 11)
+	assert.Empty(t, client.Actions())
+
+	var disabled *challengeAnnotator
+	disabled.annotate(context.Background(), "uid-1", 5, 11)
+}
+
+func TestChallengeInProgress(t *testing.T) {
+	s := New(Options{})
+	s.annotator = newTestAnnotator(t, &cmacme.Challenge{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "ch", UID: "uid-1"},
+		Spec:       cmacme.ChallengeSpec{DNSName: "example.com", Key: "other-replica"},
+	})
+	s.activeRecords.Store(recordKey("_acme-challenge.example.com.", "local"), struct{}{})
+
+	assert.True(t, s.challengeInProgress("_acme-challenge.example.com.", "local"))
+	assert.True(t, s.challengeInProgress("_acme-challenge.example.com.", "other-replica"), "Challenges of other replicas count")
+	assert.False(t, s.challengeInProgress("_acme-challenge.example.com.", "stale"))
+}
+
+func TestPresent_AnnotatesChallenge(t *testing.T) {
+	api := bunnytest.NewServer()
+	defer api.Close()
+	api.RequireAPIKey("secret")
+	zone := api.AddZone("example.com")
+
+	s := New(Options{APIKey: "secret", APIBase: api.URL})
+	s.annotator = newTestAnnotator(t, &cmacme.Challenge{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "ch", UID: "uid-1"}})
+	ch := fakeChallenge(api, "token")
+	ch.UID = types.UID("uid-1")
+	require.NoError(t, s.Present(ch))
+
+	records := api.Records(zone)
+	require.Len(t, records, 1)
+	assert.Equal(t, map[string]string{
+		annotationZoneID:   strconv.Itoa(zone),
+		annotationRecordID: strconv.Itoa(records[0].ID),
+	}, getChallenge(t, s.annotator, "team-a", "ch").Annotations)
+}
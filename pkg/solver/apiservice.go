@@ -0,0 +1,97 @@
+package solver
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+const webhookAPIVersion = "v1alpha1"
+
+var apiServiceGVR = schema.GroupVersionResource{
+	Group:    "apiregistration.k8s.io",
+	Version:  "v1",
+	Resource: "apiservices",
+}
+
+// apiServiceBootstrapReasons are reported by the aggregator while the
+// webhook Service has no ready endpoints. They are expected until the first
+// replica becomes ready, so they must not hold readiness back or a fresh
+// install would never become ready.
+var apiServiceBootstrapReasons = map[string]bool{
+	"MissingEndpoints":  true,
+	"EndpointsNotFound": true,
+}
+
+type apiServiceProbe struct {
+	dyn    dynamic.Interface
+	client kubernetes.Interface
+}
+
+// CheckAPIService verifies that the APIService of every group is Available
+// and that the group can be discovered through the apiserver. This catches
+// aggregation misconfiguration (wrong CA, blocked apiserver to pod traffic,
+// API priority and fairness rejecting the webhook) at rollout time rather
+// than when the first certificate is issued. It does nothing unless
+// Options.CheckAPIService is set and the solver is initialized.
+func (c *Solver) CheckAPIService(ctx context.Context) error {
+	probe := c.apiService.Load()
+	if probe == nil {
+		return nil
+	}
+	for _, group := range c.opts.Groups {
+		if err := probe.check(ctx, group); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *apiServiceProbe) check(ctx context.Context, group string) error {
+	name := webhookAPIVersion + "." + group
+	svc, err := p.dyn.Resource(apiServiceGVR).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get APIService %s: %w", name, err)
+	}
+
+	status, reason, message := apiServiceAvailable(svc)
+	switch {
+	case status == "True":
+	case apiServiceBootstrapReasons[reason]:
+		// Discovery can't succeed without endpoints either.
+		return nil
+	default:
+		return fmt.Errorf("APIService %s is not available: %s: %s", name, reason, message)
+	}
+
+	err = p.client.Discovery().RESTClient().Get().
+		AbsPath("/apis", group, webhookAPIVersion).
+		Do(ctx).
+		Error()
+	if err != nil {
+		return fmt.Errorf("failed to reach %s/%s through the apiserver: %w", group, webhookAPIVersion, err)
+	}
+	return nil
+}
+
+// apiServiceAvailable returns the status, reason and message of the
+// APIService's Available condition.
+func apiServiceAvailable(svc *unstructured.Unstructured) (status, reason, message string) {
+	conditions, _, _ := unstructured.NestedSlice(svc.Object, "status", "conditions")
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if !ok || cond["type"] != "Available" {
+			continue
+		}
+		status, _ = cond["status"].(string)
+		reason, _ = cond["reason"].(string)
+		message, _ = cond["message"].(string)
+		return status, reason, message
+	}
+	return "Unknown", "NoAvailableCondition", "the APIService has no Available condition yet"
+}
@@ -0,0 +1,31 @@
+package solver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestAPIServiceAvailable(t *testing.T) {
+	svc := &unstructured.Unstructured{Object: map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []interface{}{
+				map[string]interface{}{
+					"type":    "Available",
+					"status":  "False",
+					"reason":  "FailedDiscoveryCheck",
+					"message": "failing or missing response",
+				},
+			},
+		},
+	}}
+	status, reason, message := apiServiceAvailable(svc)
+	assert.Equal(t, "False", status)
+	assert.Equal(t, "FailedDiscoveryCheck", reason)
+	assert.Equal(t, "failing or missing response", message)
+
+	status, reason, _ = apiServiceAvailable(&unstructured.Unstructured{Object: map[string]interface{}{}})
+	assert.Equal(t, "Unknown", status)
+	assert.Equal(t, "NoAvailableCondition", reason)
+}
@@ -0,0 +1,130 @@
+package solver
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const defaultCircuitBreakerCooldown = 30 * time.Second
+
+// ErrCircuitOpen is returned, wrapped, for Bunny API requests that were
+// not sent because the API kept failing. The request can be retried once
+// the breaker's cooldown has passed.
+var ErrCircuitOpen = errors.New("bunny API circuit breaker is open")
+
+// circuitBreaker stops sending Bunny API requests after threshold
+// consecutive transient failures. After cooldown a single probe request is
+// let through; its success closes the breaker again.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	// endpoint is the API base the breaker guards, for logging.
+	endpoint string
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// circuitBreakers holds a circuitBreaker per API endpoint and key, so a
+// broken custom endpoint or account doesn't fail the challenges of
+// everybody else.
+type circuitBreakers struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	breakers map[breakerKey]*circuitBreaker
+}
+
+type breakerKey struct {
+	apiBase, apiKey string
+}
+
+func newCircuitBreakers(threshold int, cooldown time.Duration) *circuitBreakers {
+	return &circuitBreakers{threshold: threshold, cooldown: cooldown, breakers: make(map[breakerKey]*circuitBreaker)}
+}
+
+// get returns the breaker for the endpoint and API key of cfg. A nil
+// circuitBreakers returns nil breakers, which let every request through.
+func (b *circuitBreakers) get(cfg bunnyNetDNSConfig) *circuitBreaker {
+	if b == nil {
+		return nil
+	}
+	key := breakerKey{apiBase: cfg.apiBase(), apiKey: cfg.APIKey}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	breaker, ok := b.breakers[key]
+	if !ok {
+		breaker = newCircuitBreaker(b.threshold, b.cooldown)
+		breaker.endpoint = key.apiBase
+		b.breakers[key] = breaker
+	}
+	return breaker
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	if cooldown <= 0 {
+		cooldown = defaultCircuitBreakerCooldown
+	}
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// allow returns an error wrapping ErrCircuitOpen if a request must not be
+// sent.
+func (b *circuitBreaker) allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return nil
+	}
+	if wait := b.openedAt.Add(b.cooldown).Sub(b.now()); wait > 0 || b.probing {
+		if wait < 0 {
+			wait = 0
+		}
+		return fmt.Errorf("%w after %d consecutive failures, retry in %s", ErrCircuitOpen, b.failures, wait.Round(time.Second))
+	}
+	b.probing = true
+	return nil
+}
+
+// record reports the outcome of a request let through by allow.
+func (b *circuitBreaker) record(resp *http.Response, err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if err != nil && !retryable(nil, err) {
+		// Cancelled by the caller; says nothing about the API.
+		return
+	}
+	if !retryable(resp, err) {
+		if b.failures >= b.threshold {
+			slog.Info("Bunny API recovered, closing the circuit breaker", "endpoint", b.endpoint)
+		}
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		if b.failures == b.threshold {
+			slog.Warn("Bunny API keeps failing, opening the circuit breaker", "endpoint", b.endpoint, "failures", b.failures, "cooldown", b.cooldown)
+		}
+		b.openedAt = b.now()
+	}
+}
@@ -0,0 +1,55 @@
+package solver
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	b := newCircuitBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+	failed := &http.Response{StatusCode: http.StatusServiceUnavailable}
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, b.allow())
+		b.record(failed, nil)
+	}
+	err := b.allow()
+	require.True(t, errors.Is(err, ErrCircuitOpen), "got %v", err)
+	assert.Contains(t, err.Error(), "retry in 1m0s")
+
+	// After the cooldown a single probe is let through.
+	now = now.Add(time.Minute)
+	require.NoError(t, b.allow())
+	assert.ErrorIs(t, b.allow(), ErrCircuitOpen)
+	b.record(failed, nil)
+	assert.ErrorIs(t, b.allow(), ErrCircuitOpen, "a failed probe reopens the breaker")
+
+	now = now.Add(time.Minute)
+	require.NoError(t, b.allow())
+	b.record(&http.Response{StatusCode: http.StatusNotFound}, nil)
+	assert.NoError(t, b.allow(), "client errors mean the API is up")
+}
+
+func TestCircuitBreakers(t *testing.T) {
+	breakers := newCircuitBreakers(1, time.Minute)
+	failed := &http.Response{StatusCode: http.StatusServiceUnavailable}
+	broken := bunnyNetDNSConfig{APIURL: "https://broken.example.com", APIKey: "a"}
+
+	b := breakers.get(broken)
+	require.NoError(t, b.allow())
+	b.record(failed, nil)
+	assert.ErrorIs(t, breakers.get(broken).allow(), ErrCircuitOpen)
+
+	assert.NoError(t, breakers.get(bunnyNetDNSConfig{APIKey: "a"}).allow(), "other endpoints are unaffected")
+	assert.NoError(t, breakers.get(bunnyNetDNSConfig{APIURL: broken.APIURL, APIKey: "b"}).allow(), "other keys are unaffected")
+
+	var disabled *circuitBreakers
+	assert.NoError(t, disabled.get(broken).allow())
+}
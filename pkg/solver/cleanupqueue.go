@@ -0,0 +1,298 @@
+package solver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	// cleanupMaxRetries bounds how often a pending deletion is retried. With
+	// the backoff below that is several hours, long enough to ride out a
+	// Bunny API outage.
+	cleanupMaxRetries = 20
+	cleanupBaseDelay  = time.Second
+	cleanupMaxDelay   = 30 * time.Minute
+
+	// cleanupQueueMaxBytes caps the data of the cleanup queue ConfigMap,
+	// leaving headroom below the 1 MiB object size limit of the API server.
+	cleanupQueueMaxBytes = 768 << 10
+
+	// fallbackEntryPrefix marks the entries for records published through
+	// the fallback. They are not pending deletions, but let whichever
+	// replica gets the CleanUp remove the record from the fallback.
+	fallbackEntryPrefix = "fallback-"
+)
+
+// errCleanupQueueFull is returned by add when the ConfigMap has no room for
+// another pending deletion, as may happen during a long Bunny API outage.
+var errCleanupQueueFull = errors.New("cleanup queue is full")
+
+// cleanupQueue processes CleanUp asynchronously. cert-manager only calls
+// CleanUp a limited number of times, so a transient API failure could leave
+// the TXT record behind for good. Instead, CleanUp records the pending
+// deletion in a ConfigMap and returns; a background job retries deletions
+// from that ConfigMap with backoff. Keeping the queue in a ConfigMap means
+// pending deletions survive restarts and are shared between replicas.
+type cleanupQueue struct {
+	solver    *Solver
+	client    kubernetes.Interface
+	namespace string
+	name      string
+	maxBytes  int
+
+	factory informers.SharedInformerFactory
+	lister  corelisters.ConfigMapLister
+	queue   workqueue.TypedRateLimitingInterface[string]
+}
+
+func newCleanupQueue(solver *Solver, cl kubernetes.Interface, namespace, name string) (*cleanupQueue, error) {
+	factory := informers.NewSharedInformerFactoryWithOptions(cl, 0,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+		}),
+	)
+	q := &cleanupQueue{
+		solver:    solver,
+		client:    cl,
+		namespace: namespace,
+		name:      name,
+		maxBytes:  cleanupQueueMaxBytes,
+		factory:   factory,
+		lister:    factory.Core().V1().ConfigMaps().Lister(),
+		queue: workqueue.NewTypedRateLimitingQueue(
+			workqueue.NewTypedItemExponentialFailureRateLimiter[string](cleanupBaseDelay, cleanupMaxDelay),
+		),
+	}
+
+	enqueue := func(obj interface{}) {
+		cm, ok := obj.(*corev1.ConfigMap)
+		if !ok {
+			return
+		}
+		for key := range cm.Data {
+			if !strings.HasPrefix(key, fallbackEntryPrefix) {
+				q.queue.Add(key)
+			}
+		}
+	}
+	if _, err := factory.Core().V1().ConfigMaps().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    enqueue,
+		UpdateFunc: func(_, obj interface{}) { enqueue(obj) },
+	}); err != nil {
+		return nil, fmt.Errorf("failed to register cleanup queue event handler: %w", err)
+	}
+
+	return q, nil
+}
+
+func (q *cleanupQueue) job() BackgroundJob {
+	return BackgroundJob{Name: "cleanup-queue", Run: q.run}
+}
+
+// cleanupKey returns the key of the pending deletion for ch in the
+// ConfigMap. Challenge requests that don't come from a Challenge, such as
+// those of the gRPC API or the standalone commands, have no UID; they are
+// keyed by a hash of the record instead, so they don't overwrite each other.
+func cleanupKey(ch *v1alpha1.ChallengeRequest) string {
+	if ch.UID != "" {
+		return string(ch.UID)
+	}
+	sum := sha256.Sum256([]byte(ch.ResolvedFQDN + "\x00" + ch.Key))
+	return "sha256-" + hex.EncodeToString(sum[:])
+}
+
+// add persists a pending deletion for ch. It returns errCleanupQueueFull if
+// the ConfigMap would grow beyond maxBytes.
+func (q *cleanupQueue) add(ctx context.Context, ch *v1alpha1.ChallengeRequest) error {
+	data, err := json.Marshal(ch)
+	if err != nil {
+		return fmt.Errorf("failed to encode challenge request: %w", err)
+	}
+	key := cleanupKey(ch)
+	return q.update(ctx, func(cm *corev1.ConfigMap) error {
+		return q.put(cm, key, string(data))
+	})
+}
+
+// put sets key to value in cm unless that grows its data beyond maxBytes.
+func (q *cleanupQueue) put(cm *corev1.ConfigMap, key, value string) error {
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	size := len(key) + len(value)
+	for k, v := range cm.Data {
+		if k != key {
+			size += len(k) + len(v)
+		}
+	}
+	if size > q.maxBytes {
+		return errCleanupQueueFull
+	}
+	cm.Data[key] = value
+	return nil
+}
+
+// fallbackEntryKey returns the key of the entry for the fallback record of
+// fqdn with value.
+func fallbackEntryKey(fqdn, value string) string {
+	sum := sha256.Sum256([]byte(fqdn + "\x00" + value))
+	return fallbackEntryPrefix + hex.EncodeToString(sum[:])
+}
+
+// addFallback persists that the record of fqdn with value was published
+// through the fallback.
+func (q *cleanupQueue) addFallback(ctx context.Context, fqdn, value string) error {
+	return q.update(ctx, func(cm *corev1.ConfigMap) error {
+		return q.put(cm, fallbackEntryKey(fqdn, value), fqdn)
+	})
+}
+
+// hasFallback reports whether the record of fqdn with value was published
+// through the fallback. It reads the ConfigMap from the API server, as only
+// the leader runs the informer.
+func (q *cleanupQueue) hasFallback(ctx context.Context, fqdn, value string) (bool, error) {
+	cm, err := q.client.CoreV1().ConfigMaps(q.namespace).Get(ctx, q.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get cleanup queue configmap %s/%s: %w", q.namespace, q.name, err)
+	}
+	_, ok := cm.Data[fallbackEntryKey(fqdn, value)]
+	return ok, nil
+}
+
+// removeFallback drops the entry for the fallback record of fqdn with value.
+func (q *cleanupQueue) removeFallback(ctx context.Context, fqdn, value string) error {
+	return q.remove(ctx, fallbackEntryKey(fqdn, value))
+}
+
+// remove drops the pending deletion stored under key.
+func (q *cleanupQueue) remove(ctx context.Context, key string) error {
+	return q.update(ctx, func(cm *corev1.ConfigMap) error {
+		delete(cm.Data, key)
+		return nil
+	})
+}
+
+func (q *cleanupQueue) update(ctx context.Context, mutate func(*corev1.ConfigMap) error) error {
+	configMaps := q.client.CoreV1().ConfigMaps(q.namespace)
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := configMaps.Get(ctx, q.name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			cm = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: q.namespace, Name: q.name}}
+			if err := mutate(cm); err != nil {
+				return err
+			}
+			_, err = configMaps.Create(ctx, cm, metav1.CreateOptions{})
+			if apierrors.IsAlreadyExists(err) {
+				// Lost a race with another replica; retry as an update.
+				return apierrors.NewConflict(corev1.Resource("configmaps"), q.name, err)
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if err := mutate(cm); err != nil {
+			return err
+		}
+		_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+		return err
+	})
+	if errors.Is(err, errCleanupQueueFull) {
+		return fmt.Errorf("%w: configmap %s/%s holds %d bytes at most", err, q.namespace, q.name, q.maxBytes)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update cleanup queue configmap %s/%s: %w", q.namespace, q.name, err)
+	}
+	return nil
+}
+
+func (q *cleanupQueue) run(ctx context.Context) {
+	q.factory.Start(ctx.Done())
+	for typ, ok := range q.factory.WaitForCacheSync(ctx.Done()) {
+		if !ok {
+			slog.Error("failed to sync informer cache", "type", typ)
+			return
+		}
+	}
+
+	go func() {
+		<-ctx.Done()
+		q.queue.ShutDown()
+	}()
+
+	for q.processNextItem(ctx) {
+	}
+}
+
+func (q *cleanupQueue) processNextItem(ctx context.Context) bool {
+	key, shutdown := q.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer q.queue.Done(key)
+
+	err := q.sync(ctx, key)
+	switch {
+	case err == nil:
+		q.queue.Forget(key)
+	case q.queue.NumRequeues(key) < cleanupMaxRetries:
+		slog.Warn("failed to clean up challenge, will retry", "key", key, "error", err)
+		q.queue.AddRateLimited(key)
+	default:
+		slog.Error("giving up cleaning up challenge", "key", key, "attempts", cleanupMaxRetries, "error", err)
+		q.queue.Forget(key)
+		if err := q.remove(ctx, key); err != nil {
+			slog.Error("failed to remove cleanup entry", "key", key, "error", err)
+		}
+	}
+	return true
+}
+
+func (q *cleanupQueue) sync(ctx context.Context, key string) error {
+	cm, err := q.lister.ConfigMaps(q.namespace).Get(q.name)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	data, ok := cm.Data[key]
+	if !ok {
+		return nil
+	}
+
+	ch := &v1alpha1.ChallengeRequest{}
+	if err := json.Unmarshal([]byte(data), ch); err != nil {
+		// Retrying won't help with a corrupt entry.
+		slog.Error("dropping unreadable cleanup entry", "key", key, "error", err)
+		return q.remove(ctx, key)
+	}
+
+	if err := q.solver.cleanUp(ctx, ch); err != nil {
+		return err
+	}
+	return q.remove(ctx, key)
+}
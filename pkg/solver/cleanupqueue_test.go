@@ -0,0 +1,72 @@
+package solver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCleanupQueue_AddRemove(t *testing.T) {
+	ctx := context.Background()
+	cl := fake.NewSimpleClientset()
+	q, err := newCleanupQueue(New(Options{}), cl, "cert-manager", "bunny-cleanup")
+	require.NoError(t, err)
+
+	require.NoError(t, q.add(ctx, &v1alpha1.ChallengeRequest{UID: "one", ResolvedFQDN: "_acme-challenge.example.com."}))
+	require.NoError(t, q.add(ctx, &v1alpha1.ChallengeRequest{UID: "two", ResolvedFQDN: "_acme-challenge.example.org."}))
+
+	cm, err := cl.CoreV1().ConfigMaps("cert-manager").Get(ctx, "bunny-cleanup", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Len(t, cm.Data, 2)
+	assert.Contains(t, cm.Data["one"], "_acme-challenge.example.com.")
+
+	require.NoError(t, q.remove(ctx, "one"))
+
+	cm, err = cl.CoreV1().ConfigMaps("cert-manager").Get(ctx, "bunny-cleanup", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.NotContains(t, cm.Data, "one")
+	assert.Contains(t, cm.Data, "two")
+}
+
+func TestCleanupQueue_KeysWithoutUID(t *testing.T) {
+	ctx := context.Background()
+	cl := fake.NewSimpleClientset()
+	q, err := newCleanupQueue(New(Options{}), cl, "cert-manager", "bunny-cleanup")
+	require.NoError(t, err)
+
+	// Challenge requests of the gRPC API and standalone commands have no UID.
+	one := &v1alpha1.ChallengeRequest{ResolvedFQDN: "_acme-challenge.example.com.", Key: "one"}
+	two := &v1alpha1.ChallengeRequest{ResolvedFQDN: "_acme-challenge.example.com.", Key: "two"}
+	require.NoError(t, q.add(ctx, one))
+	require.NoError(t, q.add(ctx, two))
+
+	cm, err := cl.CoreV1().ConfigMaps("cert-manager").Get(ctx, "bunny-cleanup", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Len(t, cm.Data, 2)
+	assert.Contains(t, cm.Data, cleanupKey(one))
+	assert.NotEqual(t, cleanupKey(one), cleanupKey(two))
+	assert.Equal(t, "uid", cleanupKey(&v1alpha1.ChallengeRequest{UID: "uid", Key: "one"}))
+}
+
+func TestCleanupQueue_Full(t *testing.T) {
+	ctx := context.Background()
+	cl := fake.NewSimpleClientset()
+	q, err := newCleanupQueue(New(Options{}), cl, "cert-manager", "bunny-cleanup")
+	require.NoError(t, err)
+	q.maxBytes = 300
+
+	require.NoError(t, q.add(ctx, &v1alpha1.ChallengeRequest{UID: "one", ResolvedFQDN: "_acme-challenge.example.com."}))
+	err = q.add(ctx, &v1alpha1.ChallengeRequest{UID: "two", ResolvedFQDN: "_acme-challenge.example.org."})
+	assert.ErrorIs(t, err, errCleanupQueueFull)
+	// Updating a pending deletion doesn't count its old entry.
+	require.NoError(t, q.add(ctx, &v1alpha1.ChallengeRequest{UID: "one", ResolvedFQDN: "_acme-challenge.example.com."}))
+
+	cm, err := cl.CoreV1().ConfigMaps("cert-manager").Get(ctx, "bunny-cleanup", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Len(t, cm.Data, 1)
+}
@@ -0,0 +1,117 @@
+package solver
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/net/http/httpproxy"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// OpenShift publishes the cluster-wide egress proxy as the Proxy "cluster",
+// with the effective settings in its status and an optional trusted CA bundle
+// in a ConfigMap in openshift-config.
+const (
+	clusterProxyName        = "cluster"
+	clusterProxyCANamespace = "openshift-config"
+	clusterProxyCAKey       = "ca-bundle.crt"
+)
+
+var clusterProxyGVR = schema.GroupVersionResource{
+	Group:    "config.openshift.io",
+	Version:  "v1",
+	Resource: "proxies",
+}
+
+// clusterProxySettings is what the webhook uses from the Proxy resource.
+type clusterProxySettings struct {
+	HTTPProxy  string
+	HTTPSProxy string
+	NoProxy    string
+	TrustedCA  string
+}
+
+func loadClusterProxy(ctx context.Context, dyn dynamic.Interface, cl kubernetes.Interface) (clusterProxySettings, error) {
+	var settings clusterProxySettings
+
+	proxy, err := dyn.Resource(clusterProxyGVR).Get(ctx, clusterProxyName, metav1.GetOptions{})
+	if err != nil {
+		return settings, fmt.Errorf("failed to get cluster proxy: %w", err)
+	}
+	settings.HTTPProxy, _, _ = unstructured.NestedString(proxy.Object, "status", "httpProxy")
+	settings.HTTPSProxy, _, _ = unstructured.NestedString(proxy.Object, "status", "httpsProxy")
+	settings.NoProxy, _, _ = unstructured.NestedString(proxy.Object, "status", "noProxy")
+
+	caName, _, _ := unstructured.NestedString(proxy.Object, "spec", "trustedCA", "name")
+	if caName == "" {
+		return settings, nil
+	}
+	cm, err := cl.CoreV1().ConfigMaps(clusterProxyCANamespace).Get(ctx, caName, metav1.GetOptions{})
+	if err != nil {
+		return settings, fmt.Errorf("failed to get cluster proxy trusted CA %s/%s: %w", clusterProxyCANamespace, caName, err)
+	}
+	settings.TrustedCA = cm.Data[clusterProxyCAKey]
+	return settings, nil
+}
+
+// transport returns an HTTP transport using the proxy settings, trusting the
+// system roots plus the proxy's CA bundle.
+func (s clusterProxySettings) transport() (*http.Transport, error) {
+	proxyConfig := &httpproxy.Config{
+		HTTPProxy:  s.HTTPProxy,
+		HTTPSProxy: s.HTTPSProxy,
+		NoProxy:    s.NoProxy,
+	}
+	proxyFunc := proxyConfig.ProxyFunc()
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}
+
+	if s.TrustedCA != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(s.TrustedCA)) {
+			return nil, fmt.Errorf("cluster proxy trusted CA bundle contains no certificates")
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	return transport, nil
+}
+
+// applyClusterProxy routes Bunny API requests through the cluster-wide
+// egress proxy. The settings are read once at startup.
+func applyClusterProxy(ctx context.Context, dyn dynamic.Interface, cl kubernetes.Interface) error {
+	settings, err := loadClusterProxy(ctx, dyn, cl)
+	if err != nil {
+		return err
+	}
+	transport, err := settings.transport()
+	if err != nil {
+		return err
+	}
+	httpClient.Transport = transport
+	slog.Info("Using cluster proxy", "httpsProxy", redactProxyURL(settings.HTTPSProxy), "noProxy", settings.NoProxy)
+	return nil
+}
+
+// redactProxyURL hides credentials embedded in a proxy URL.
+func redactProxyURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+	return u.Redacted()
+}
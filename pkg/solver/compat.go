@@ -0,0 +1,105 @@
+package solver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/cert-manager/cert-manager/pkg/issuer/acme/dns/util"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/dynamic"
+)
+
+// minCertManagerVersion is the oldest cert-manager release supported: the
+// first to serve the acme.cert-manager.io/v1 API the solver reads Challenges
+// and Orders through.
+const minCertManagerVersion = "v1.0.0"
+
+const challengeCRDName = "challenges.acme.cert-manager.io"
+
+var crdGVR = schema.GroupVersionResource{
+	Group:    "apiextensions.k8s.io",
+	Version:  "v1",
+	Resource: "customresourcedefinitions",
+}
+
+// normalizeChallenge returns ch in the shape current cert-manager releases
+// send, tolerating the differences of older ones: wildcard prefixes in
+// DNSName, names without the trailing dot, in mixed case, in Unicode or
+// with surrounding whitespace, a missing
+// ResolvedFQDN or ResolvedZone and a JSON null config.
+func normalizeChallenge(ctx context.Context, ch *v1alpha1.ChallengeRequest) (*v1alpha1.ChallengeRequest, error) {
+	out := *ch
+	out.DNSName = strings.TrimPrefix(strings.TrimSpace(out.DNSName), "*.")
+
+	if strings.TrimSpace(out.ResolvedFQDN) == "" {
+		if out.DNSName == "" {
+			return nil, fmt.Errorf("challenge request has neither a resolved FQDN nor a DNS name")
+		}
+		out.ResolvedFQDN = "_acme-challenge." + out.DNSName
+	}
+	fqdn, err := canonicalName(out.ResolvedFQDN)
+	if err != nil {
+		return nil, err
+	}
+	out.ResolvedFQDN = fqdn
+
+	if strings.TrimSpace(out.ResolvedZone) == "" {
+		zone, err := util.FindZoneByFqdn(ctx, out.ResolvedFQDN, util.RecursiveNameservers)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve zone for %s: %w", out.ResolvedFQDN, err)
+		}
+		out.ResolvedZone = zone
+	}
+	zone, err := canonicalName(out.ResolvedZone)
+	if err != nil {
+		return nil, err
+	}
+	out.ResolvedZone = zone
+
+	if out.Config != nil && bytes.Equal(bytes.TrimSpace(out.Config.Raw), []byte("null")) {
+		out.Config = nil
+	}
+	return &out, nil
+}
+
+// checkCertManagerVersion fails if the cluster runs a cert-manager release
+// older than minCertManagerVersion, going by the version label cert-manager
+// puts on its CRDs. Clusters where the CRD cannot be read or carries no
+// version label are let through with a warning.
+func checkCertManagerVersion(ctx context.Context, dyn dynamic.Interface) error {
+	crd, err := dyn.Resource(crdGVR).Get(ctx, challengeCRDName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return fmt.Errorf("cert-manager is not installed: CRD %s not found", challengeCRDName)
+	}
+	if err != nil {
+		slog.Warn("Skipping the cert-manager version check", "error", err)
+		return nil
+	}
+
+	label := crd.GetLabels()["app.kubernetes.io/version"]
+	if label == "" {
+		slog.Warn("Skipping the cert-manager version check: CRD has no version label", "crd", challengeCRDName)
+		return nil
+	}
+	return compareCertManagerVersion(label)
+}
+
+func compareCertManagerVersion(installed string) error {
+	v, err := version.ParseSemantic(installed)
+	if err != nil {
+		slog.Warn("Skipping the cert-manager version check: cannot parse version", "version", installed, "error", err)
+		return nil
+	}
+	if v.LessThan(version.MustParseSemantic(minCertManagerVersion)) {
+		return fmt.Errorf("cert-manager %s is not supported, %s or later is required", installed, minCertManagerVersion)
+	}
+	slog.Info("Running against cert-manager", "version", installed)
+	return nil
+}
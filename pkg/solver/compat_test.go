@@ -0,0 +1,48 @@
+package solver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+
+	"github.com/cert-manager/webhook-example/pkg/bunny"
+)
+
+func TestNormalizeChallenge(t *testing.T) {
+	ch := &v1alpha1.ChallengeRequest{
+		DNSName:      "*.Example.com",
+		ResolvedZone: "Example.com",
+		Config:       &apiextensionsv1.JSON{Raw: []byte(" null")},
+	}
+	got, err := normalizeChallenge(context.Background(), ch)
+	require.NoError(t, err)
+	assert.Equal(t, "Example.com", got.DNSName)
+	assert.Equal(t, "_acme-challenge.example.com.", got.ResolvedFQDN)
+	assert.Equal(t, "example.com.", got.ResolvedZone)
+	assert.Nil(t, got.Config)
+	assert.Equal(t, "*.Example.com", ch.DNSName, "the request must not be modified")
+
+	_, err = normalizeChallenge(context.Background(), &v1alpha1.ChallengeRequest{})
+	assert.Error(t, err)
+}
+
+func TestNormalizeChallenge_IDN(t *testing.T) {
+	got, err := normalizeChallenge(context.Background(), &v1alpha1.ChallengeRequest{
+		ResolvedFQDN: "_acme-challenge.www.Bücher.example.",
+		ResolvedZone: "bücher.example",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "_acme-challenge.www.xn--bcher-kva.example.", got.ResolvedFQDN)
+	assert.Equal(t, "xn--bcher-kva.example.", got.ResolvedZone)
+	assert.Equal(t, "_acme-challenge.www", bunny.RecordName(got.ResolvedZone, got.ResolvedFQDN))
+}
+
+func TestCompareCertManagerVersion(t *testing.T) {
+	assert.NoError(t, compareCertManagerVersion("v1.16.3"))
+	assert.NoError(t, compareCertManagerVersion("not-a-version"))
+	assert.Error(t, compareCertManagerVersion("v0.16.1"))
+}
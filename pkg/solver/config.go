@@ -0,0 +1,496 @@
+package solver
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	"golang.org/x/time/rate"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/cert-manager/webhook-example/pkg/bunny"
+)
+
+// Versions of the Issuer webhook config schema. A config without an
+// apiVersion is treated as v1alpha1, the original shape.
+const (
+	configVersionV1Alpha1 = "v1alpha1"
+	configVersionV1Beta1  = "v1beta1"
+)
+
+// bunnyNetDNSConfig is the internal representation of the solver config.
+// Every versioned schema is converted into it, so the rest of the solver
+// never sees field renames between versions.
+type bunnyNetDNSConfig struct {
+	// APIKeySecretRef references the Secret holding the Bunny API key. The
+	// Secret is read from the challenge's resource namespace.
+	APIKeySecretRef *cmmeta.SecretKeySelector
+
+	// ZoneEndpoints send the API calls for specific zones to another
+	// endpoint or account.
+	ZoneEndpoints []zoneEndpoint
+
+	// Provider selects the DNS backend, Bunny when empty. ProviderConfig is
+	// handed to other backends undecoded.
+	Provider       string
+	ProviderConfig []byte
+
+	// TTL is the record TTL in seconds, the solver's RecordTTL when zero.
+	TTL int
+
+	// Zone, when set, replaces the zone cert-manager resolved for the
+	// challenge, e.g. when the public SOA lookup finds a parent zone.
+	Zone string
+
+	// ZoneID, when set, pins the Bunny zone records are written to,
+	// skipping the zone lookup. It only applies to the primary account.
+	ZoneID int64
+
+	// Upsert updates a stale TXT record of the challenge name in place
+	// instead of creating another one next to it.
+	Upsert bool
+
+	// PropagationTimeout and PollInterval control how long and how often
+	// Present polls for the new record, the solver's defaults when zero.
+	PropagationTimeout time.Duration
+	PollInterval       time.Duration
+
+	// KeepRecordsOnCleanup leaves challenge records in place on CleanUp,
+	// for debugging failed issuance only.
+	KeepRecordsOnCleanup bool
+
+	// RecordTemplates rewrite the record name and value, in order.
+	RecordTemplates []recordTemplate
+
+	// APIKey is the resolved API key and is never read from the Issuer.
+	// keySource is where it came from.
+	APIKey    string
+	keySource keySource
+
+	// AuthScheme is how APIKey is sent, AuthSchemeAccessKey when empty.
+	AuthScheme string
+
+	// APIURL is the resolved API endpoint: the zone endpoint's, else the
+	// Issuer's. Empty means the process-wide API base.
+	APIURL string
+
+	// fallbackKeys are tried in order when Bunny rejects APIKey.
+	fallbackKeys []string
+
+	instrumentation Instrumentation
+	retry           RetryPolicy
+	limiter         *rate.Limiter
+	breakers        *circuitBreakers
+	zones           *zoneCache
+}
+
+// keySource is where the API key of a challenge came from.
+type keySource int
+
+const (
+	// keyFromIssuer is a key from the Issuer's own apiKeySecretRef.
+	keyFromIssuer keySource = iota
+	// keyFromBinding is a key from a BunnyZoneBinding.
+	keyFromBinding
+	// keyAmbient is the webhook-wide key.
+	keyAmbient
+)
+
+// zoneEndpoint maps zones to an alternative API endpoint, e.g. a regional
+// gateway in front of the Bunny API, and optionally its own credentials.
+type zoneEndpoint struct {
+	Zones           []string                  `json:"zones"`
+	APIURL          string                    `json:"apiURL,omitempty"`
+	APIKeySecretRef *cmmeta.SecretKeySelector `json:"apiKeySecretRef,omitempty"`
+}
+
+// Ways of sending the API key to the Bunny API.
+const (
+	AuthSchemeAccessKey = bunny.AuthSchemeAccessKey
+	AuthSchemeBearer    = bunny.AuthSchemeBearer
+)
+
+// authorize adds the API key to req according to the auth scheme. Keys
+// sent are redacted from logs and errors from then on.
+func (cfg bunnyNetDNSConfig) authorize(req *http.Request) {
+	registerSecret(cfg.APIKey)
+	bunny.Authorize(req, cfg.APIKey, cfg.AuthScheme)
+}
+
+// client returns a Bunny API client for cfg. Its requests go through
+// cfg.do, which authenticates, retries, rate limits and instruments them.
+func (cfg bunnyNetDNSConfig) client() bunny.Client {
+	return bunny.New(bunny.Options{BaseURL: cfg.apiBase(), Doer: bunny.DoerFunc(cfg.do)})
+}
+
+func validateAuthScheme(path, scheme string) error {
+	switch scheme {
+	case "", AuthSchemeAccessKey, AuthSchemeBearer:
+		return nil
+	}
+	return &configFieldError{
+		Field:  path,
+		Reason: fmt.Sprintf("must be %s or %s, got %q", AuthSchemeAccessKey, AuthSchemeBearer, scheme),
+	}
+}
+
+// MaxRecordTTL is the longest record TTL Bunny DNS offers, one day.
+const MaxRecordTTL = 86400
+
+// ValidateRecordTTL fails for TTLs Bunny doesn't accept. Zero, meaning the
+// default TTL, is valid.
+func ValidateRecordTTL(ttl int) error {
+	if ttl < 0 || ttl > MaxRecordTTL {
+		return fmt.Errorf("must be between 1 and %d seconds, got %d", MaxRecordTTL, ttl)
+	}
+	return nil
+}
+
+func validateDuration(path string, d *metav1.Duration) error {
+	if d != nil && d.Duration < 0 {
+		return &configFieldError{Field: path, Reason: "must not be negative"}
+	}
+	return nil
+}
+
+func durationOf(d *metav1.Duration) time.Duration {
+	if d == nil {
+		return 0
+	}
+	return d.Duration
+}
+
+func validateTTL(path string, ttl int) error {
+	if err := ValidateRecordTTL(ttl); err != nil {
+		return &configFieldError{Field: path, Reason: err.Error()}
+	}
+	return nil
+}
+
+// bunnyAPIBase is where API calls go unless an Issuer or zone endpoint
+// says otherwise.
+var bunnyAPIBase = DefaultAPIBase
+
+// SetAPIBase changes the process-wide Bunny API base URL, e.g. to a mock
+// server or an internal API gateway. It must be called before the solver
+// makes API calls.
+func SetAPIBase(base string) error {
+	if err := ValidateAPIBase(base); err != nil {
+		return err
+	}
+	bunnyAPIBase = strings.TrimSuffix(base, "/")
+	return nil
+}
+
+// ValidateAPIBase checks that base is usable as an API base URL.
+func ValidateAPIBase(base string) error {
+	if !validAPIURL(base) {
+		return fmt.Errorf("API base %q must be an http(s) URL", base)
+	}
+	return nil
+}
+
+// validAPIURL reports whether s is a usable API endpoint.
+func validAPIURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+}
+
+// apiBase returns the base URL API calls for cfg go to.
+func (cfg bunnyNetDNSConfig) apiBase() string {
+	if cfg.APIURL != "" {
+		return strings.TrimSuffix(cfg.APIURL, "/")
+	}
+	return bunnyAPIBase
+}
+
+// forZone applies the zone endpoint covering zone, if any.
+func (cfg bunnyNetDNSConfig) forZone(zone string) bunnyNetDNSConfig {
+	for _, ep := range cfg.ZoneEndpoints {
+		for _, z := range ep.Zones {
+			if foldName(z) != foldName(zone) {
+				continue
+			}
+			if ep.APIURL != "" {
+				cfg.APIURL = ep.APIURL
+			}
+			if ep.APIKeySecretRef != nil {
+				cfg.APIKeySecretRef = ep.APIKeySecretRef
+			}
+			return cfg
+		}
+	}
+	return cfg
+}
+
+func validateAPIURL(path, s string) error {
+	if s != "" && !validAPIURL(s) {
+		return &configFieldError{Field: path, Reason: "must be an http(s) URL"}
+	}
+	return nil
+}
+
+func validateZoneEndpoints(path string, endpoints []zoneEndpoint) error {
+	for i, ep := range endpoints {
+		field := fmt.Sprintf("%s[%d]", path, i)
+		if len(ep.Zones) == 0 {
+			return &configFieldError{Field: field + ".zones", Reason: "must not be empty"}
+		}
+		if ep.APIURL == "" && ep.APIKeySecretRef == nil {
+			return &configFieldError{Field: field, Reason: "must set apiURL or apiKeySecretRef"}
+		}
+		if err := validateAPIURL(field+".apiURL", ep.APIURL); err != nil {
+			return err
+		}
+		if err := validateSecretRef(field+".apiKeySecretRef", ep.APIKeySecretRef); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// configV1Alpha1 is the original config shape.
+type configV1Alpha1 struct {
+	APIVersion           string                    `json:"apiVersion,omitempty"`
+	APIKeySecretRef      *cmmeta.SecretKeySelector `json:"apiKeySecretRef,omitempty"`
+	APIURL               string                    `json:"apiURL,omitempty"`
+	ZoneEndpoints        []zoneEndpoint            `json:"zoneEndpoints,omitempty"`
+	Provider             string                    `json:"provider,omitempty"`
+	ProviderConfig       json.RawMessage           `json:"providerConfig,omitempty"`
+	AuthScheme           string                    `json:"authScheme,omitempty"`
+	RecordTemplates      []recordTemplate          `json:"recordTemplates,omitempty"`
+	TTL                  int                       `json:"ttl,omitempty"`
+	Zone                 string                    `json:"zone,omitempty"`
+	ZoneID               int64                     `json:"zoneID,omitempty"`
+	UpsertRecords        bool                      `json:"upsertRecords,omitempty"`
+	KeepRecordsOnCleanup bool                      `json:"keepRecordsOnCleanup,omitempty"`
+	PropagationTimeout   *metav1.Duration          `json:"propagationTimeout,omitempty"`
+	PollInterval         *metav1.Duration          `json:"pollInterval,omitempty"`
+}
+
+func (v configV1Alpha1) validate() error {
+	if err := validateSecretRef("apiKeySecretRef", v.APIKeySecretRef); err != nil {
+		return err
+	}
+	if err := validateAuthScheme("authScheme", v.AuthScheme); err != nil {
+		return err
+	}
+	if err := validateAPIURL("apiURL", v.APIURL); err != nil {
+		return err
+	}
+	if err := validateRecordTemplates("recordTemplates", v.RecordTemplates); err != nil {
+		return err
+	}
+	if err := validateTTL("ttl", v.TTL); err != nil {
+		return err
+	}
+	if err := validateDuration("propagationTimeout", v.PropagationTimeout); err != nil {
+		return err
+	}
+	if err := validateDuration("pollInterval", v.PollInterval); err != nil {
+		return err
+	}
+	if v.ZoneID < 0 {
+		return &configFieldError{Field: "zoneID", Reason: "must be a positive zone ID"}
+	}
+	return validateZoneEndpoints("zoneEndpoints", v.ZoneEndpoints)
+}
+
+func (v configV1Alpha1) convert() bunnyNetDNSConfig {
+	return bunnyNetDNSConfig{
+		APIKeySecretRef:      v.APIKeySecretRef,
+		APIURL:               v.APIURL,
+		ZoneEndpoints:        v.ZoneEndpoints,
+		Provider:             v.Provider,
+		ProviderConfig:       v.ProviderConfig,
+		AuthScheme:           v.AuthScheme,
+		RecordTemplates:      v.RecordTemplates,
+		TTL:                  v.TTL,
+		Zone:                 v.Zone,
+		ZoneID:               v.ZoneID,
+		Upsert:               v.UpsertRecords,
+		KeepRecordsOnCleanup: v.KeepRecordsOnCleanup,
+		PropagationTimeout:   durationOf(v.PropagationTimeout),
+		PollInterval:         durationOf(v.PollInterval),
+	}
+}
+
+// configV1Beta1 groups credentials under their own key.
+type configV1Beta1 struct {
+	APIVersion     string              `json:"apiVersion"`
+	Credentials    *credentialsV1Beta1 `json:"credentials,omitempty"`
+	APIURL         string              `json:"apiURL,omitempty"`
+	ZoneEndpoints  []zoneEndpoint      `json:"zoneEndpoints,omitempty"`
+	Provider       string              `json:"provider,omitempty"`
+	ProviderConfig json.RawMessage     `json:"providerConfig,omitempty"`
+
+	RecordTemplates      []recordTemplate `json:"recordTemplates,omitempty"`
+	TTL                  int              `json:"ttl,omitempty"`
+	Zone                 string           `json:"zone,omitempty"`
+	ZoneID               int64            `json:"zoneID,omitempty"`
+	UpsertRecords        bool             `json:"upsertRecords,omitempty"`
+	KeepRecordsOnCleanup bool             `json:"keepRecordsOnCleanup,omitempty"`
+	PropagationTimeout   *metav1.Duration `json:"propagationTimeout,omitempty"`
+	PollInterval         *metav1.Duration `json:"pollInterval,omitempty"`
+}
+
+type credentialsV1Beta1 struct {
+	APIKeySecretRef *cmmeta.SecretKeySelector `json:"apiKeySecretRef,omitempty"`
+	AuthScheme      string                    `json:"authScheme,omitempty"`
+}
+
+func (v configV1Beta1) validate() error {
+	if v.Credentials != nil {
+		if err := validateSecretRef("credentials.apiKeySecretRef", v.Credentials.APIKeySecretRef); err != nil {
+			return err
+		}
+		if err := validateAuthScheme("credentials.authScheme", v.Credentials.AuthScheme); err != nil {
+			return err
+		}
+	}
+	if err := validateAPIURL("apiURL", v.APIURL); err != nil {
+		return err
+	}
+	if err := validateRecordTemplates("recordTemplates", v.RecordTemplates); err != nil {
+		return err
+	}
+	if err := validateTTL("ttl", v.TTL); err != nil {
+		return err
+	}
+	if err := validateDuration("propagationTimeout", v.PropagationTimeout); err != nil {
+		return err
+	}
+	if err := validateDuration("pollInterval", v.PollInterval); err != nil {
+		return err
+	}
+	if v.ZoneID < 0 {
+		return &configFieldError{Field: "zoneID", Reason: "must be a positive zone ID"}
+	}
+	return validateZoneEndpoints("zoneEndpoints", v.ZoneEndpoints)
+}
+
+func (v configV1Beta1) convert() bunnyNetDNSConfig {
+	cfg := bunnyNetDNSConfig{
+		APIURL:               v.APIURL,
+		ZoneEndpoints:        v.ZoneEndpoints,
+		Provider:             v.Provider,
+		ProviderConfig:       v.ProviderConfig,
+		RecordTemplates:      v.RecordTemplates,
+		TTL:                  v.TTL,
+		Zone:                 v.Zone,
+		ZoneID:               v.ZoneID,
+		Upsert:               v.UpsertRecords,
+		KeepRecordsOnCleanup: v.KeepRecordsOnCleanup,
+		PropagationTimeout:   durationOf(v.PropagationTimeout),
+		PollInterval:         durationOf(v.PollInterval),
+	}
+	if v.Credentials != nil {
+		cfg.APIKeySecretRef = v.Credentials.APIKeySecretRef
+		cfg.AuthScheme = v.Credentials.AuthScheme
+	}
+	return cfg
+}
+
+// configFieldError reports a problem with a single field of the Issuer's
+// webhook config, so a bad Issuer fails with a message naming the field.
+type configFieldError struct {
+	Field  string
+	Reason string
+}
+
+func (e *configFieldError) Error() string {
+	return fmt.Sprintf("invalid solver config: %s %s", e.Field, e.Reason)
+}
+
+// decodeConfig strictly decodes and validates an Issuer webhook config of
+// any supported version and converts it to the internal representation.
+func decodeConfig(raw []byte) (bunnyNetDNSConfig, error) {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return bunnyNetDNSConfig{}, nil
+	}
+
+	var meta struct {
+		APIVersion string `json:"apiVersion"`
+	}
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return bunnyNetDNSConfig{}, configDecodeError(err)
+	}
+
+	switch meta.APIVersion {
+	case "", configVersionV1Alpha1:
+		var v configV1Alpha1
+		if err := strictUnmarshal(raw, &v); err != nil {
+			return bunnyNetDNSConfig{}, err
+		}
+		if err := v.validate(); err != nil {
+			return bunnyNetDNSConfig{}, err
+		}
+		return v.convert(), nil
+	case configVersionV1Beta1:
+		var v configV1Beta1
+		if err := strictUnmarshal(raw, &v); err != nil {
+			return bunnyNetDNSConfig{}, err
+		}
+		if err := v.validate(); err != nil {
+			return bunnyNetDNSConfig{}, err
+		}
+		return v.convert(), nil
+	default:
+		return bunnyNetDNSConfig{}, &configFieldError{
+			Field: "apiVersion",
+			Reason: fmt.Sprintf("%q is not supported, must be one of %s or %s",
+				meta.APIVersion, configVersionV1Alpha1, configVersionV1Beta1),
+		}
+	}
+}
+
+func strictUnmarshal(raw []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return configDecodeError(err)
+	}
+	if dec.More() {
+		return errors.New("invalid solver config: unexpected data after the config object")
+	}
+	return nil
+}
+
+func configDecodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return &configFieldError{
+			Field:  typeErr.Field,
+			Reason: fmt.Sprintf("must be of type %s, got %s", typeErr.Type, typeErr.Value),
+		}
+	}
+
+	// encoding/json has no typed error for unknown fields.
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return &configFieldError{
+			Field:  strings.Trim(field, `"`),
+			Reason: "is not a known field",
+		}
+	}
+
+	return fmt.Errorf("invalid solver config: %w", err)
+}
+
+func validateSecretRef(path string, ref *cmmeta.SecretKeySelector) error {
+	if ref == nil {
+		return nil
+	}
+	if ref.Name == "" {
+		return &configFieldError{Field: path + ".name", Reason: "must be set"}
+	}
+	if ref.Key == "" {
+		return &configFieldError{Field: path + ".key", Reason: "must be set"}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package solver
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeConfig(t *testing.T) {
+	cfg, err := decodeConfig([]byte(`{"apiKeySecretRef":{"name":"bunny","key":"api-key"}}`))
+	require.NoError(t, err)
+	require.NotNil(t, cfg.APIKeySecretRef)
+	assert.Equal(t, "bunny", cfg.APIKeySecretRef.Name)
+	assert.Equal(t, "api-key", cfg.APIKeySecretRef.Key)
+}
+
+func TestDecodeConfig_V1Beta1(t *testing.T) {
+	cfg, err := decodeConfig([]byte(`{"apiVersion":"v1beta1","credentials":{"apiKeySecretRef":{"name":"bunny","key":"api-key"}}}`))
+	require.NoError(t, err)
+	require.NotNil(t, cfg.APIKeySecretRef)
+	assert.Equal(t, "bunny", cfg.APIKeySecretRef.Name)
+	assert.Equal(t, "api-key", cfg.APIKeySecretRef.Key)
+}
+
+func TestDecodeConfig_Empty(t *testing.T) {
+	cfg, err := decodeConfig(nil)
+	require.NoError(t, err)
+	assert.Nil(t, cfg.APIKeySecretRef)
+}
+
+func TestDecodeConfig_FieldErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		raw   string
+		field string
+	}{
+		{"unknown field", `{"apiKeySecret":{"name":"bunny"}}`, "apiKeySecret"},
+		{"wrong type", `{"apiKeySecretRef":{"name":1,"key":"api-key"}}`, "apiKeySecretRef.name"},
+		{"missing name", `{"apiKeySecretRef":{"key":"api-key"}}`, "apiKeySecretRef.name"},
+		{"missing key", `{"apiKeySecretRef":{"name":"bunny"}}`, "apiKeySecretRef.key"},
+		{"unknown version", `{"apiVersion":"v2"}`, "apiVersion"},
+		{"version type", `{"apiVersion":2}`, "apiVersion"},
+		{"v1alpha1 field in v1beta1", `{"apiVersion":"v1beta1","apiKeySecretRef":{"name":"bunny","key":"api-key"}}`, "apiKeySecretRef"},
+		{"v1beta1 missing key", `{"apiVersion":"v1beta1","credentials":{"apiKeySecretRef":{"name":"bunny"}}}`, "credentials.apiKeySecretRef.key"},
+		{"zone endpoint without zones", `{"zoneEndpoints":[{"apiURL":"https://gw.example.com"}]}`, "zoneEndpoints[0].zones"},
+		{"unknown auth scheme", `{"authScheme":"Basic"}`, "authScheme"},
+		{"v1beta1 unknown auth scheme", `{"apiVersion":"v1beta1","credentials":{"authScheme":"Basic"}}`, "credentials.authScheme"},
+		{"negative ttl", `{"ttl":-1}`, "ttl"},
+		{"ttl above a day", `{"apiVersion":"v1beta1","ttl":86401}`, "ttl"},
+		{"negative poll interval", `{"pollInterval":"-5s"}`, "pollInterval"},
+		{"zone endpoint bad URL", `{"zoneEndpoints":[{"zones":["example.com"],"apiURL":"gw.example.com"}]}`, "zoneEndpoints[0].apiURL"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := decodeConfig([]byte(test.raw))
+			var fieldErr *configFieldError
+			require.True(t, errors.As(err, &fieldErr), "expected a configFieldError, got %v", err)
+			assert.Equal(t, test.field, fieldErr.Field)
+		})
+	}
+}
+
+func TestDecodeConfig_ZoneEndpoints(t *testing.T) {
+	cfg, err := decodeConfig([]byte(`{"apiKeySecretRef":{"name":"bunny","key":"api-key"},"zoneEndpoints":[{"zones":["eu.example.com"],"apiURL":"https://bunny-gw.eu.example.com/","apiKeySecretRef":{"name":"bunny-eu","key":"api-key"}}]}`))
+	require.NoError(t, err)
+
+	eu := cfg.forZone("eu.example.com.")
+	assert.Equal(t, "https://bunny-gw.eu.example.com", eu.apiBase())
+	assert.Equal(t, "bunny-eu", eu.APIKeySecretRef.Name)
+
+	other := cfg.forZone("example.com.")
+	assert.Equal(t, bunnyAPIBase, other.apiBase())
+	assert.Equal(t, "bunny", other.APIKeySecretRef.Name)
+}
+
+func TestDecodeConfig_APIURL(t *testing.T) {
+	cfg, err := decodeConfig([]byte(`{"apiVersion":"v1beta1","apiURL":"https://bunny-gw.example.com/","zoneEndpoints":[{"zones":["eu.example.com"],"apiKeySecretRef":{"name":"bunny-eu","key":"api-key"}}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, "https://bunny-gw.example.com", cfg.apiBase())
+	assert.Equal(t, "https://bunny-gw.example.com", cfg.forZone("eu.example.com.").apiBase(), "endpoints without apiURL keep the Issuer's")
+
+	_, err = decodeConfig([]byte(`{"apiURL":"bunny-gw.example.com"}`))
+	assert.Error(t, err)
+}
+
+func TestSetAPIBase(t *testing.T) {
+	defer func() { bunnyAPIBase = DefaultAPIBase }()
+
+	require.NoError(t, SetAPIBase("http://localhost:8080/"))
+	assert.Equal(t, "http://localhost:8080", bunnyNetDNSConfig{}.apiBase())
+	assert.Error(t, SetAPIBase("localhost:8080"))
+}
+
+func TestAuthorize(t *testing.T) {
+	cfg, err := decodeConfig([]byte(`{"apiVersion":"v1beta1","credentials":{"authScheme":"Bearer"}}`))
+	require.NoError(t, err)
+	cfg.APIKey = "key"
+
+	req, _ := http.NewRequest(http.MethodGet, bunnyAPIBase, nil)
+	cfg.authorize(req)
+	assert.Equal(t, "Bearer key", req.Header.Get("Authorization"))
+	assert.Empty(t, req.Header.Get("AccessKey"))
+
+	req, _ = http.NewRequest(http.MethodGet, bunnyAPIBase, nil)
+	bunnyNetDNSConfig{APIKey: "key"}.authorize(req)
+	assert.Equal(t, "key", req.Header.Get("AccessKey"))
+	assert.Empty(t, req.Header.Get("Authorization"))
+}
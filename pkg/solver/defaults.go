@@ -0,0 +1,102 @@
+package solver
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// defaultsConfigMapKey is the key in the defaults ConfigMap holding solver
+// config in the same shape as an Issuer's webhook config, as YAML or JSON.
+const defaultsConfigMapKey = "defaults.yaml"
+
+// solverDefaults supplies fleet-wide solver settings from a ConfigMap in the
+// webhook's own namespace. Issuer config is merged on top, so an Issuer only
+// needs to spell out what differs from the defaults.
+type solverDefaults struct {
+	namespace string
+	name      string
+	lister    corelisters.ConfigMapLister
+}
+
+func newSolverDefaults(cl kubernetes.Interface, namespace, name string, stopCh <-chan struct{}) (*solverDefaults, error) {
+	factory := informers.NewSharedInformerFactoryWithOptions(cl, 0, informers.WithNamespace(namespace))
+	informer := factory.Core().V1().ConfigMaps()
+	lister := informer.Lister()
+
+	factory.Start(stopCh)
+	for typ, ok := range factory.WaitForCacheSync(stopCh) {
+		if !ok {
+			return nil, fmt.Errorf("failed to sync informer cache for %v", typ)
+		}
+	}
+
+	return &solverDefaults{namespace: namespace, name: name, lister: lister}, nil
+}
+
+// get returns the defaults as JSON, or nil if there are none.
+func (d *solverDefaults) get() ([]byte, error) {
+	if d == nil {
+		return nil, nil
+	}
+
+	cm, err := d.lister.ConfigMaps(d.namespace).Get(d.name)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get defaults configmap %s/%s: %w", d.namespace, d.name, err)
+	}
+
+	data, ok := cm.Data[defaultsConfigMapKey]
+	if !ok {
+		slog.Warn("Defaults configmap has no defaults key, ignoring it", "namespace", d.namespace, "name", d.name, "key", defaultsConfigMapKey)
+		return nil, nil
+	}
+
+	raw, err := yaml.YAMLToJSON([]byte(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse defaults configmap %s/%s: %w", d.namespace, d.name, err)
+	}
+	return raw, nil
+}
+
+// mergeConfig overlays the Issuer's config onto the defaults. Objects are
+// merged recursively; any other value set by the Issuer replaces the default.
+func mergeConfig(defaults, issuer []byte) ([]byte, error) {
+	if len(defaults) == 0 {
+		return issuer, nil
+	}
+
+	var base map[string]interface{}
+	if err := json.Unmarshal(defaults, &base); err != nil {
+		return nil, fmt.Errorf("defaults must be an object: %w", err)
+	}
+	if len(issuer) > 0 {
+		var override map[string]interface{}
+		if err := json.Unmarshal(issuer, &override); err != nil {
+			return nil, fmt.Errorf("invalid solver config: %w", err)
+		}
+		mergeObjects(base, override)
+	}
+
+	return json.Marshal(base)
+}
+
+func mergeObjects(dst, src map[string]interface{}) {
+	for k, v := range src {
+		srcObj, srcIsObj := v.(map[string]interface{})
+		dstObj, dstIsObj := dst[k].(map[string]interface{})
+		if srcIsObj && dstIsObj {
+			mergeObjects(dstObj, srcObj)
+			continue
+		}
+		dst[k] = v
+	}
+}
@@ -0,0 +1,30 @@
+package solver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		defaults string
+		issuer   string
+		expected string
+	}{
+		{"no defaults", ``, `{"a":1}`, `{"a":1}`},
+		{"no issuer config", `{"a":1}`, ``, `{"a":1}`},
+		{"issuer wins", `{"a":1,"b":2}`, `{"a":3}`, `{"a":3,"b":2}`},
+		{"nested objects merge", `{"ref":{"name":"default","key":"api-key"}}`, `{"ref":{"name":"team"}}`, `{"ref":{"key":"api-key","name":"team"}}`},
+		{"non-objects replace", `{"list":[1,2]}`, `{"list":[3]}`, `{"list":[3]}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			merged, err := mergeConfig([]byte(test.defaults), []byte(test.issuer))
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, string(merged))
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package solver
+
+import (
+	"strings"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+// delegated returns the challenge as written to the delegation zone. The
+// record for _acme-challenge.<domain> lives at <domain>.<delegation zone>,
+// which the domain's _acme-challenge name must be a CNAME to. Challenges
+// cert-manager already resolved into the delegation zone, by following such
+// a CNAME, are returned unchanged.
+func (c *Solver) delegated(ch *v1alpha1.ChallengeRequest) *v1alpha1.ChallengeRequest {
+	if c.opts.DelegationZone == "" {
+		return ch
+	}
+	zone := withTrailingDot(c.opts.DelegationZone)
+	if foldName(ch.ResolvedZone) == foldName(zone) {
+		return ch
+	}
+
+	out := *ch
+	out.ResolvedZone = zone
+	out.ResolvedFQDN = DelegatedFQDN(ch.ResolvedFQDN, zone)
+	return &out
+}
+
+// DelegatedFQDN returns the name in delegationZone that the challenge
+// record for fqdn is written to, i.e. the CNAME target of fqdn.
+func DelegatedFQDN(fqdn, delegationZone string) string {
+	domain := strings.TrimSuffix(strings.TrimPrefix(fqdn, "_acme-challenge."), ".")
+	return strings.ToLower(domain) + "." + withTrailingDot(delegationZone)
+}
@@ -0,0 +1,26 @@
+package solver
+
+import (
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDelegated(t *testing.T) {
+	ch := &v1alpha1.ChallengeRequest{
+		ResolvedFQDN: "_acme-challenge.www.Example.com.",
+		ResolvedZone: "example.com.",
+	}
+
+	assert.Same(t, ch, New(Options{}).delegated(ch))
+
+	s := New(Options{DelegationZone: "acme.example.net"})
+	got := s.delegated(ch)
+	assert.Equal(t, "www.example.com.acme.example.net.", got.ResolvedFQDN)
+	assert.Equal(t, "acme.example.net.", got.ResolvedZone)
+	assert.Equal(t, "_acme-challenge.www.Example.com.", ch.ResolvedFQDN, "the request must not be modified")
+
+	// cert-manager followed the CNAME into the delegation zone already.
+	assert.Same(t, got, s.delegated(got))
+}
@@ -0,0 +1,39 @@
+package solver
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// deprecation describes a legacy configuration option and what replaces it.
+type deprecation struct {
+	replacement string
+	hint        string
+}
+
+var deprecations = map[string]deprecation{
+	"API_KEY": {
+		replacement: "apiKeySecretRef",
+		hint:        "store the Bunny API key in a Secret and reference it from the Issuer's webhook config",
+	},
+}
+
+var deprecationWarned sync.Map
+
+// warnDeprecated records a use of a deprecated option. The warning is logged
+// once per option for the lifetime of the process, while the metric counts
+// every use so operators can tell whether a migration is complete.
+func warnDeprecated(option string) {
+	deprecatedConfigTotal.WithLabelValues(option).Inc()
+
+	if _, warned := deprecationWarned.LoadOrStore(option, struct{}{}); warned {
+		return
+	}
+
+	d, ok := deprecations[option]
+	if !ok {
+		slog.Warn("deprecated configuration option used", "option", option)
+		return
+	}
+	slog.Warn("deprecated configuration option used", "option", option, "replacement", d.replacement, "hint", d.hint)
+}
@@ -0,0 +1,55 @@
+package solver
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cert-manager/webhook-example/pkg/bunny/bunnytest"
+)
+
+func TestWarnDeprecated(t *testing.T) {
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	deprecationWarned.Delete("API_KEY")
+	deprecationWarned.Delete("LEGACY_OPTION")
+	used := testutil.ToFloat64(deprecatedConfigTotal.WithLabelValues("API_KEY"))
+
+	warnDeprecated("API_KEY")
+	warnDeprecated("API_KEY")
+	warnDeprecated("LEGACY_OPTION")
+
+	assert.Equal(t, used+2, testutil.ToFloat64(deprecatedConfigTotal.WithLabelValues("API_KEY")), "every use is counted")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2, "each option is logged once")
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+	assert.Equal(t, "WARN", record["level"])
+	assert.Equal(t, "API_KEY", record["option"])
+	assert.Equal(t, "apiKeySecretRef", record["replacement"])
+	assert.NotEmpty(t, record["hint"])
+
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &record))
+	assert.Equal(t, "LEGACY_OPTION", record["option"])
+}
+
+func TestPresent_CountsAmbientAPIKey(t *testing.T) {
+	api := bunnytest.NewServer()
+	defer api.Close()
+	api.RequireAPIKey("secret")
+	api.AddZone("example.com")
+	used := testutil.ToFloat64(deprecatedConfigTotal.WithLabelValues("API_KEY"))
+
+	s := New(Options{APIKey: "secret", APIBase: api.URL})
+	require.NoError(t, s.Present(fakeChallenge(api, "token")))
+
+	assert.Equal(t, used+1, testutil.ToFloat64(deprecatedConfigTotal.WithLabelValues("API_KEY")))
+}
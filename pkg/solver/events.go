@@ -0,0 +1,108 @@
+package solver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+// Types of the challenge lifecycle events.
+const (
+	EventPresented  = "net.bunny.webhook.challenge.presented"
+	EventPropagated = "net.bunny.webhook.challenge.propagated"
+	EventCleaned    = "net.bunny.webhook.challenge.cleaned"
+	EventFailed     = "net.bunny.webhook.challenge.failed"
+)
+
+// ChallengeEvent is a step in the lifecycle of a challenge record.
+type ChallengeEvent struct {
+	Type string
+	Time time.Time
+	Data ChallengeEventData
+}
+
+// ChallengeEventData describes the challenge an event is about.
+type ChallengeEventData struct {
+	UID       string `json:"uid,omitempty"`
+	Domain    string `json:"domain"`
+	FQDN      string `json:"fqdn"`
+	Zone      string `json:"zone"`
+	Namespace string `json:"namespace,omitempty"`
+	Operation string `json:"operation,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// EventSink receives challenge lifecycle events, for auditing.
+type EventSink interface {
+	Send(ctx context.Context, event ChallengeEvent) error
+}
+
+// emit sends an event about ch to the configured sink in the background.
+// operation and err are only set for EventFailed.
+func (c *Solver) emit(eventType string, ch *v1alpha1.ChallengeRequest, operation string, err error) {
+	if c.opts.EventSink == nil {
+		return
+	}
+	event := ChallengeEvent{
+		Type: eventType,
+		Time: time.Now().UTC(),
+		Data: ChallengeEventData{
+			UID:       string(ch.UID),
+			Domain:    ch.DNSName,
+			FQDN:      ch.ResolvedFQDN,
+			Zone:      ch.ResolvedZone,
+			Namespace: ch.ResourceNamespace,
+			Operation: operation,
+		},
+	}
+	if err != nil {
+		event.Data.Error = err.Error()
+	}
+	go func() {
+		if err := c.opts.EventSink.Send(c.context(), event); err != nil {
+			challengeLogger(ch).Error("failed to send event", "type", eventType, "error", err)
+		}
+	}()
+}
+
+// CloudEventsSink sends events to an HTTP endpoint as CloudEvents 1.0 in
+// structured JSON mode. Kafka topics can be reached through an HTTP bridge
+// such as a Knative KafkaSink.
+type CloudEventsSink struct {
+	URL string
+	// Source is the CloudEvents source attribute, e.g. the webhook's group
+	// name.
+	Source string
+}
+
+type cloudEvent struct {
+	SpecVersion     string             `json:"specversion"`
+	ID              string             `json:"id"`
+	Source          string             `json:"source"`
+	Type            string             `json:"type"`
+	Subject         string             `json:"subject,omitempty"`
+	Time            time.Time          `json:"time"`
+	DataContentType string             `json:"datacontenttype"`
+	Data            ChallengeEventData `json:"data"`
+}
+
+func (s CloudEventsSink) Send(ctx context.Context, event ChallengeEvent) error {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return fmt.Errorf("failed to generate event ID: %w", err)
+	}
+	return postJSONAs(ctx, s.URL, "application/cloudevents+json", cloudEvent{
+		SpecVersion:     "1.0",
+		ID:              hex.EncodeToString(id),
+		Source:          s.Source,
+		Type:            event.Type,
+		Subject:         event.Data.FQDN,
+		Time:            event.Time,
+		DataContentType: "application/json",
+		Data:            event.Data,
+	})
+}
@@ -0,0 +1,79 @@
+package solver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+type chanSink chan ChallengeEvent
+
+func (c chanSink) Send(_ context.Context, event ChallengeEvent) error {
+	c <- event
+	return nil
+}
+
+func TestSolver_Events(t *testing.T) {
+	events := make(chanSink, 10)
+	p := &recordingProvider{records: map[string]string{}}
+	s := New(Options{
+		EventSink: events,
+		Providers: map[string]ProviderFactory{
+			"recording": func([]byte, SecretFunc) (Provider, error) { return p, nil },
+		},
+	})
+	ch := &v1alpha1.ChallengeRequest{
+		DNSName:      "example.com",
+		Key:          "token",
+		ResolvedFQDN: "_acme-challenge.example.com.",
+		ResolvedZone: "example.com.",
+		Config:       &apiextensionsv1.JSON{Raw: []byte(`{"provider":"recording"}`)},
+	}
+
+	require.NoError(t, s.Present(ch))
+	require.NoError(t, s.CleanUp(ch))
+	ch.Config.Raw = []byte(`{"provider":"unknown"}`)
+	require.Error(t, s.Present(ch))
+
+	var types []string
+	for len(types) < 3 {
+		select {
+		case event := <-events:
+			assert.Equal(t, "_acme-challenge.example.com.", event.Data.FQDN)
+			types = append(types, event.Type)
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "missing events", "got %v", types)
+		}
+	}
+	assert.ElementsMatch(t, []string{EventPresented, EventCleaned, EventFailed}, types)
+}
+
+func TestCloudEventsSink(t *testing.T) {
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/cloudevents+json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+	}))
+	defer srv.Close()
+
+	sink := CloudEventsSink{URL: srv.URL, Source: "acme.example.com"}
+	require.NoError(t, sink.Send(context.Background(), ChallengeEvent{
+		Type: EventCleaned,
+		Time: time.Now(),
+		Data: ChallengeEventData{Domain: "example.com", FQDN: "_acme-challenge.example.com."},
+	}))
+
+	assert.Equal(t, "1.0", got["specversion"])
+	assert.Equal(t, EventCleaned, got["type"])
+	assert.Equal(t, "acme.example.com", got["source"])
+	assert.NotEmpty(t, got["id"])
+	assert.Equal(t, "example.com", got["data"].(map[string]interface{})["domain"])
+}
@@ -0,0 +1,324 @@
+package solver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/cert-manager/webhook-example/pkg/bunny"
+)
+
+// externalDNSMediaType is the content type of the external-dns webhook
+// provider API.
+const externalDNSMediaType = "application/external.dns.webhook+json;version=1"
+
+// externalDNSMaxBody bounds the request bodies read from external-dns.
+const externalDNSMaxBody = 1 << 20
+
+// externalDNSTTL is the TTL of records external-dns creates without one.
+const externalDNSTTL = 300
+
+// externalDNSRecordTypes maps the record types served to external-dns to
+// their Bunny numbers.
+var externalDNSRecordTypes = map[string]int{
+	"A":     bunny.RecordTypeA,
+	"AAAA":  bunny.RecordTypeAAAA,
+	"CNAME": bunny.RecordTypeCNAME,
+	"TXT":   bunny.RecordTypeTXT,
+}
+
+// Endpoint is a DNS name with its targets, as exchanged with external-dns.
+type Endpoint struct {
+	DNSName       string            `json:"dnsName"`
+	Targets       []string          `json:"targets"`
+	RecordType    string            `json:"recordType"`
+	SetIdentifier string            `json:"setIdentifier,omitempty"`
+	RecordTTL     int64             `json:"recordTTL,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"`
+}
+
+// Changes are the changes external-dns asks for.
+type Changes struct {
+	Create    []*Endpoint `json:"Create"`
+	UpdateOld []*Endpoint `json:"UpdateOld"`
+	UpdateNew []*Endpoint `json:"UpdateNew"`
+	Delete    []*Endpoint `json:"Delete"`
+}
+
+// DomainFilter tells external-dns which domains the provider manages.
+type DomainFilter struct {
+	Include []string `json:"include,omitempty"`
+}
+
+// NewExternalDNSHandler returns a handler serving the external-dns webhook
+// provider API for the zones visible to the webhook-wide API key of s, or
+// only those in domains if it isn't empty:
+//
+//	GET  /                 negotiation, returns the domain filter
+//	GET  /records          current A, AAAA, CNAME and TXT records
+//	POST /records          apply changes
+//	POST /adjustendpoints  returns the endpoints unchanged
+//	GET  /healthz          liveness
+//
+// _acme-challenge records belong to the solver and are neither listed nor
+// changed. The handler does no authentication of its own, so it must only
+// be reachable from external-dns.
+func NewExternalDNSHandler(s *Solver, domains []string) http.Handler {
+	h := &externalDNSHandler{solver: s}
+	for _, d := range domains {
+		h.domains = append(h.domains, strings.ToLower(strings.TrimSuffix(d, ".")))
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		writeExternalDNS(w, http.StatusOK, DomainFilter{Include: h.domains})
+	})
+	mux.HandleFunc("GET /records", func(w http.ResponseWriter, r *http.Request) {
+		endpoints, err := h.records(r.Context())
+		if err != nil {
+			http.Error(w, redact(err.Error()), http.StatusInternalServerError)
+			return
+		}
+		writeExternalDNS(w, http.StatusOK, endpoints)
+	})
+	mux.HandleFunc("POST /records", func(w http.ResponseWriter, r *http.Request) {
+		var changes Changes
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, externalDNSMaxBody)).Decode(&changes); err != nil {
+			http.Error(w, fmt.Sprintf("invalid changes: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := h.apply(r.Context(), changes); err != nil {
+			http.Error(w, redact(err.Error()), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /adjustendpoints", func(w http.ResponseWriter, r *http.Request) {
+		var endpoints []*Endpoint
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, externalDNSMaxBody)).Decode(&endpoints); err != nil {
+			http.Error(w, fmt.Sprintf("invalid endpoints: %v", err), http.StatusBadRequest)
+			return
+		}
+		writeExternalDNS(w, http.StatusOK, endpoints)
+	})
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+	return mux
+}
+
+type externalDNSHandler struct {
+	solver  *Solver
+	domains []string
+}
+
+// managedZone is a zone served to external-dns, with its records.
+type managedZone struct {
+	id      int64
+	domain  string
+	records []bunny.Record
+}
+
+// zones returns the zones served to external-dns, with their records.
+func (h *externalDNSHandler) zones(ctx context.Context, cfg bunnyNetDNSConfig) ([]managedZone, error) {
+	items, err := listZones(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	var zones []managedZone
+	for _, item := range items {
+		domain := strings.ToLower(item.Domain)
+		if !h.served(domain) {
+			continue
+		}
+		records, err := cfg.client().ListRecords(ctx, int64(item.ID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list records of zone %s: %w", domain, err)
+		}
+		zones = append(zones, managedZone{id: int64(item.ID), domain: domain, records: records})
+	}
+	return zones, nil
+}
+
+// served reports whether domain is in the domain filter.
+func (h *externalDNSHandler) served(domain string) bool {
+	if len(h.domains) == 0 {
+		return true
+	}
+	for _, d := range h.domains {
+		if domain == d || strings.HasSuffix(domain, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *externalDNSHandler) records(ctx context.Context) ([]*Endpoint, error) {
+	zones, err := h.zones(ctx, h.solver.ambientConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := []*Endpoint{}
+	byKey := map[string]*Endpoint{}
+	for _, zone := range zones {
+		for _, rec := range zone.records {
+			typ, ok := externalDNSRecordType(rec.Type)
+			if !ok || rec.Disabled || isChallengeName(rec.Name) {
+				continue
+			}
+			name := zone.domain
+			if rec.Name != "" {
+				name = strings.ToLower(rec.Name) + "." + zone.domain
+			}
+			key := name + "/" + typ
+			ep, ok := byKey[key]
+			if !ok {
+				ep = &Endpoint{DNSName: name, RecordType: typ, RecordTTL: int64(rec.Ttl)}
+				byKey[key] = ep
+				endpoints = append(endpoints, ep)
+			}
+			ep.Targets = append(ep.Targets, rec.Value)
+		}
+	}
+	for _, ep := range endpoints {
+		sort.Strings(ep.Targets)
+	}
+	return endpoints, nil
+}
+
+// apply makes the changes. Updates are applied against the current records,
+// so only the targets and TTL that changed are touched.
+func (h *externalDNSHandler) apply(ctx context.Context, changes Changes) error {
+	cfg := h.solver.ambientConfig()
+	zones, err := h.zones(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	client := cfg.client()
+
+	var errs []error
+	for _, ep := range changes.Delete {
+		errs = append(errs, h.set(ctx, client, zones, ep, nil))
+	}
+	for _, ep := range changes.UpdateNew {
+		errs = append(errs, h.set(ctx, client, zones, ep, ep.Targets))
+	}
+	for _, ep := range changes.Create {
+		errs = append(errs, h.set(ctx, client, zones, ep, append(existingTargets(zones, ep), ep.Targets...)))
+	}
+	return errors.Join(errs...)
+}
+
+// set makes targets the records of ep's name and type, removing all of them
+// if targets is empty.
+func (h *externalDNSHandler) set(ctx context.Context, client bunny.Client, zones []managedZone, ep *Endpoint, targets []string) error {
+	typ, ok := externalDNSRecordTypes[ep.RecordType]
+	if !ok {
+		return fmt.Errorf("record type %s of %s is not supported", ep.RecordType, ep.DNSName)
+	}
+	zone, name, ok := zoneOf(zones, ep.DNSName)
+	if !ok {
+		return fmt.Errorf("no zone for %s", ep.DNSName)
+	}
+	if isChallengeName(name) {
+		return fmt.Errorf("%s is managed by the ACME solver", ep.DNSName)
+	}
+	ttl := int(ep.RecordTTL)
+	if ttl <= 0 {
+		ttl = externalDNSTTL
+	}
+
+	wanted := map[string]bool{}
+	for _, t := range targets {
+		if typ == bunny.RecordTypeCNAME {
+			t = strings.TrimSuffix(t, ".")
+		}
+		wanted[t] = true
+	}
+	for _, rec := range zone.records {
+		if rec.Type != typ || !strings.EqualFold(rec.Name, name) {
+			continue
+		}
+		if !wanted[rec.Value] {
+			if err := client.DeleteRecord(ctx, zone.id, rec.ID); err != nil && !bunny.IsNotFound(err) {
+				return fmt.Errorf("failed to delete %s record %s: %w", ep.RecordType, ep.DNSName, err)
+			}
+			continue
+		}
+		delete(wanted, rec.Value)
+		if len(targets) > 0 && rec.Ttl != ttl {
+			rec.Ttl = ttl
+			if err := client.UpdateRecord(ctx, zone.id, rec); err != nil {
+				return fmt.Errorf("failed to update %s record %s: %w", ep.RecordType, ep.DNSName, err)
+			}
+		}
+	}
+	for value := range wanted {
+		rec := bunny.Record{Type: typ, Name: name, Value: value, Ttl: ttl}
+		if _, err := client.CreateRecord(ctx, zone.id, rec); err != nil && !errors.Is(err, bunny.ErrMalformedResponse) {
+			return fmt.Errorf("failed to create %s record %s: %w", ep.RecordType, ep.DNSName, err)
+		}
+	}
+	return nil
+}
+
+// existingTargets returns the current targets of ep's name and type.
+func existingTargets(zones []managedZone, ep *Endpoint) []string {
+	zone, name, ok := zoneOf(zones, ep.DNSName)
+	if !ok {
+		return nil
+	}
+	var targets []string
+	for _, rec := range zone.records {
+		if rec.Type == externalDNSRecordTypes[ep.RecordType] && strings.EqualFold(rec.Name, name) {
+			targets = append(targets, rec.Value)
+		}
+	}
+	return targets
+}
+
+// zoneOf returns the most specific zone containing dnsName and the record
+// name relative to it.
+func zoneOf(zones []managedZone, dnsName string) (managedZone, string, bool) {
+	dnsName = strings.ToLower(strings.TrimSuffix(dnsName, "."))
+	var best managedZone
+	found := false
+	for _, zone := range zones {
+		if dnsName != zone.domain && !strings.HasSuffix(dnsName, "."+zone.domain) {
+			continue
+		}
+		if !found || len(zone.domain) > len(best.domain) {
+			best, found = zone, true
+		}
+	}
+	if !found {
+		return managedZone{}, "", false
+	}
+	return best, strings.TrimSuffix(strings.TrimSuffix(dnsName, best.domain), "."), true
+}
+
+func externalDNSRecordType(typ int) (string, bool) {
+	for name, t := range externalDNSRecordTypes {
+		if t == typ {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// isChallengeName reports whether a record name relative to its zone is an
+// ACME challenge name.
+func isChallengeName(name string) bool {
+	return strings.HasPrefix(strings.ToLower(name), "_acme-challenge")
+}
+
+func writeExternalDNS(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", externalDNSMediaType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
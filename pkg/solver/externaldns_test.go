@@ -0,0 +1,76 @@
+package solver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cert-manager/webhook-example/pkg/bunny"
+	"github.com/cert-manager/webhook-example/pkg/bunny/bunnytest"
+)
+
+func TestExternalDNSHandler(t *testing.T) {
+	api := bunnytest.NewServer()
+	defer api.Close()
+	require.NoError(t, SetAPIBase(api.URL))
+	defer SetAPIBase(DefaultAPIBase)
+	zone := api.AddZone("example.com")
+	api.AddZone("example.org")
+	api.AddRecord(zone, bunny.Record{Type: bunny.RecordTypeA, Name: "www", Value: "192.0.2.1", Ttl: 300})
+	api.AddRecord(zone, bunny.Record{Type: bunny.RecordTypeA, Name: "www", Value: "192.0.2.2", Ttl: 300})
+	api.AddRecord(zone, bunny.Record{Type: bunny.RecordTypeTXT, Name: "_acme-challenge", Value: "token", Ttl: 10})
+
+	h := NewExternalDNSHandler(New(Options{APIKey: "secret"}), []string{"example.com."})
+	do := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&buf).Encode(body))
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, path, &buf))
+		return w
+	}
+	records := func() []*Endpoint {
+		w := do(http.MethodGet, "/records", nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var endpoints []*Endpoint
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&endpoints))
+		return endpoints
+	}
+
+	w := do(http.MethodGet, "/", nil)
+	assert.Equal(t, externalDNSMediaType, w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"include":["example.com"]}`, w.Body.String())
+
+	assert.Equal(t, []*Endpoint{{DNSName: "www.example.com", RecordType: "A", Targets: []string{"192.0.2.1", "192.0.2.2"}, RecordTTL: 300}}, records())
+
+	w = do(http.MethodPost, "/records", Changes{
+		Create:    []*Endpoint{{DNSName: "example.com", RecordType: "TXT", Targets: []string{"heritage=external-dns"}}},
+		UpdateOld: []*Endpoint{{DNSName: "www.example.com", RecordType: "A", Targets: []string{"192.0.2.1", "192.0.2.2"}, RecordTTL: 300}},
+		UpdateNew: []*Endpoint{{DNSName: "www.example.com", RecordType: "A", Targets: []string{"192.0.2.2", "192.0.2.3"}, RecordTTL: 60}},
+	})
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+	assert.ElementsMatch(t, []*Endpoint{
+		{DNSName: "www.example.com", RecordType: "A", Targets: []string{"192.0.2.2", "192.0.2.3"}, RecordTTL: 60},
+		{DNSName: "example.com", RecordType: "TXT", Targets: []string{"heritage=external-dns"}, RecordTTL: externalDNSTTL},
+	}, records())
+
+	w = do(http.MethodPost, "/records", Changes{Delete: []*Endpoint{{DNSName: "www.example.com", RecordType: "A", Targets: []string{"192.0.2.2", "192.0.2.3"}}}})
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+	assert.Len(t, records(), 1)
+
+	w = do(http.MethodPost, "/records", Changes{Delete: []*Endpoint{{DNSName: "_acme-challenge.example.com", RecordType: "TXT", Targets: []string{"token"}}}})
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "managed by the ACME solver")
+	assert.Len(t, api.Records(zone), 2, "the challenge record must be kept")
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/records", strings.NewReader(`{"Create":"`+strings.Repeat("x", externalDNSMaxBody)+`"}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code, "oversized bodies are rejected")
+}
@@ -0,0 +1,110 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/cert-manager/webhook-example/pkg/bunny"
+)
+
+// defaultFallbackAfterFailures is how many consecutive Bunny API failures
+// switch Present over to the fallback.
+const defaultFallbackAfterFailures = 3
+
+// Fallback solves challenges while the Bunny API is failing, so renewals
+// survive a provider outage. It must be able to publish TXT records that the
+// ACME server will see for the challenge FQDN, e.g. a secondary provider
+// hosting the same names or acme-dns.
+type Fallback interface {
+	Present(ctx context.Context, fqdn, value string) error
+	CleanUp(ctx context.Context, fqdn, value string) error
+}
+
+// isOutage reports whether err says the Bunny API is unavailable, rather
+// than that a request or its credentials were wrong: an open circuit
+// breaker, a 5xx or 429 response, or no response in time. Only these count
+// towards the fallback threshold, so a few misconfigured Issuers can't send
+// everybody's challenges to the fallback.
+func isOutage(err error) bool {
+	if errors.Is(err, ErrCircuitOpen) {
+		return true
+	}
+	var apiErr *bunny.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= http.StatusInternalServerError || apiErr.StatusCode == http.StatusTooManyRequests
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return !errors.Is(err, context.Canceled)
+	}
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+// bunnyFailed records a Bunny API outage and reports whether the fallback
+// should be used.
+func (c *Solver) bunnyFailed() bool {
+	failures := c.bunnyFailures.Add(1)
+	if c.opts.Fallback == nil {
+		return false
+	}
+	threshold := c.opts.FallbackAfterFailures
+	if threshold <= 0 {
+		threshold = defaultFallbackAfterFailures
+	}
+	return int(failures) >= threshold
+}
+
+func (c *Solver) bunnySucceeded() {
+	c.bunnyFailures.Store(0)
+}
+
+// presentFallback publishes the record through the fallback after the Bunny
+// API failed with bunnyErr.
+func (c *Solver) presentFallback(ctx context.Context, fqdn, value string, bunnyErr error) error {
+	logger(ctx).Warn("Bunny API keeps failing, presenting through the fallback", "record", fqdn, "error", bunnyErr)
+	if err := c.opts.Fallback.Present(ctx, fqdn, value); err != nil {
+		return fmt.Errorf("%w; fallback also failed: %v", bunnyErr, err)
+	}
+	c.fallbackRecords.Store(fallbackKey(fqdn, value), struct{}{})
+	// With a cleanup queue the record is also remembered in its ConfigMap,
+	// so it is removed from the fallback after a restart or by another
+	// replica.
+	if c.cleanups != nil {
+		if err := c.cleanups.addFallback(ctx, fqdn, value); err != nil {
+			logger(ctx).Warn("failed to persist fallback record, only this replica can clean it up", "record", fqdn, "error", err)
+		}
+	}
+	return nil
+}
+
+// cleanUpFallback removes a record published through the fallback. It
+// reports false if the record was not published by the fallback.
+func (c *Solver) cleanUpFallback(ctx context.Context, fqdn, value string) (bool, error) {
+	key := fallbackKey(fqdn, value)
+	if _, ok := c.fallbackRecords.Load(key); !ok {
+		if c.cleanups == nil {
+			return false, nil
+		}
+		persisted, err := c.cleanups.hasFallback(ctx, fqdn, value)
+		if err != nil || !persisted {
+			return false, err
+		}
+	}
+	if err := c.opts.Fallback.CleanUp(ctx, fqdn, value); err != nil {
+		return true, fmt.Errorf("failed to clean up fallback record: %w", err)
+	}
+	c.fallbackRecords.Delete(key)
+	if c.cleanups != nil {
+		if err := c.cleanups.removeFallback(ctx, fqdn, value); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+func fallbackKey(fqdn, value string) string {
+	return fqdn + "/" + value
+}
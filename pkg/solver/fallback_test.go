@@ -0,0 +1,159 @@
+package solver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/cert-manager/webhook-example/pkg/bunny"
+	"github.com/cert-manager/webhook-example/pkg/bunny/bunnytest"
+)
+
+type recordingFallback struct {
+	presented, cleaned []string
+}
+
+func (f *recordingFallback) Present(_ context.Context, fqdn, value string) error {
+	f.presented = append(f.presented, fqdn)
+	return nil
+}
+
+func (f *recordingFallback) CleanUp(_ context.Context, fqdn, value string) error {
+	f.cleaned = append(f.cleaned, fqdn)
+	return nil
+}
+
+func TestSolver_FallbackAfterFailures(t *testing.T) {
+	fallback := &recordingFallback{}
+	s := New(Options{Fallback: fallback, FallbackAfterFailures: 2})
+
+	assert.False(t, s.bunnyFailed())
+	assert.True(t, s.bunnyFailed())
+	s.bunnySucceeded()
+	assert.False(t, s.bunnyFailed())
+
+	require.NoError(t, s.presentFallback(context.Background(), "_acme-challenge.example.com.", "value", errors.New("bunny down")))
+	handled, err := s.cleanUpFallback(context.Background(), "_acme-challenge.example.com.", "value")
+	assert.True(t, handled)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"_acme-challenge.example.com."}, fallback.cleaned)
+
+	handled, _ = s.cleanUpFallback(context.Background(), "_acme-challenge.example.com.", "value")
+	assert.False(t, handled)
+}
+
+func TestSolver_FallbackRecordsPersisted(t *testing.T) {
+	ctx := context.Background()
+	cl := fake.NewSimpleClientset()
+	newSolver := func(fallback Fallback) *Solver {
+		s := New(Options{Fallback: fallback})
+		cleanups, err := newCleanupQueue(s, cl, "cert-manager", "bunny-cleanup")
+		require.NoError(t, err)
+		s.cleanups = cleanups
+		return s
+	}
+
+	require.NoError(t, newSolver(&recordingFallback{}).presentFallback(ctx, "_acme-challenge.example.com.", "value", errors.New("bunny down")))
+
+	// Another replica, or this one after a restart, cleans up the record.
+	fallback := &recordingFallback{}
+	s := newSolver(fallback)
+	handled, err := s.cleanUpFallback(ctx, "_acme-challenge.example.com.", "value")
+	assert.True(t, handled)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"_acme-challenge.example.com."}, fallback.cleaned)
+
+	handled, err = s.cleanUpFallback(ctx, "_acme-challenge.example.com.", "value")
+	assert.False(t, handled)
+	require.NoError(t, err)
+	cm, err := cl.CoreV1().ConfigMaps("cert-manager").Get(ctx, "bunny-cleanup", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, cm.Data)
+}
+
+func TestSolver_NoFallback(t *testing.T) {
+	s := New(Options{})
+	for i := 0; i < 5; i++ {
+		assert.False(t, s.bunnyFailed())
+	}
+}
+
+func TestIsOutage(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{fmt.Errorf("zone lookup: %w", ErrCircuitOpen), true},
+		{&bunny.APIError{StatusCode: http.StatusBadGateway}, true},
+		{&bunny.APIError{StatusCode: http.StatusTooManyRequests}, true},
+		{fmt.Errorf("failed to send request: %w", &url.Error{Op: "Get", URL: "https://api.bunny.net", Err: errors.New("connection refused")}), true},
+		{context.DeadlineExceeded, true},
+		{&bunny.APIError{StatusCode: http.StatusUnauthorized}, false},
+		{&bunny.APIError{StatusCode: http.StatusBadRequest}, false},
+		{fmt.Errorf("%w for example.com", errZoneNotFound), false},
+		{&configFieldError{Field: "apiKeySecretRef", Reason: "is required"}, false},
+		{&url.Error{Op: "Get", URL: "https://api.bunny.net", Err: context.Canceled}, false},
+	} {
+		assert.Equal(t, tc.want, isOutage(tc.err), "%v", tc.err)
+	}
+}
+
+func TestPresent_FallbackOnlyOnOutage(t *testing.T) {
+	api := bunnytest.NewServer()
+	defer api.Close()
+	api.RequireAPIKey("secret")
+	zone := api.AddZone("example.com")
+
+	fallback := &recordingFallback{}
+	s := New(Options{APIKey: "secret", APIBase: api.URL, Fallback: fallback, FallbackAfterFailures: 2, Retry: RetryPolicy{MaxAttempts: 1}})
+
+	// A broken Issuer doesn't switch anybody to the fallback.
+	wrongKey := New(Options{APIKey: "wrong", APIBase: api.URL, Fallback: fallback, FallbackAfterFailures: 2})
+	for i := 0; i < 3; i++ {
+		assert.Error(t, wrongKey.Present(fakeChallenge(api, "token")))
+	}
+	api.FailNext(3, http.StatusBadRequest, "dnszone.record.invalid")
+	for i := 0; i < 3; i++ {
+		assert.Error(t, s.Present(fakeChallenge(api, "token")))
+	}
+	assert.Empty(t, fallback.presented)
+
+	api.FailNext(2, http.StatusServiceUnavailable, "")
+	assert.Error(t, s.Present(fakeChallenge(api, "token")))
+	require.NoError(t, s.Present(fakeChallenge(api, "token")))
+	assert.Equal(t, []string{"_acme-challenge.example.com."}, fallback.presented)
+	assert.Empty(t, challengeValues(api.Records(zone)))
+}
+
+func TestACMEDNS_Present(t *testing.T) {
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/update", r.URL.Path)
+		assert.Equal(t, "user", r.Header.Get("X-Api-User"))
+		assert.Equal(t, "secret", r.Header.Get("X-Api-Key"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+	}))
+	defer srv.Close()
+
+	accounts := filepath.Join(t.TempDir(), "accounts.json")
+	require.NoError(t, os.WriteFile(accounts, []byte(`{"example.com": {"username": "user", "password": "secret", "subdomain": "abc"}}`), 0o600))
+
+	a, err := NewACMEDNS(srv.URL+"/", accounts)
+	require.NoError(t, err)
+	require.NoError(t, a.Present(context.Background(), "_acme-challenge.example.com.", "value"))
+	assert.Equal(t, map[string]string{"subdomain": "abc", "txt": "value"}, got)
+
+	assert.Error(t, a.Present(context.Background(), "_acme-challenge.other.com.", "value"))
+}
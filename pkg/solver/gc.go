@@ -0,0 +1,103 @@
+package solver
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const defaultOrphanGCInterval = 15 * time.Minute
+
+// orphanCollector deletes challenge TXT records left behind when the
+// webhook died between Present and CleanUp. Only records carrying the
+// solver's marker comment are collected, and never while a Challenge with
+// their value exists in the cluster. The Bunny API doesn't report when a
+// record was created, so a record's age is counted from the first scan that
+// saw it.
+type orphanCollector struct {
+	solver   *Solver
+	maxAge   time.Duration
+	interval time.Duration
+	now      func() time.Time
+
+	// firstSeen is only used by the job's goroutine.
+	firstSeen map[orphanKey]time.Time
+}
+
+type orphanKey struct {
+	zoneID, recordID int
+}
+
+func newOrphanCollector(solver *Solver, maxAge, interval time.Duration) *orphanCollector {
+	if interval <= 0 {
+		interval = defaultOrphanGCInterval
+	}
+	return &orphanCollector{
+		solver:    solver,
+		maxAge:    maxAge,
+		interval:  interval,
+		now:       time.Now,
+		firstSeen: make(map[orphanKey]time.Time),
+	}
+}
+
+// job runs the collector. Replicas would race each other deleting the same
+// records, so it runs on the leader only.
+func (g *orphanCollector) job() BackgroundJob {
+	return BackgroundJob{
+		Name: "orphan-record-gc",
+		Run: func(ctx context.Context) {
+			wait.UntilWithContext(ctx, func(ctx context.Context) {
+				if err := g.collect(ctx); err != nil {
+					slog.Error("failed to collect orphaned challenge records", "error", err)
+				}
+			}, g.interval)
+		},
+	}
+}
+
+// collect scans every zone of the account once, deleting the challenge
+// records seen for longer than maxAge.
+func (g *orphanCollector) collect(ctx context.Context) error {
+	cfg := g.solver.ambientConfig()
+	zones, err := listZones(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	now := g.now()
+	seen := make(map[orphanKey]time.Time)
+	for _, zone := range zones {
+		for _, rec := range zone.Records {
+			if rec.Type != recordType || !ownedRecord(rec) {
+				continue
+			}
+			fqdn := withTrailingDot(zone.Domain)
+			if rec.Name != "" {
+				fqdn = rec.Name + "." + fqdn
+			}
+			if g.solver.challengeInProgress(fqdn, rec.Value) {
+				continue
+			}
+			key := orphanKey{zoneID: zone.ID, recordID: rec.ID}
+			first, ok := g.firstSeen[key]
+			if !ok {
+				first = now
+			}
+			if now.Sub(first) < g.maxAge {
+				seen[key] = first
+				continue
+			}
+			slog.Info("Deleting orphaned TXT record", "fqdn", fqdn, "age", now.Sub(first).Round(time.Second))
+			if err := deleteTXTRecordIn(ctx, cfg, zone, withTrailingDot(zone.Domain), fqdn, rec.Value); err != nil {
+				slog.Error("failed to delete orphaned TXT record", "fqdn", fqdn, "error", err)
+				seen[key] = first
+			}
+		}
+	}
+	// Records gone since the last scan are forgotten.
+	g.firstSeen = seen
+	return nil
+}
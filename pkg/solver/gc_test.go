@@ -0,0 +1,59 @@
+package solver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	cmacme "github.com/cert-manager/cert-manager/pkg/apis/acme/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestOrphanCollector(t *testing.T) {
+	var mu sync.Mutex
+	var deleted []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodGet:
+			fmt.Fprint(w, `{"Items":[{"Id":5,"Domain":"example.com","Records":[`+
+				`{"Id":1,"Type":3,"Name":"_acme-challenge","Value":"orphan","Comment":"cert-manager-webhook-bunny"},`+
+				`{"Id":2,"Type":3,"Name":"_acme-challenge.www","Value":"active","Comment":"cert-manager-webhook-bunny"},`+
+				`{"Id":3,"Type":3,"Name":"spf","Value":"v=spf1 -all"},`+
+				`{"Id":4,"Type":3,"Name":"_acme-challenge.api","Value":"other-replica","Comment":"cert-manager-webhook-bunny"},`+
+				`{"Id":5,"Type":3,"Name":"_acme-challenge.mail","Value":"foreign"}]}]}`)
+		case http.MethodDelete:
+			deleted = append(deleted, r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	s := New(Options{APIBase: srv.URL})
+	s.activeRecords.Store(recordKey("_acme-challenge.www.example.com.", "active"), struct{}{})
+	s.annotator = newTestAnnotator(t, &cmacme.Challenge{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "ch"},
+		Spec:       cmacme.ChallengeSpec{DNSName: "api.example.com", Key: "other-replica"},
+	})
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	g := newOrphanCollector(s, time.Hour, 0)
+	g.now = func() time.Time { return now }
+
+	require.NoError(t, g.collect(context.Background()))
+	assert.Empty(t, deleted, "records are only deleted after maxAge")
+
+	now = now.Add(59 * time.Minute)
+	require.NoError(t, g.collect(context.Background()))
+	assert.Empty(t, deleted)
+
+	now = now.Add(time.Minute)
+	require.NoError(t, g.collect(context.Background()))
+	assert.Equal(t, []string{"/dnszone/5/records/1"}, deleted, "only the orphaned record of the solver is deleted")
+}
@@ -0,0 +1,236 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/cert-manager/cert-manager/pkg/issuer/acme/dns/util"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// GRPCServiceName is the full name of the gRPC service in grpc.proto.
+const GRPCServiceName = "bunny.solver.v1.Solver"
+
+// GRPCChallengeRequest is the ChallengeRequest message of grpc.proto.
+type GRPCChallengeRequest struct {
+	UID               string
+	DNSName           string
+	Key               string
+	ResolvedFQDN      string
+	ResolvedZone      string
+	ResourceNamespace string
+	Config            []byte
+}
+
+// GRPCChallengeResponse is the empty ChallengeResponse message of grpc.proto.
+type GRPCChallengeResponse struct{}
+
+// grpcZoneLookupTimeout bounds resolving the zone of requests that don't
+// name it.
+const grpcZoneLookupTimeout = 30 * time.Second
+
+// NewGRPCServer returns a gRPC server exposing Present and CleanUp of s as
+// described by grpc.proto. Requests never use the webhook-wide API key: the
+// config must reference a Secret in the request's resource namespace, which
+// must be one of allowedNamespaces. The server doesn't authenticate clients
+// itself; opts must, e.g. with mutual TLS credentials.
+func NewGRPCServer(s *Solver, allowedNamespaces []string, opts ...grpc.ServerOption) *grpc.Server {
+	srv := grpc.NewServer(append(opts, grpc.ForceServerCodec(GRPCCodec{}))...)
+	srv.RegisterService(&grpcServiceDesc, &grpcSolver{solver: s, allowedNamespaces: allowedNamespaces})
+	return srv
+}
+
+type grpcSolver struct {
+	solver            *Solver
+	allowedNamespaces []string
+}
+
+// namespaceAllowed reports whether requests may read Secrets from
+// namespace.
+func (g *grpcSolver) namespaceAllowed(namespace string) bool {
+	for _, ns := range g.allowedNamespaces {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+var grpcServiceDesc = grpc.ServiceDesc{
+	ServiceName: GRPCServiceName,
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Present", Handler: grpcHandler("Present", (*Solver).Present)},
+		{MethodName: "CleanUp", Handler: grpcHandler("CleanUp", (*Solver).CleanUp)},
+	},
+	Metadata: "grpc.proto",
+}
+
+func grpcHandler(method string, call func(*Solver, *v1alpha1.ChallengeRequest) error) func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		req := new(GRPCChallengeRequest)
+		if err := dec(req); err != nil {
+			return nil, err
+		}
+		handle := func(ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.(*grpcSolver).handle(ctx, req.(*GRPCChallengeRequest), call)
+		}
+		if interceptor == nil {
+			return handle(ctx, req)
+		}
+		return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + GRPCServiceName + "/" + method}, handle)
+	}
+}
+
+func (g *grpcSolver) handle(ctx context.Context, req *GRPCChallengeRequest, call func(*Solver, *v1alpha1.ChallengeRequest) error) (*GRPCChallengeResponse, error) {
+	if !g.solver.Initialized() {
+		return nil, status.Error(codes.Unavailable, "solver is not initialized")
+	}
+	if req.DNSName == "" || req.Key == "" {
+		return nil, status.Error(codes.InvalidArgument, "dns_name and key are required")
+	}
+	if !g.namespaceAllowed(req.ResourceNamespace) {
+		return nil, status.Errorf(codes.PermissionDenied, "resource namespace %q is not allowed", req.ResourceNamespace)
+	}
+
+	ch, err := req.challengeRequest(ctx)
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err := call(g.solver, ch); err != nil {
+		var fieldErr *configFieldError
+		if errors.As(err, &fieldErr) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, status.Error(codes.Unknown, err.Error())
+	}
+	return &GRPCChallengeResponse{}, nil
+}
+
+// challengeRequest converts req into the request cert-manager would have
+// sent, resolving the zone the same way cert-manager does when unset.
+func (req *GRPCChallengeRequest) challengeRequest(ctx context.Context) (*v1alpha1.ChallengeRequest, error) {
+	fqdn := req.ResolvedFQDN
+	if fqdn == "" {
+		fqdn = "_acme-challenge." + strings.TrimSuffix(strings.TrimPrefix(req.DNSName, "*."), ".") + "."
+	}
+	zone := req.ResolvedZone
+	if zone == "" {
+		ctx, cancel := context.WithTimeout(ctx, grpcZoneLookupTimeout)
+		defer cancel()
+		var err error
+		if zone, err = util.FindZoneByFqdn(ctx, fqdn, util.RecursiveNameservers); err != nil {
+			return nil, fmt.Errorf("failed to resolve zone for %s: %w", fqdn, err)
+		}
+	}
+
+	ch := &v1alpha1.ChallengeRequest{
+		UID:               types.UID(req.UID),
+		Type:              "dns-01",
+		DNSName:           req.DNSName,
+		Key:               req.Key,
+		ResolvedFQDN:      withTrailingDot(fqdn),
+		ResolvedZone:      withTrailingDot(zone),
+		ResourceNamespace: req.ResourceNamespace,
+	}
+	if len(req.Config) > 0 {
+		ch.Config = &apiextensionsv1.JSON{Raw: req.Config}
+	}
+	return ch, nil
+}
+
+// GRPCCodec encodes the messages of grpc.proto in the protobuf wire format,
+// so clients generated from it interoperate with NewGRPCServer.
+type GRPCCodec struct{}
+
+func (GRPCCodec) Name() string {
+	return "proto"
+}
+
+func (GRPCCodec) Marshal(v interface{}) ([]byte, error) {
+	switch m := v.(type) {
+	case *GRPCChallengeRequest:
+		var b []byte
+		for _, f := range []struct {
+			num protowire.Number
+			val string
+		}{
+			{1, m.UID}, {2, m.DNSName}, {3, m.Key}, {4, m.ResolvedFQDN},
+			{5, m.ResolvedZone}, {6, m.ResourceNamespace}, {7, string(m.Config)},
+		} {
+			if f.val != "" {
+				b = protowire.AppendTag(b, f.num, protowire.BytesType)
+				b = protowire.AppendString(b, f.val)
+			}
+		}
+		return b, nil
+	case *GRPCChallengeResponse:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("cannot marshal %T", v)
+	}
+}
+
+func (GRPCCodec) Unmarshal(data []byte, v interface{}) error {
+	switch m := v.(type) {
+	case *GRPCChallengeRequest:
+		return unmarshalFields(data, func(num protowire.Number, val []byte) {
+			switch num {
+			case 1:
+				m.UID = string(val)
+			case 2:
+				m.DNSName = string(val)
+			case 3:
+				m.Key = string(val)
+			case 4:
+				m.ResolvedFQDN = string(val)
+			case 5:
+				m.ResolvedZone = string(val)
+			case 6:
+				m.ResourceNamespace = string(val)
+			case 7:
+				m.Config = append([]byte(nil), val...)
+			}
+		})
+	case *GRPCChallengeResponse:
+		return unmarshalFields(data, func(protowire.Number, []byte) {})
+	default:
+		return fmt.Errorf("cannot unmarshal into %T", v)
+	}
+}
+
+// unmarshalFields calls set for every length-delimited field in data and
+// skips fields of other types, as unknown fields.
+func unmarshalFields(data []byte, set func(protowire.Number, []byte)) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+		if typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			data = data[n:]
+			continue
+		}
+		val, n := protowire.ConsumeBytes(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		set(num, val)
+		data = data[n:]
+	}
+	return nil
+}
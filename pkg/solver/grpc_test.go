@@ -0,0 +1,172 @@
+package solver
+
+import (
+	"context"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestGRPCCodec_RoundTrip(t *testing.T) {
+	in := &GRPCChallengeRequest{
+		UID:          "uid",
+		DNSName:      "example.com",
+		Key:          "token",
+		ResolvedZone: "example.com.",
+		Config:       []byte(`{"apiKeySecretRef":{"name":"bunny","key":"api-key"}}`),
+	}
+	data, err := GRPCCodec{}.Marshal(in)
+	require.NoError(t, err)
+
+	out := new(GRPCChallengeRequest)
+	require.NoError(t, GRPCCodec{}.Unmarshal(data, out))
+	assert.Equal(t, in, out)
+}
+
+// grpcProtoFile builds the descriptor of grpc.proto from the file itself,
+// so the codec is checked against the published schema rather than a copy.
+// It only understands the constructs grpc.proto uses.
+func grpcProtoFile(t *testing.T) protoreflect.FileDescriptor {
+	src, err := os.ReadFile("grpc.proto")
+	require.NoError(t, err)
+
+	pkg := regexp.MustCompile(`(?m)^package ([\w.]+);`).FindSubmatch(src)
+	require.NotNil(t, pkg, "package")
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("grpc.proto"),
+		Package: proto.String(string(pkg[1])),
+		Syntax:  proto.String("proto3"),
+	}
+	types := map[string]descriptorpb.FieldDescriptorProto_Type{
+		"string": descriptorpb.FieldDescriptorProto_TYPE_STRING,
+		"bytes":  descriptorpb.FieldDescriptorProto_TYPE_BYTES,
+	}
+	field := regexp.MustCompile(`(?m)^\s*(\w+) (\w+) = (\d+);`)
+	for _, m := range regexp.MustCompile(`(?s)message (\w+) \{(.*?)\}`).FindAllSubmatch(src, -1) {
+		msg := &descriptorpb.DescriptorProto{Name: proto.String(string(m[1]))}
+		for _, f := range field.FindAllSubmatch(m[2], -1) {
+			typ, ok := types[string(f[1])]
+			require.True(t, ok, "unsupported field type %s", f[1])
+			num, err := strconv.Atoi(string(f[3]))
+			require.NoError(t, err)
+			msg.Field = append(msg.Field, &descriptorpb.FieldDescriptorProto{
+				Name:   proto.String(string(f[2])),
+				Number: proto.Int32(int32(num)),
+				Type:   typ.Enum(),
+				Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			})
+		}
+		file.MessageType = append(file.MessageType, msg)
+	}
+	fd, err := protodesc.NewFile(file, nil)
+	require.NoError(t, err)
+	return fd
+}
+
+func TestGRPCCodec_Schema(t *testing.T) {
+	fd := grpcProtoFile(t)
+	desc := fd.Messages().ByName("ChallengeRequest")
+	require.NotNil(t, desc)
+	in := &GRPCChallengeRequest{
+		UID:               "uid",
+		DNSName:           "example.com",
+		Key:               "token",
+		ResolvedFQDN:      "_acme-challenge.example.com.",
+		ResolvedZone:      "example.com.",
+		ResourceNamespace: "default",
+		Config:            []byte(`{"apiKeySecretRef":{"name":"bunny","key":"api-key"}}`),
+	}
+	values := map[protoreflect.Name]protoreflect.Value{
+		"uid":                protoreflect.ValueOfString(in.UID),
+		"dns_name":           protoreflect.ValueOfString(in.DNSName),
+		"key":                protoreflect.ValueOfString(in.Key),
+		"resolved_fqdn":      protoreflect.ValueOfString(in.ResolvedFQDN),
+		"resolved_zone":      protoreflect.ValueOfString(in.ResolvedZone),
+		"resource_namespace": protoreflect.ValueOfString(in.ResourceNamespace),
+		"config":             protoreflect.ValueOfBytes(in.Config),
+	}
+	require.Equal(t, len(values), desc.Fields().Len(), "fields of grpc.proto")
+
+	// A message encoded from the schema decodes with the codec...
+	msg := dynamicpb.NewMessage(desc)
+	for i := 0; i < desc.Fields().Len(); i++ {
+		f := desc.Fields().Get(i)
+		v, ok := values[f.Name()]
+		require.True(t, ok, "field %s of grpc.proto is not covered by the codec", f.Name())
+		msg.Set(f, v)
+	}
+	data, err := proto.Marshal(msg)
+	require.NoError(t, err)
+	out := new(GRPCChallengeRequest)
+	require.NoError(t, GRPCCodec{}.Unmarshal(data, out))
+	assert.Equal(t, in, out)
+
+	// ...and the codec's encoding decodes with the schema.
+	data, err = GRPCCodec{}.Marshal(in)
+	require.NoError(t, err)
+	decoded := dynamicpb.NewMessage(desc)
+	require.NoError(t, proto.Unmarshal(data, decoded))
+	assert.True(t, proto.Equal(msg, decoded), "got %v", decoded)
+
+	resp := fd.Messages().ByName("ChallengeResponse")
+	require.NotNil(t, resp)
+	data, err = GRPCCodec{}.Marshal(&GRPCChallengeResponse{})
+	require.NoError(t, err)
+	require.NoError(t, proto.Unmarshal(data, dynamicpb.NewMessage(resp)))
+}
+
+func TestGRPCServer(t *testing.T) {
+	s := New(Options{})
+	lis := bufconn.Listen(1 << 20)
+	srv := NewGRPCServer(s, []string{"team-a"})
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(GRPCCodec{})),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	req := &GRPCChallengeRequest{
+		DNSName:           "example.com",
+		Key:               "token",
+		ResolvedFQDN:      "_acme-challenge.example.com.",
+		ResolvedZone:      "example.com.",
+		ResourceNamespace: "team-a",
+	}
+	present := func(req *GRPCChallengeRequest) codes.Code {
+		err := conn.Invoke(context.Background(), "/"+GRPCServiceName+"/Present", req, new(GRPCChallengeResponse))
+		return status.Code(err)
+	}
+
+	assert.Equal(t, codes.Unavailable, present(req))
+
+	s.initialized.Store(true)
+	assert.Equal(t, codes.InvalidArgument, present(&GRPCChallengeRequest{DNSName: "example.com"}))
+	// Without a Secret reference the webhook-wide key must not be used.
+	assert.Equal(t, codes.InvalidArgument, present(req))
+
+	other := *req
+	other.ResourceNamespace = "kube-system"
+	assert.Equal(t, codes.PermissionDenied, present(&other))
+	other.ResourceNamespace = ""
+	assert.Equal(t, codes.PermissionDenied, present(&other))
+}
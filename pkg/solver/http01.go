@@ -0,0 +1,209 @@
+package solver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	cmacme "github.com/cert-manager/cert-manager/pkg/apis/acme/v1"
+	cmclient "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned"
+	cminformers "github.com/cert-manager/cert-manager/pkg/client/informers/externalversions"
+	cmacmelisters "github.com/cert-manager/cert-manager/pkg/client/listers/acme/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/cert-manager/webhook-example/pkg/bunny"
+	"github.com/cert-manager/webhook-example/pkg/bunnylego"
+)
+
+// http01Solver solves the HTTP01 challenges of Issuers that select it by
+// uploading the key authorization to a Bunny Edge Storage zone. cert-manager
+// only delegates DNS01 challenges to webhooks, so Issuers select it with an
+// http01.ingress solver of the solver's ingress class, which no ingress
+// controller serves: cert-manager's own solver pod and Ingress stay unused,
+// while the CA and cert-manager's self check fetch the file through the
+// pull zone in front of the storage zone.
+type http01Solver struct {
+	class   string
+	storage *bunny.Storage
+
+	factory    cminformers.SharedInformerFactory
+	challenges cmacmelisters.ChallengeLister
+	queue      workqueue.TypedRateLimitingInterface[string]
+
+	// uploaded holds the token of each selected Challenge once its file is
+	// uploaded, so resyncs don't repeat the upload, and of deleted ones
+	// until their file is deleted.
+	mu       sync.Mutex
+	uploaded map[string]string
+}
+
+func newHTTP01Solver(opts Options, cfg *rest.Config) (*http01Solver, error) {
+	cl, err := cmclient.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cert-manager client: %w", err)
+	}
+
+	factory := cminformers.NewSharedInformerFactory(cl, 0)
+	h := &http01Solver{
+		class: opts.HTTP01IngressClass,
+		storage: bunny.NewStorage(bunny.StorageOptions{
+			Endpoint: opts.StorageEndpoint,
+			Zone:     opts.StorageZone,
+			Password: opts.StoragePassword,
+		}),
+		factory:    factory,
+		challenges: factory.Acme().V1().Challenges().Lister(),
+		queue: workqueue.NewTypedRateLimitingQueue(
+			workqueue.DefaultTypedControllerRateLimiter[string](),
+		),
+		uploaded: make(map[string]string),
+	}
+
+	enqueue := func(obj interface{}) {
+		if key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj); err == nil {
+			h.queue.Add(key)
+		}
+	}
+	if _, err := factory.Acme().V1().Challenges().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    enqueue,
+		UpdateFunc: func(_, obj interface{}) { enqueue(obj) },
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			// Remember the token, which sync can't look up any more.
+			if ch, ok := obj.(*cmacme.Challenge); ok && h.selected(ch) {
+				h.mu.Lock()
+				h.uploaded[ch.Namespace+"/"+ch.Name] = ch.Spec.Token
+				h.mu.Unlock()
+			}
+			enqueue(obj)
+		},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to register challenge event handler: %w", err)
+	}
+
+	return h, nil
+}
+
+func (h *http01Solver) job() BackgroundJob {
+	return BackgroundJob{Name: "http01-storage", Run: h.run}
+}
+
+func (h *http01Solver) run(ctx context.Context) {
+	h.factory.Start(ctx.Done())
+	for typ, ok := range h.factory.WaitForCacheSync(ctx.Done()) {
+		if !ok {
+			slog.Error("failed to sync informer cache", "type", typ)
+			return
+		}
+	}
+
+	go func() {
+		<-ctx.Done()
+		h.queue.ShutDown()
+	}()
+
+	for h.processNextItem(ctx) {
+	}
+}
+
+func (h *http01Solver) processNextItem(ctx context.Context) bool {
+	key, shutdown := h.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer h.queue.Done(key)
+
+	if err := h.sync(ctx, key); err != nil {
+		slog.Error("failed to sync HTTP01 challenge", "challenge", key, "error", redactError(err))
+		h.queue.AddRateLimited(key)
+		return true
+	}
+	h.queue.Forget(key)
+	return true
+}
+
+// sync uploads the challenge file of the Challenge named key while it is
+// pending and deletes it once the Challenge is done or gone.
+func (h *http01Solver) sync(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil
+	}
+	ch, err := h.challenges.Challenges(namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		return h.remove(ctx, key)
+	}
+	if err != nil {
+		return err
+	}
+	if !h.selected(ch) {
+		return nil
+	}
+	if ch.DeletionTimestamp != nil || challengeDone(ch.Status.State) {
+		h.mu.Lock()
+		h.uploaded[key] = ch.Spec.Token
+		h.mu.Unlock()
+		return h.remove(ctx, key)
+	}
+
+	h.mu.Lock()
+	token, done := h.uploaded[key]
+	h.mu.Unlock()
+	if done && token == ch.Spec.Token {
+		return nil
+	}
+	if err := h.storage.Upload(ctx, bunnylego.ChallengePath(ch.Spec.Token), []byte(ch.Spec.Key)); err != nil {
+		return fmt.Errorf("failed to upload challenge for %s: %w", ch.Spec.DNSName, err)
+	}
+	slog.Info("Uploaded HTTP01 challenge to Edge Storage", "challenge", key, "domain", ch.Spec.DNSName)
+	h.mu.Lock()
+	h.uploaded[key] = ch.Spec.Token
+	h.mu.Unlock()
+	return nil
+}
+
+// remove deletes the challenge file of the Challenge named key, if any.
+func (h *http01Solver) remove(ctx context.Context, key string) error {
+	h.mu.Lock()
+	token, ok := h.uploaded[key]
+	h.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	if err := h.storage.Delete(ctx, bunnylego.ChallengePath(token)); err != nil && !bunny.IsNotFound(err) {
+		return fmt.Errorf("failed to delete challenge file: %w", err)
+	}
+	h.mu.Lock()
+	delete(h.uploaded, key)
+	h.mu.Unlock()
+	return nil
+}
+
+// selected reports whether ch is an HTTP01 challenge of an Issuer selecting
+// this solver through its ingress class.
+func (h *http01Solver) selected(ch *cmacme.Challenge) bool {
+	if ch.Spec.Type != cmacme.ACMEChallengeTypeHTTP01 || ch.Spec.Solver.HTTP01 == nil || ch.Spec.Solver.HTTP01.Ingress == nil {
+		return false
+	}
+	ingress := ch.Spec.Solver.HTTP01.Ingress
+	if ingress.IngressClassName != nil {
+		return *ingress.IngressClassName == h.class
+	}
+	return ingress.Class != nil && *ingress.Class == h.class
+}
+
+// challengeDone reports whether a Challenge in state s has been decided and
+// no longer needs its challenge file.
+func challengeDone(s cmacme.State) bool {
+	switch s {
+	case cmacme.Valid, cmacme.Invalid, cmacme.Errored, cmacme.Expired:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package solver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	cmacme "github.com/cert-manager/cert-manager/pkg/apis/acme/v1"
+	cmacmelisters "github.com/cert-manager/cert-manager/pkg/client/listers/acme/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/cert-manager/webhook-example/pkg/bunny"
+)
+
+// storageFiles is a fake Edge Storage API keeping the uploaded files.
+type storageFiles struct {
+	mu    sync.Mutex
+	files map[string]string
+}
+
+func (s *storageFiles) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		s.files[r.URL.Path] = string(body)
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodDelete:
+		if _, ok := s.files[r.URL.Path]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(s.files, r.URL.Path)
+	}
+}
+
+func http01Challenge(name, class, token string) *cmacme.Challenge {
+	return &cmacme.Challenge{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Spec: cmacme.ChallengeSpec{
+			Type:    cmacme.ACMEChallengeTypeHTTP01,
+			DNSName: "www.example.com",
+			Token:   token,
+			Key:     token + ".thumbprint",
+			Solver: cmacme.ACMEChallengeSolver{HTTP01: &cmacme.ACMEChallengeSolverHTTP01{
+				Ingress: &cmacme.ACMEChallengeSolverHTTP01Ingress{IngressClassName: &class},
+			}},
+		},
+	}
+}
+
+func TestHTTP01Solver_Sync(t *testing.T) {
+	ctx := context.Background()
+	storage := &storageFiles{files: map[string]string{}}
+	srv := httptest.NewServer(storage)
+	defer srv.Close()
+
+	challenges := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	h := &http01Solver{
+		class:      "bunny-edge-storage",
+		storage:    bunny.NewStorage(bunny.StorageOptions{Endpoint: srv.URL, Zone: "site", Password: "password"}),
+		challenges: cmacmelisters.NewChallengeLister(challenges),
+		uploaded:   make(map[string]string),
+	}
+
+	ours := http01Challenge("ours", "bunny-edge-storage", "token")
+	require.NoError(t, challenges.Add(ours))
+	require.NoError(t, challenges.Add(http01Challenge("nginx", "nginx", "other")))
+	require.NoError(t, h.sync(ctx, "default/ours"))
+	require.NoError(t, h.sync(ctx, "default/nginx"))
+	assert.Equal(t, map[string]string{"/site/.well-known/acme-challenge/token": "token.thumbprint"}, storage.files,
+		"only Challenges of Issuers selecting the ingress class are uploaded")
+
+	valid := ours.DeepCopy()
+	valid.Status.State = cmacme.Valid
+	require.NoError(t, challenges.Update(valid))
+	require.NoError(t, h.sync(ctx, "default/ours"))
+	assert.Empty(t, storage.files, "the file is deleted once the Challenge is valid")
+
+	// A Challenge deleted before it was decided, e.g. after a restart.
+	require.NoError(t, challenges.Update(ours))
+	require.NoError(t, h.sync(ctx, "default/ours"))
+	require.Len(t, storage.files, 1)
+	require.NoError(t, challenges.Delete(ours))
+	require.NoError(t, h.sync(ctx, "default/ours"))
+	assert.Empty(t, storage.files)
+}
@@ -0,0 +1,146 @@
+package solver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+
+	"github.com/cert-manager/webhook-example/pkg/version"
+)
+
+// Instrumentation receives the solver's operations and Bunny API calls, for
+// APM products that are not fed through Prometheus. Implementations must be
+// safe for concurrent use.
+type Instrumentation interface {
+	// StartOperation is called when a Present or CleanUp starts. The
+	// returned function is called with the operation's result when it ends.
+	StartOperation(ctx context.Context, operation string, attrs map[string]string) (context.Context, func(err error))
+
+	// APICall is called after every Bunny API request. endpoint is the
+	// request path with IDs replaced by {id}; status is 0 if no response
+	// was received.
+	APICall(ctx context.Context, method, endpoint string, status int, duration time.Duration, err error)
+}
+
+type noopInstrumentation struct{}
+
+func (noopInstrumentation) StartOperation(ctx context.Context, _ string, _ map[string]string) (context.Context, func(error)) {
+	return ctx, func(error) {}
+}
+
+func (noopInstrumentation) APICall(context.Context, string, string, int, time.Duration, error) {}
+
+// instrumentation returns the configured Instrumentation, or one doing
+// nothing.
+func (c *Solver) instrumentation() Instrumentation {
+	if c.opts.Instrumentation == nil {
+		return noopInstrumentation{}
+	}
+	return c.opts.Instrumentation
+}
+
+// challengeAttrs are the attributes operations on ch are reported with.
+func challengeAttrs(ch *v1alpha1.ChallengeRequest) map[string]string {
+	return map[string]string{
+		"uid":       string(ch.UID),
+		"fqdn":      ch.ResolvedFQDN,
+		"zone":      ch.ResolvedZone,
+		"namespace": ch.ResourceNamespace,
+	}
+}
+
+// do authenticates and sends a Bunny API request, retrying transient
+// failures, and reports every attempt to Prometheus and the config's
+// instrumentation.
+// Every attempt waits for the config's rate limiter, if any, and requests
+// fail fast while the circuit breaker is open. A request rejected with 401
+// is repeated with each of the config's fallback keys in turn.
+func (cfg bunnyNetDNSConfig) do(req *http.Request) (*http.Response, error) {
+	breaker := cfg.breakers.get(cfg)
+	if err := breaker.allow(); err != nil {
+		return nil, err
+	}
+	resp, err := cfg.send(req)
+	for _, key := range cfg.fallbackKeys {
+		if err != nil || resp.StatusCode != http.StatusUnauthorized {
+			break
+		}
+		next, ok := replayable(req)
+		if !ok {
+			break
+		}
+		resp.Body.Close()
+		apiKeyFallbackTotal.Inc()
+		logger(req.Context()).Warn("Bunny rejected the API key, retrying with the next one")
+		cfg.APIKey = key
+		req = next
+		resp, err = cfg.send(req)
+	}
+	breaker.record(resp, err)
+	return resp, err
+}
+
+// userAgent identifies the webhook's traffic to Bunny.
+var userAgent = "cert-manager-webhook-bunny/" + version.Get().Version
+
+// send authenticates req with the config's API key and sends it.
+func (cfg bunnyNetDNSConfig) send(req *http.Request) (*http.Response, error) {
+	cfg.authorize(req)
+	req.Header.Set("User-Agent", userAgent)
+	return cfg.retry.doWithRetry(req, func(req *http.Request) (*http.Response, error) {
+		if cfg.limiter != nil {
+			if err := cfg.limiter.Wait(req.Context()); err != nil {
+				return nil, fmt.Errorf("failed to wait for the API rate limiter: %w", err)
+			}
+		}
+		start := time.Now()
+		apiRequestsInFlight.Inc()
+		resp, err := httpClient.Do(req)
+		apiRequestsInFlight.Dec()
+		duration := time.Since(start)
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		endpoint := apiEndpoint(req.URL.Path)
+		observeAPICall(req.Method, endpoint, status, duration)
+		if cfg.instrumentation != nil {
+			cfg.instrumentation.APICall(req.Context(), req.Method, endpoint, status, duration, err)
+		}
+		return resp, err
+	})
+}
+
+// replayable returns a copy of req that can be sent again, or false when
+// its body can't be replayed.
+func replayable(req *http.Request) (*http.Request, bool) {
+	next := req.Clone(req.Context())
+	if req.Body == nil || req.Body == http.NoBody {
+		return next, true
+	}
+	if req.GetBody == nil {
+		return nil, false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, false
+	}
+	next.Body = body
+	return next, true
+}
+
+// apiEndpoint replaces the numeric segments of an API path with {id}, so
+// endpoints can be used as low-cardinality labels.
+func apiEndpoint(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if s != "" && strings.Trim(s, "0123456789") == "" {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
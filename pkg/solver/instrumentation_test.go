@@ -0,0 +1,31 @@
+package solver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIEndpoint(t *testing.T) {
+	assert.Equal(t, "/dnszone/{id}/records/{id}", apiEndpoint("/dnszone/123/records/45"))
+	assert.Equal(t, "/dnszone", apiEndpoint("/dnszone"))
+}
+
+func TestDoSetsUserAgent(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("User-Agent")
+	}))
+	defer srv.Close()
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	require.NoError(t, err)
+	resp, err := bunnyNetDNSConfig{}.do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.True(t, strings.HasPrefix(got, "cert-manager-webhook-bunny/"), got)
+}
@@ -0,0 +1,99 @@
+package solver
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// listZonesPageSize is the largest page the Bunny API returns.
+const listZonesPageSize = 1000
+
+// InventoryZone is a zone as reported by the inventory API.
+type InventoryZone struct {
+	ID      int           `json:"id"`
+	Domain  string        `json:"domain"`
+	Records []AdminRecord `json:"records"`
+}
+
+// Inventory is the webhook's view of the Bunny account.
+type Inventory struct {
+	GeneratedAt time.Time       `json:"generatedAt"`
+	Zones       []InventoryZone `json:"zones"`
+}
+
+// NewInventoryHandler returns a read-only handler reporting every zone
+// visible to the webhook-wide API key of s with the challenge TXT records in
+// it, so reconciliation tools can detect drift without Bunny credentials of
+// their own:
+//
+//	GET /inventory              all zones
+//	GET /inventory?zone=name    a single zone
+//
+// The handler does no authentication of its own.
+func NewInventoryHandler(s *Solver) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /inventory", func(w http.ResponseWriter, r *http.Request) {
+		cfg := s.ambientConfig()
+		var zones []Item
+		if zone := r.URL.Query().Get("zone"); zone != "" {
+			item, err := getZoneRecords(r.Context(), cfg, zone)
+			if err != nil {
+				http.Error(w, redact(err.Error()), http.StatusBadGateway)
+				return
+			}
+			zones = []Item{item}
+		} else {
+			var err error
+			if zones, err = listZones(r.Context(), cfg); err != nil {
+				http.Error(w, redact(err.Error()), http.StatusBadGateway)
+				return
+			}
+		}
+
+		inventory := Inventory{GeneratedAt: time.Now().UTC(), Zones: []InventoryZone{}}
+		for _, zone := range zones {
+			inventory.Zones = append(inventory.Zones, InventoryZone{
+				ID:      zone.ID,
+				Domain:  zone.Domain,
+				Records: challengeRecords(zone),
+			})
+		}
+		writeAdminJSON(w, http.StatusOK, inventory)
+	})
+	return mux
+}
+
+// challengeRecords returns the _acme-challenge TXT records of zone.
+func challengeRecords(zone Item) []AdminRecord {
+	records := []AdminRecord{}
+	for _, rec := range zone.Records {
+		if rec.Type != recordType || !strings.HasPrefix(rec.Name, "_acme-challenge") {
+			continue
+		}
+		records = append(records, AdminRecord{
+			ID:    rec.ID,
+			Zone:  zone.Domain,
+			FQDN:  rec.Name + "." + zone.Domain + ".",
+			Value: rec.Value,
+			TTL:   rec.Ttl,
+		})
+	}
+	return records
+}
+
+// listZones returns every zone in the account, following pagination.
+func listZones(ctx context.Context, cfg bunnyNetDNSConfig) ([]Item, error) {
+	var zones []Item
+	for page := 1; ; page++ {
+		data, err := zonePage(ctx, cfg, "", page, listZonesPageSize)
+		if err != nil {
+			return nil, err
+		}
+		zones = append(zones, data.Items...)
+		if !data.HasMoreItems || len(data.Items) == 0 {
+			return zones, nil
+		}
+	}
+}
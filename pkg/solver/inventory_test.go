@@ -0,0 +1,39 @@
+package solver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListZones_Pagination(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "key", r.Header.Get("AccessKey"))
+		resp := ZoneResponse{Items: []Item{{ID: 1, Domain: "example.com"}}, HasMoreItems: true}
+		if r.URL.Query().Get("page") == "2" {
+			resp = ZoneResponse{Items: []Item{{ID: 2, Domain: "example.org"}}}
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	zones, err := listZones(context.Background(), bunnyNetDNSConfig{APIKey: "key", APIURL: srv.URL})
+	require.NoError(t, err)
+	require.Len(t, zones, 2)
+	assert.Equal(t, "example.org", zones[1].Domain)
+}
+
+func TestChallengeRecords(t *testing.T) {
+	records := challengeRecords(Item{Domain: "example.com", Records: []Record{
+		{ID: 1, Type: recordType, Name: "_acme-challenge.www", Value: "token", Ttl: 10},
+		{ID: 2, Type: recordType, Name: "www", Value: "v=spf1 -all"},
+		{ID: 3, Type: 0, Name: "_acme-challenge"},
+	}})
+	require.Len(t, records, 1)
+	assert.Equal(t, "_acme-challenge.www.example.com.", records[0].FQDN)
+}
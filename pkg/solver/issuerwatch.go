@@ -0,0 +1,224 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmclient "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned"
+	cminformers "github.com/cert-manager/cert-manager/pkg/client/informers/externalversions"
+	cmlisters "github.com/cert-manager/cert-manager/pkg/client/listers/certmanager/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	// issuerRevalidateInterval re-checks every Issuer periodically so a
+	// revoked key or deleted zone is noticed before the next renewal.
+	issuerRevalidateInterval = 30 * time.Minute
+
+	reasonConfigInvalid = "BunnyConfigInvalid"
+	reasonConfigValid   = "BunnyConfigValid"
+)
+
+type issuerKey struct {
+	kind, namespace, name string
+}
+
+// issuerWatcher validates the bunny solver config of every Issuer and
+// ClusterIssuer in the background and reports the result through the
+// issuer_config_valid metric and Events on the issuer, before any
+// Certificate is requested.
+type issuerWatcher struct {
+	solver *Solver
+
+	factory        cminformers.SharedInformerFactory
+	issuers        cmlisters.IssuerLister
+	clusterIssuers cmlisters.ClusterIssuerLister
+	recorder       record.EventRecorder
+	queue          workqueue.TypedRateLimitingInterface[issuerKey]
+
+	mu    sync.Mutex
+	valid map[issuerKey]bool
+}
+
+func newIssuerWatcher(solver *Solver, cfg *rest.Config, recorder record.EventRecorder) (*issuerWatcher, error) {
+	cl, err := cmclient.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cert-manager client: %w", err)
+	}
+
+	factory := cminformers.NewSharedInformerFactory(cl, issuerRevalidateInterval)
+	w := &issuerWatcher{
+		solver:         solver,
+		factory:        factory,
+		issuers:        factory.Certmanager().V1().Issuers().Lister(),
+		clusterIssuers: factory.Certmanager().V1().ClusterIssuers().Lister(),
+		recorder:       recorder,
+		queue: workqueue.NewTypedRateLimitingQueue(
+			workqueue.DefaultTypedControllerRateLimiter[issuerKey](),
+		),
+		valid: make(map[issuerKey]bool),
+	}
+
+	handler := func(kind string) cache.ResourceEventHandlerFuncs {
+		enqueue := func(obj interface{}) {
+			key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+			if err != nil {
+				return
+			}
+			namespace, name, err := cache.SplitMetaNamespaceKey(key)
+			if err != nil {
+				return
+			}
+			w.queue.Add(issuerKey{kind: kind, namespace: namespace, name: name})
+		}
+		return cache.ResourceEventHandlerFuncs{
+			AddFunc:    enqueue,
+			UpdateFunc: func(_, obj interface{}) { enqueue(obj) },
+			DeleteFunc: enqueue,
+		}
+	}
+	if _, err := factory.Certmanager().V1().Issuers().Informer().AddEventHandler(handler(cmapi.IssuerKind)); err != nil {
+		return nil, fmt.Errorf("failed to register issuer event handler: %w", err)
+	}
+	if _, err := factory.Certmanager().V1().ClusterIssuers().Informer().AddEventHandler(handler(cmapi.ClusterIssuerKind)); err != nil {
+		return nil, fmt.Errorf("failed to register cluster issuer event handler: %w", err)
+	}
+
+	return w, nil
+}
+
+func (w *issuerWatcher) job() BackgroundJob {
+	return BackgroundJob{Name: "issuer-watcher", Run: w.run}
+}
+
+func (w *issuerWatcher) run(ctx context.Context) {
+	w.factory.Start(ctx.Done())
+	for typ, ok := range w.factory.WaitForCacheSync(ctx.Done()) {
+		if !ok {
+			slog.Error("failed to sync informer cache", "type", typ)
+			return
+		}
+	}
+
+	go func() {
+		<-ctx.Done()
+		w.queue.ShutDown()
+	}()
+
+	for w.processNextItem(ctx) {
+	}
+}
+
+func (w *issuerWatcher) processNextItem(ctx context.Context) bool {
+	key, shutdown := w.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer w.queue.Done(key)
+
+	if err := w.sync(ctx, key); err != nil {
+		slog.Error("failed to validate issuer", "kind", key.kind, "namespace", key.namespace, "name", key.name, "error", err)
+		w.queue.AddRateLimited(key)
+		return true
+	}
+	w.queue.Forget(key)
+	return true
+}
+
+func (w *issuerWatcher) sync(ctx context.Context, key issuerKey) error {
+	var (
+		obj          runtime.Object
+		spec         cmapi.IssuerSpec
+		namespace    string
+		allowAmbient bool
+		err          error
+	)
+
+	switch key.kind {
+	case cmapi.IssuerKind:
+		var issuer *cmapi.Issuer
+		issuer, err = w.issuers.Issuers(key.namespace).Get(key.name)
+		if err == nil {
+			obj, spec, namespace = issuer, issuer.Spec, issuer.Namespace
+		}
+	case cmapi.ClusterIssuerKind:
+		var issuer *cmapi.ClusterIssuer
+		issuer, err = w.clusterIssuers.Get(key.name)
+		if err == nil {
+			obj, spec, namespace, allowAmbient = issuer, issuer.Spec, w.solver.opts.ClusterResourceNamespace, true
+		}
+	}
+	if apierrors.IsNotFound(err) {
+		w.forget(key)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if !w.usesSolver(spec) {
+		w.forget(key)
+		return nil
+	}
+
+	problems, warnings := w.solver.validateIssuerSpec(ctx, spec, namespace, allowAmbient)
+	if len(problems) == 0 && len(warnings) > 0 {
+		// Bunny couldn't be asked; keep the last verdict and try again.
+		return errors.New(strings.Join(warnings, "; "))
+	}
+	valid := len(problems) == 0
+
+	w.mu.Lock()
+	previous, seen := w.valid[key]
+	w.valid[key] = valid
+	w.mu.Unlock()
+
+	issuerConfigValid.WithLabelValues(key.kind, key.namespace, key.name).Set(boolToFloat(valid))
+
+	switch {
+	case !valid:
+		w.recorder.Eventf(obj, corev1.EventTypeWarning, reasonConfigInvalid,
+			"bunny-net solver config is invalid: %s", strings.Join(problems, "; "))
+	case seen && !previous:
+		w.recorder.Event(obj, corev1.EventTypeNormal, reasonConfigValid, "bunny-net solver config is valid")
+	}
+	return nil
+}
+
+func (w *issuerWatcher) usesSolver(spec cmapi.IssuerSpec) bool {
+	if spec.ACME == nil {
+		return false
+	}
+	for _, solver := range spec.ACME.Solvers {
+		if w.solver.handlesSolver(solver) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *issuerWatcher) forget(key issuerKey) {
+	w.mu.Lock()
+	delete(w.valid, key)
+	w.mu.Unlock()
+	issuerConfigValid.DeleteLabelValues(key.kind, key.namespace, key.name)
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
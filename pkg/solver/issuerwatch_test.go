@@ -0,0 +1,179 @@
+package solver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	cmacme "github.com/cert-manager/cert-manager/pkg/apis/acme/v1"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmlisters "github.com/cert-manager/cert-manager/pkg/client/listers/certmanager/v1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/cert-manager/webhook-example/pkg/bunny/bunnytest"
+)
+
+// issuerSpec returns an ACME issuer spec whose only solver is the webhook
+// in group with config.
+func issuerSpec(group, config string) cmapi.IssuerSpec {
+	return cmapi.IssuerSpec{IssuerConfig: cmapi.IssuerConfig{ACME: &cmacme.ACMEIssuer{
+		Solvers: []cmacme.ACMEChallengeSolver{{DNS01: &cmacme.ACMEChallengeSolverDNS01{
+			Webhook: &cmacme.ACMEIssuerDNS01ProviderWebhook{
+				GroupName:  group,
+				SolverName: DefaultName,
+				Config:     &apiextensionsv1.JSON{Raw: []byte(config)},
+			},
+		}}},
+	}}}
+}
+
+func newTestIssuerWatcher(t *testing.T) (*issuerWatcher, cache.Indexer, cache.Indexer, *record.FakeRecorder, func(zone string) string) {
+	t.Helper()
+	api := bunnytest.NewServer()
+	t.Cleanup(api.Close)
+	api.RequireAPIKey("secret")
+	api.AddZone("example.com")
+
+	s := New(Options{Groups: []string{"acme.example.com"}, ClusterResourceNamespace: "cert-manager"})
+	s.client = fake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "bunny"},
+			Data:       map[string][]byte{"api-key": []byte("secret")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "cert-manager", Name: "bunny"},
+			Data:       map[string][]byte{"api-key": []byte("secret")},
+		},
+	)
+	issuers := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	clusterIssuers := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	recorder := record.NewFakeRecorder(10)
+	w := &issuerWatcher{
+		solver:         s,
+		issuers:        cmlisters.NewIssuerLister(issuers),
+		clusterIssuers: cmlisters.NewClusterIssuerLister(clusterIssuers),
+		recorder:       recorder,
+		valid:          make(map[issuerKey]bool),
+	}
+	config := func(zone string) string {
+		return fmt.Sprintf(`{"apiURL":%q,"apiKeySecretRef":{"name":"bunny","key":"api-key"},"zone":%q}`, api.URL, zone)
+	}
+	return w, issuers, clusterIssuers, recorder, config
+}
+
+func TestIssuerWatcher_Sync(t *testing.T) {
+	w, issuers, _, recorder, config := newTestIssuerWatcher(t)
+	key := issuerKey{kind: cmapi.IssuerKind, namespace: "team-a", name: "letsencrypt"}
+	issuer := &cmapi.Issuer{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "letsencrypt"},
+		Spec:       issuerSpec("acme.example.com", config("example.com")),
+	}
+	valid := func() float64 {
+		return testutil.ToFloat64(issuerConfigValid.WithLabelValues(key.kind, key.namespace, key.name))
+	}
+
+	require.NoError(t, issuers.Add(issuer))
+	require.NoError(t, w.sync(context.Background(), key))
+	assert.Equal(t, 1.0, valid())
+	assert.Empty(t, recorder.Events, "a valid config is not reported")
+
+	broken := issuer.DeepCopy()
+	broken.Spec = issuerSpec("acme.example.com", config("example.org"))
+	require.NoError(t, issuers.Update(broken))
+	require.NoError(t, w.sync(context.Background(), key))
+	assert.Equal(t, 0.0, valid())
+	require.Len(t, recorder.Events, 1)
+	event := <-recorder.Events
+	assert.Contains(t, event, "Warning BunnyConfigInvalid bunny-net solver config is invalid: ")
+	assert.Contains(t, event, "no DNS zone found for example.org")
+
+	require.NoError(t, issuers.Update(issuer))
+	require.NoError(t, w.sync(context.Background(), key))
+	assert.Equal(t, 1.0, valid())
+	require.Len(t, recorder.Events, 1)
+	assert.Equal(t, "Normal BunnyConfigValid bunny-net solver config is valid", <-recorder.Events)
+
+	// Deleted Issuers drop out of the metric.
+	require.NoError(t, issuers.Delete(issuer))
+	require.NoError(t, w.sync(context.Background(), key))
+	assert.False(t, issuerConfigValid.DeleteLabelValues(key.kind, key.namespace, key.name))
+	assert.Empty(t, w.valid)
+}
+
+func TestIssuerWatcher_SubdomainDNSZones(t *testing.T) {
+	w, issuers, _, recorder, config := newTestIssuerWatcher(t)
+	key := issuerKey{kind: cmapi.IssuerKind, namespace: "team-a", name: "letsencrypt"}
+	issuer := &cmapi.Issuer{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "letsencrypt"},
+		Spec:       issuerSpec("acme.example.com", config("example.com")),
+	}
+	// Records for a subdomain go to the enclosing Bunny zone, as in Present.
+	issuer.Spec.ACME.Solvers[0].Selector = &cmacme.CertificateDNSNameSelector{DNSZones: []string{"sub.example.com", "example.com"}}
+
+	require.NoError(t, issuers.Add(issuer))
+	require.NoError(t, w.sync(context.Background(), key))
+	assert.Equal(t, 1.0, testutil.ToFloat64(issuerConfigValid.WithLabelValues(key.kind, key.namespace, key.name)))
+	assert.Empty(t, recorder.Events)
+
+	issuer.Spec.ACME.Solvers[0].Selector.DNSZones = []string{"sub.example.org"}
+	require.NoError(t, issuers.Update(issuer))
+	require.NoError(t, w.sync(context.Background(), key))
+	assert.Equal(t, 0.0, testutil.ToFloat64(issuerConfigValid.WithLabelValues(key.kind, key.namespace, key.name)))
+	require.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, "selector.dnsZones: no DNS zone found for sub.example.org")
+}
+
+func TestIssuerWatcher_BunnyUnreachable(t *testing.T) {
+	w, issuers, _, recorder, _ := newTestIssuerWatcher(t)
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	w.solver.opts.Retry = RetryPolicy{MaxAttempts: 1}
+	key := issuerKey{kind: cmapi.IssuerKind, namespace: "team-a", name: "letsencrypt"}
+
+	require.NoError(t, issuers.Add(&cmapi.Issuer{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "letsencrypt"},
+		Spec:       issuerSpec("acme.example.com", fmt.Sprintf(`{"apiURL":%q,"apiKeySecretRef":{"name":"bunny","key":"api-key"},"zone":"example.com"}`, down.URL)),
+	}))
+	assert.ErrorContains(t, w.sync(context.Background(), key), "could not be checked", "the Issuer is retried later")
+	assert.Empty(t, w.valid, "an outage says nothing about the config")
+	assert.Empty(t, recorder.Events)
+}
+
+func TestIssuerWatcher_ClusterIssuer(t *testing.T) {
+	w, _, clusterIssuers, recorder, config := newTestIssuerWatcher(t)
+	key := issuerKey{kind: cmapi.ClusterIssuerKind, name: "letsencrypt"}
+
+	// The Secret is read from the cluster resource namespace.
+	require.NoError(t, clusterIssuers.Add(&cmapi.ClusterIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "letsencrypt"},
+		Spec:       issuerSpec("acme.example.com", config("example.com")),
+	}))
+	require.NoError(t, w.sync(context.Background(), key))
+	assert.Equal(t, 1.0, testutil.ToFloat64(issuerConfigValid.WithLabelValues(key.kind, "", key.name)))
+	assert.Empty(t, recorder.Events)
+}
+
+func TestIssuerWatcher_IgnoresOtherSolvers(t *testing.T) {
+	w, issuers, _, recorder, config := newTestIssuerWatcher(t)
+	key := issuerKey{kind: cmapi.IssuerKind, namespace: "team-a", name: "other"}
+
+	require.NoError(t, issuers.Add(&cmapi.Issuer{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "other"},
+		Spec:       issuerSpec("acme.other.example", config("example.org")),
+	}))
+	require.NoError(t, w.sync(context.Background(), key))
+	assert.False(t, issuerConfigValid.DeleteLabelValues(key.kind, key.namespace, key.name), "no metric for Issuers of other webhooks")
+	assert.Empty(t, recorder.Events)
+}
@@ -0,0 +1,92 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// DefaultKeyFilePollInterval is how often a KeyFile is re-read. The kubelet
+// takes up to a minute to update mounted Secrets, so polling faster gains
+// little.
+const DefaultKeyFilePollInterval = 10 * time.Second
+
+// APIKeyProvider supplies the webhook-wide API key, which may change while
+// the webhook runs.
+type APIKeyProvider interface {
+	APIKey() string
+}
+
+// StaticAPIKey is an API key that never changes.
+type StaticAPIKey string
+
+func (k StaticAPIKey) APIKey() string { return string(k) }
+
+// KeyFile is an API key read from a file, typically a mounted Secret. Watch
+// re-reads it so a rotated key is picked up without a restart.
+type KeyFile struct {
+	path string
+
+	mu  sync.RWMutex
+	key string
+}
+
+// NewKeyFile reads the API key in path. Surrounding whitespace is ignored.
+func NewKeyFile(path string) (*KeyFile, error) {
+	f := &KeyFile{path: path}
+	if _, err := f.reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// APIKey returns the key last read from the file.
+func (f *KeyFile) APIKey() string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.key
+}
+
+// Watch re-reads the file every interval until ctx is done. A file that
+// can't be read or is empty, as happens briefly while a Secret is updated,
+// keeps the previous key.
+func (f *KeyFile) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultKeyFilePollInterval
+	}
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		changed, err := f.reload()
+		if err != nil {
+			slog.Error("failed to reload API key file", "path", f.path, "error", err)
+			return
+		}
+		if changed {
+			slog.Info("Reloaded API key file", "path", f.path)
+		}
+	}, interval)
+}
+
+func (f *KeyFile) reload() (bool, error) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read API key file: %w", err)
+	}
+	key := strings.TrimSpace(string(data))
+	if key == "" {
+		return false, errors.New("API key file is empty")
+	}
+	registerSecret(key)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	changed := f.key != "" && f.key != key
+	f.key = key
+	return changed, nil
+}
@@ -0,0 +1,37 @@
+package solver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyFileReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api-key")
+	require.NoError(t, os.WriteFile(path, []byte("first-key-value\n"), 0o600))
+
+	f, err := NewKeyFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "first-key-value", f.APIKey())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go f.Watch(ctx, 10*time.Millisecond)
+
+	// An empty file, as seen mid-update, keeps the previous key.
+	require.NoError(t, os.WriteFile(path, nil, 0o600))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, "first-key-value", f.APIKey())
+
+	require.NoError(t, os.WriteFile(path, []byte("second-key-value"), 0o600))
+	assert.Eventually(t, func() bool { return f.APIKey() == "second-key-value" }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, redacted, redact("second-key-value"))
+
+	_, err = NewKeyFile(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
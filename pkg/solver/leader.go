@@ -0,0 +1,122 @@
+package solver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// Any replica can serve any Present or CleanUp: the record is found from the
+// ChallengeRequest, the Issuer config and the Bunny API. Replicas do keep
+// some state of their own, which the others can't see: activeRecords, the
+// challenges between Present and CleanUp on this replica, and
+// fallbackRecords, the records published through the fallback. Other
+// replicas' challenges are recognized from the Challenge resources instead,
+// and fallback records are only shared through the cleanup queue ConfigMap;
+// without it they are only cleaned up by the replica that published them.
+// Work that is not tied to a request (garbage collection, the cleanup queue,
+// the standalone controller) would be duplicated by every replica, so it
+// runs as a background job on the elected leader only.
+
+const (
+	leaseDuration = 15 * time.Second
+	renewDeadline = 10 * time.Second
+	retryPeriod   = 2 * time.Second
+)
+
+// BackgroundJob is work that runs for the lifetime of the solver rather
+// than per request.
+type BackgroundJob struct {
+	Name string
+	Run  func(ctx context.Context)
+}
+
+// AddBackgroundJob registers a job to be started with the solver's own jobs
+// once Initialize has completed. It must be called before Initialize.
+func (c *Solver) AddBackgroundJob(job BackgroundJob) {
+	c.jobs = append(c.jobs, job)
+}
+
+// startBackgroundJobs runs the solver's jobs until ctx is done. With leader
+// election enabled they only run while this replica holds the lease.
+func (c *Solver) startBackgroundJobs(ctx context.Context, cl kubernetes.Interface) error {
+	jobs := c.jobs
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	runAll := func(ctx context.Context) {
+		for _, job := range jobs {
+			slog.Info("Starting background job", "job", job.Name)
+			go job.Run(ctx)
+		}
+		<-ctx.Done()
+	}
+
+	if !c.opts.LeaderElection {
+		go runAll(ctx)
+		return nil
+	}
+
+	// The identity must be unique per process, not just per host: a
+	// restarted container keeps the pod's hostname while the old process
+	// may still hold the Lease.
+	hostname, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("failed to determine leader election identity: %w", err)
+	}
+	id := hostname + "_" + string(uuid.NewUUID())
+	elector, err := newLeaderElector(cl, id, c.opts, runAll)
+	if err != nil {
+		return err
+	}
+	go func() {
+		// Run returns whenever leadership is lost; keep campaigning until
+		// the process shuts down.
+		for ctx.Err() == nil {
+			elector.Run(ctx)
+		}
+	}()
+	return nil
+}
+
+// newLeaderElector campaigns for the Lease named by opts as id and runs
+// onLeading while holding it.
+func newLeaderElector(cl kubernetes.Interface, id string, opts Options, onLeading func(ctx context.Context)) (*leaderelection.LeaderElector, error) {
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      opts.LeaderElectionID,
+			Namespace: opts.Namespace,
+		},
+		Client:     cl.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: id},
+	}
+
+	return leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   leaseDuration,
+		RenewDeadline:   renewDeadline,
+		RetryPeriod:     retryPeriod,
+		ReleaseOnCancel: true,
+		Name:            opts.LeaderElectionID,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: onLeading,
+			OnStoppedLeading: func() {
+				slog.Info("Lost leadership", "identity", id, "lease", opts.Namespace+"/"+opts.LeaderElectionID)
+			},
+			OnNewLeader: func(identity string) {
+				if identity != id {
+					slog.Info("Background jobs are run by another leader", "leader", identity)
+				}
+			},
+		},
+	})
+}
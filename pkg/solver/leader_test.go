@@ -0,0 +1,94 @@
+package solver
+
+import (
+	"context"
+	"os"
+	"regexp"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/leaderelection"
+)
+
+// leaseHolder returns the identity holding the test Lease, if any.
+func leaseHolder(cl kubernetes.Interface) string {
+	lease, err := cl.CoordinationV1().Leases("webhook").Get(context.Background(), "bunny-leader", metav1.GetOptions{})
+	if err != nil || lease.Spec.HolderIdentity == nil {
+		return ""
+	}
+	return *lease.Spec.HolderIdentity
+}
+
+func TestStartBackgroundJobs_WithoutLeaderElection(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var started atomic.Int32
+	s := New(Options{})
+	s.AddBackgroundJob(BackgroundJob{Name: "test", Run: func(context.Context) { started.Add(1) }})
+
+	cl := fake.NewSimpleClientset()
+	require.NoError(t, s.startBackgroundJobs(ctx, cl))
+	assert.Eventually(t, func() bool { return started.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Empty(t, cl.Actions(), "no Lease is taken")
+}
+
+func TestStartBackgroundJobs_LeaderElection(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var running atomic.Bool
+	s := New(Options{LeaderElection: true, LeaderElectionID: "bunny-leader", Namespace: "webhook"})
+	s.AddBackgroundJob(BackgroundJob{Name: "test", Run: func(ctx context.Context) {
+		running.Store(true)
+		<-ctx.Done()
+		running.Store(false)
+	}})
+
+	cl := fake.NewSimpleClientset()
+	require.NoError(t, s.startBackgroundJobs(ctx, cl))
+	assert.Eventually(t, running.Load, 5*time.Second, 10*time.Millisecond)
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+	assert.Regexp(t, "^"+regexp.QuoteMeta(hostname)+"_[0-9a-f-]{36}$", leaseHolder(cl), "the identity is unique per process")
+
+	// Shutting down stops the jobs and releases the Lease for the next
+	// leader.
+	cancel()
+	assert.Eventually(t, func() bool { return !running.Load() && leaseHolder(cl) == "" }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestNewLeaderElector_SingleLeader(t *testing.T) {
+	cl := fake.NewSimpleClientset()
+	opts := Options{LeaderElectionID: "bunny-leader", Namespace: "webhook"}
+	var leading [2]atomic.Bool
+	elector := func(i int, id string) *leaderelection.LeaderElector {
+		elector, err := newLeaderElector(cl, id, opts, func(ctx context.Context) {
+			leading[i].Store(true)
+			<-ctx.Done()
+		})
+		require.NoError(t, err)
+		return elector
+	}
+
+	ctxA, cancelA := context.WithCancel(context.Background())
+	defer cancelA()
+	go elector(0, "replica-a").Run(ctxA)
+	require.Eventually(t, leading[0].Load, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "replica-a", leaseHolder(cl))
+
+	ctxB, cancelB := context.WithCancel(context.Background())
+	defer cancelB()
+	go elector(1, "replica-b").Run(ctxB)
+	time.Sleep(100 * time.Millisecond)
+	assert.False(t, leading[1].Load(), "only one replica leads at a time")
+
+	// The other replica takes over once the leader releases the Lease.
+	cancelA()
+	assert.Eventually(t, leading[1].Load, 10*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "replica-b", leaseHolder(cl))
+}
@@ -0,0 +1,52 @@
+package solver
+
+import (
+	"time"
+
+	"github.com/cert-manager/webhook-example/pkg/bunny"
+	"github.com/cert-manager/webhook-example/pkg/bunnylego"
+)
+
+const (
+	legoPropagationTimeout = 2 * time.Minute
+	legoPollingInterval    = 2 * time.Second
+)
+
+// DNSProvider implements the go-acme/lego challenge.Provider and
+// challenge.ProviderTimeout interfaces, so ACME clients outside Kubernetes
+// can solve DNS01 challenges for Bunny zones:
+//
+//	provider, err := solver.NewDNSProvider()
+//	...
+//	client.Challenge.SetDNS01Provider(provider)
+//
+// It is a bunnylego.DNSProvider whose requests go through the webhook's
+// retries and metrics.
+//
+// Deprecated: use package bunnylego, which doesn't pull in the webhook's
+// Kubernetes dependencies.
+type DNSProvider struct {
+	*bunnylego.DNSProvider
+}
+
+// NewDNSProvider returns a DNSProvider using the API key in BUNNY_API_KEY.
+func NewDNSProvider() (*DNSProvider, error) {
+	return NewDNSProviderWithKey(bunnylego.NewDefaultConfig().APIKey)
+}
+
+// NewDNSProviderWithKey returns a DNSProvider using apiKey.
+func NewDNSProviderWithKey(apiKey string) (*DNSProvider, error) {
+	cfg := bunnyNetDNSConfig{APIKey: apiKey}
+	p, err := bunnylego.NewDNSProviderConfig(&bunnylego.Config{
+		APIKey:             apiKey,
+		BaseURL:            cfg.apiBase(),
+		TTL:                recordTTL,
+		PropagationTimeout: legoPropagationTimeout,
+		PollingInterval:    legoPollingInterval,
+		Doer:               bunny.DoerFunc(cfg.do),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &DNSProvider{DNSProvider: p}, nil
+}
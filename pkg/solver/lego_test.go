@@ -0,0 +1,36 @@
+package solver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cert-manager/webhook-example/pkg/bunny/bunnytest"
+)
+
+func TestNewDNSProviderWithKey_Missing(t *testing.T) {
+	_, err := NewDNSProviderWithKey("")
+	assert.Error(t, err)
+}
+
+func TestDNSProvider(t *testing.T) {
+	api := bunnytest.NewServer()
+	defer api.Close()
+	api.RequireAPIKey("secret")
+	zone := api.AddZone("example.com")
+	bunnyAPIBase = api.URL
+	defer func() { bunnyAPIBase = DefaultAPIBase }()
+
+	d, err := NewDNSProviderWithKey("secret")
+	require.NoError(t, err)
+
+	require.NoError(t, d.Present("www.example.com", "", "one"))
+	records := api.Records(zone)
+	require.Len(t, records, 1)
+	assert.Equal(t, "_acme-challenge.www", records[0].Name)
+	assert.Equal(t, recordTTL, records[0].Ttl)
+
+	require.NoError(t, d.CleanUp("www.example.com", "", "one"))
+	assert.Empty(t, api.Records(zone))
+}
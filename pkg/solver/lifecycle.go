@@ -0,0 +1,156 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// defaultShutdownGracePeriod fits within Kubernetes' default termination
+// grace period of 30s.
+const defaultShutdownGracePeriod = 25 * time.Second
+
+// ErrShuttingDown is returned for challenges received after shutdown began.
+// cert-manager retries them, typically against another replica.
+var ErrShuttingDown = errors.New("webhook is shutting down")
+
+// lifecycle ties the solver's work to the stop channel passed to Initialize.
+// Informers are started on the channel itself; everything else — Present and
+// CleanUp with their propagation polls, background jobs and asynchronous
+// deliveries of events and notifications — runs under its context. Once the
+// channel closes, new challenges are refused and in-flight ones get a grace
+// period to finish before the context is cancelled. A solver that is never
+// initialized, as in the CLI, runs until the process exits.
+type lifecycle struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	once   sync.Once
+
+	mu       sync.Mutex
+	draining bool
+	inflight int
+	// idle is closed once draining with no operations in flight.
+	idle chan struct{}
+}
+
+func newLifecycle() *lifecycle {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &lifecycle{ctx: ctx, cancel: cancel, idle: make(chan struct{})}
+}
+
+// stopOn drains the lifecycle once stopCh closes, waiting at most grace for
+// in-flight operations. Only the first call has an effect.
+func (l *lifecycle) stopOn(stopCh <-chan struct{}, grace time.Duration) {
+	l.once.Do(func() {
+		go func() {
+			select {
+			case <-stopCh:
+				ctx, cancel := context.WithTimeout(context.Background(), grace)
+				defer cancel()
+				_ = l.drain(ctx)
+			case <-l.ctx.Done():
+			}
+		}()
+	})
+}
+
+// begin registers an operation, reporting false once draining began.
+func (l *lifecycle) begin() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.draining {
+		return false
+	}
+	l.inflight++
+	return true
+}
+
+func (l *lifecycle) end() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inflight--
+	if l.draining && l.inflight == 0 {
+		close(l.idle)
+	}
+}
+
+// drain refuses new operations and waits for in-flight ones to finish or ctx
+// to be done, whichever is first, then cancels the lifecycle's context.
+func (l *lifecycle) drain(ctx context.Context) error {
+	l.mu.Lock()
+	if !l.draining {
+		l.draining = true
+		if l.inflight == 0 {
+			close(l.idle)
+		}
+	}
+	l.mu.Unlock()
+
+	defer l.cancel()
+	select {
+	case <-l.idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *lifecycle) shuttingDown() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.draining
+}
+
+// context returns the context work should run under, cancelled on stop.
+func (c *Solver) context() context.Context {
+	if c.lifecycle == nil {
+		return context.Background()
+	}
+	return c.lifecycle.ctx
+}
+
+// admit registers a Present or CleanUp call with the lifecycle, failing with
+// ErrShuttingDown once shutdown began. release must be called when the call
+// returns.
+func (c *Solver) admit() (release func(), err error) {
+	if c.lifecycle != nil && !c.lifecycle.begin() {
+		return nil, ErrShuttingDown
+	}
+	operationsInFlight.Inc()
+	return func() {
+		operationsInFlight.Dec()
+		if c.lifecycle != nil {
+			c.lifecycle.end()
+		}
+	}, nil
+}
+
+// Shutdown stops accepting challenges and waits for in-flight Present and
+// CleanUp calls to return or ctx to be done, then stops background work.
+// Closing the stop channel passed to Initialize does the same, bounded by
+// Options.ShutdownGracePeriod.
+func (c *Solver) Shutdown(ctx context.Context) error {
+	if c.lifecycle == nil {
+		return nil
+	}
+	return c.lifecycle.drain(ctx)
+}
+
+// Done is closed once the solver stopped, i.e. after shutdown drained or
+// gave up on in-flight calls.
+func (c *Solver) Done() <-chan struct{} {
+	return c.context().Done()
+}
+
+// ShuttingDown reports whether Shutdown was called or the stop channel closed.
+func (c *Solver) ShuttingDown() bool {
+	return c.lifecycle != nil && c.lifecycle.shuttingDown()
+}
+
+func (c *Solver) shutdownGracePeriod() time.Duration {
+	if c.opts.ShutdownGracePeriod > 0 {
+		return c.opts.ShutdownGracePeriod
+	}
+	return defaultShutdownGracePeriod
+}
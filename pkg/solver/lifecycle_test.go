@@ -0,0 +1,75 @@
+package solver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLifecycleStopsPropagationPolls(t *testing.T) {
+	srv := fakeDoHServer(t)
+	defer srv.Close()
+
+	s := New(Options{DoHResolvers: []string{srv.URL}})
+	stopCh := make(chan struct{})
+	s.lifecycle.stopOn(stopCh, time.Millisecond)
+	require.NoError(t, s.context().Err())
+
+	done := make(chan error)
+	go func() {
+		done <- s.waitForPropagation(s.context(), "_acme-challenge.example.com.", "token", time.Minute, 10*time.Millisecond)
+	}()
+	close(stopCh)
+
+	select {
+	case err := <-done:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("propagation poll did not stop")
+	}
+	assert.Error(t, s.context().Err())
+}
+
+func TestLifecycleDrainsInFlightCalls(t *testing.T) {
+	s := New(Options{})
+	release, err := s.admit()
+	require.NoError(t, err)
+
+	stopCh := make(chan struct{})
+	s.lifecycle.stopOn(stopCh, time.Minute)
+	close(stopCh)
+	require.Eventually(t, s.ShuttingDown, 5*time.Second, 10*time.Millisecond)
+
+	err = s.Present(&v1alpha1.ChallengeRequest{ResolvedFQDN: "_acme-challenge.example.com.", ResolvedZone: "example.com.", Key: "token"})
+	assert.ErrorIs(t, err, ErrShuttingDown)
+	select {
+	case <-s.Done():
+		t.Fatal("solver stopped with a call in flight")
+	default:
+	}
+
+	release()
+	select {
+	case <-s.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("solver did not stop after the in-flight call returned")
+	}
+}
+
+func TestLifecycleGracePeriodExpires(t *testing.T) {
+	s := New(Options{})
+	_, err := s.admit()
+	require.NoError(t, err)
+
+	stopCh := make(chan struct{})
+	s.lifecycle.stopOn(stopCh, 10*time.Millisecond)
+	close(stopCh)
+	select {
+	case <-s.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("solver did not stop after the grace period")
+	}
+}
@@ -0,0 +1,34 @@
+package solver
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+type loggerKey struct{}
+
+// withChallengeLogger attaches a logger carrying the identifiers of ch to
+// ctx, so every message logged while handling ch can be attributed to it
+// among concurrent challenges.
+func withChallengeLogger(ctx context.Context, ch *v1alpha1.ChallengeRequest) context.Context {
+	return context.WithValue(ctx, loggerKey{}, challengeLogger(ch))
+}
+
+func challengeLogger(ch *v1alpha1.ChallengeRequest) *slog.Logger {
+	return slog.Default().With(
+		"uid", string(ch.UID),
+		"fqdn", ch.ResolvedFQDN,
+		"zone", ch.ResolvedZone,
+	)
+}
+
+// logger returns the logger of the challenge handled under ctx, or the
+// default logger outside of one.
+func logger(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
@@ -0,0 +1,39 @@
+package solver
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func TestChallengeIdentifiersLogged(t *testing.T) {
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	p := &recordingProvider{records: map[string]string{}}
+	s := New(Options{Providers: map[string]ProviderFactory{
+		"recording": func([]byte, SecretFunc) (Provider, error) { return p, nil },
+	}})
+	ch := &v1alpha1.ChallengeRequest{
+		UID:          "uid-1",
+		Key:          "token",
+		ResolvedFQDN: "_acme-challenge.example.com.",
+		ResolvedZone: "example.com.",
+		Config:       &apiextensionsv1.JSON{Raw: []byte(`{"provider":"recording","keepRecordsOnCleanup":true}`)},
+	}
+	require.NoError(t, s.CleanUp(ch))
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "Keeping TXT record for debugging, not deleting it", record["msg"])
+	assert.Equal(t, "uid-1", record["uid"])
+	assert.Equal(t, "_acme-challenge.example.com.", record["fqdn"])
+	assert.Equal(t, "example.com.", record["zone"])
+}
@@ -0,0 +1,106 @@
+package solver
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const metricsNamespace = "bunny_webhook"
+
+var deprecatedConfigTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: metricsNamespace,
+	Name:      "deprecated_config_total",
+	Help:      "Number of times a deprecated configuration option was used.",
+}, []string{"option"})
+
+var issuerConfigValid = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: metricsNamespace,
+	Name:      "issuer_config_valid",
+	Help:      "Whether the bunny-net solver config of an Issuer or ClusterIssuer passed validation (1) or not (0).",
+}, []string{"kind", "namespace", "name"})
+
+var operationsInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+	Namespace: metricsNamespace,
+	Name:      "operations_in_flight",
+	Help:      "Number of Present and CleanUp calls being handled.",
+})
+
+var apiRequestsInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+	Namespace: metricsNamespace,
+	Name:      "api_requests_in_flight",
+	Help:      "Number of Bunny API requests waiting for a response.",
+})
+
+var apiKeyFallbackTotal = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: metricsNamespace,
+	Name:      "api_key_fallback_total",
+	Help:      "Number of Bunny API requests retried with a fallback API key after a 401.",
+})
+
+// operationBuckets span quick API round trips up to Present calls waiting
+// minutes for propagation.
+var operationBuckets = prometheus.ExponentialBuckets(0.1, 2, 12)
+
+var presentTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: metricsNamespace,
+	Name:      "present_total",
+	Help:      "Number of Present calls, by result: success or the class of error.",
+}, []string{"result"})
+
+var cleanupTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: metricsNamespace,
+	Name:      "cleanup_total",
+	Help:      "Number of record deletions for CleanUp calls, by result: success or the class of error.",
+}, []string{"result"})
+
+var presentDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: metricsNamespace,
+	Name:      "present_duration_seconds",
+	Help:      "Duration of Present calls, including waiting for propagation, by result.",
+	Buckets:   operationBuckets,
+}, []string{"result"})
+
+var cleanupDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: metricsNamespace,
+	Name:      "cleanup_duration_seconds",
+	Help:      "Duration of record deletions for CleanUp calls, by result.",
+	Buckets:   operationBuckets,
+}, []string{"result"})
+
+// observeOperation records the outcome of a Present or CleanUp started at
+// start.
+func observeOperation(total *prometheus.CounterVec, duration *prometheus.HistogramVec, start time.Time, err error) {
+	result := "success"
+	if err != nil {
+		result = classifyError(err)
+	}
+	total.WithLabelValues(result).Inc()
+	duration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+}
+
+var apiRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: metricsNamespace,
+	Name:      "api_requests_total",
+	Help:      "Number of Bunny API requests, by method, endpoint and status code (\"none\" without a response).",
+}, []string{"method", "endpoint", "code"})
+
+var apiRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: metricsNamespace,
+	Name:      "api_request_duration_seconds",
+	Help:      "Duration of Bunny API requests, by method and endpoint.",
+	Buckets:   prometheus.DefBuckets,
+}, []string{"method", "endpoint"})
+
+// observeAPICall records a Bunny API request to endpoint; status is 0 if no
+// response was received.
+func observeAPICall(method, endpoint string, status int, duration time.Duration) {
+	code := "none"
+	if status != 0 {
+		code = strconv.Itoa(status)
+	}
+	apiRequestsTotal.WithLabelValues(method, endpoint, code).Inc()
+	apiRequestDuration.WithLabelValues(method, endpoint).Observe(duration.Seconds())
+}
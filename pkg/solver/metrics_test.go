@@ -0,0 +1,52 @@
+package solver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func TestOperationMetrics(t *testing.T) {
+	p := &recordingProvider{records: map[string]string{}}
+	s := New(Options{Providers: map[string]ProviderFactory{
+		"recording": func([]byte, SecretFunc) (Provider, error) { return p, nil },
+	}})
+	ch := &v1alpha1.ChallengeRequest{
+		Key:          "token",
+		ResolvedFQDN: "_acme-challenge.example.com.",
+		ResolvedZone: "example.com.",
+		Config:       &apiextensionsv1.JSON{Raw: []byte(`{"provider":"recording"}`)},
+	}
+
+	presented := testutil.ToFloat64(presentTotal.WithLabelValues("success"))
+	cleaned := testutil.ToFloat64(cleanupTotal.WithLabelValues("success"))
+	invalid := testutil.ToFloat64(presentTotal.WithLabelValues("config"))
+
+	require.NoError(t, s.Present(ch))
+	require.NoError(t, s.CleanUp(ch))
+	ch.Config.Raw = []byte(`{"provider":"unknown"}`)
+	require.Error(t, s.Present(ch))
+
+	assert.Equal(t, presented+1, testutil.ToFloat64(presentTotal.WithLabelValues("success")))
+	assert.Equal(t, cleaned+1, testutil.ToFloat64(cleanupTotal.WithLabelValues("success")))
+	assert.Equal(t, invalid+1, testutil.ToFloat64(presentTotal.WithLabelValues("config")))
+	assert.Positive(t, testutil.CollectAndCount(presentDuration))
+}
+
+func TestAPICallMetrics(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	notFound := apiRequestsTotal.WithLabelValues(http.MethodGet, "/dnszone/{id}", "404")
+	before := testutil.ToFloat64(notFound)
+	_, err := getZoneByID(context.Background(), bunnyNetDNSConfig{APIURL: srv.URL}, 42)
+	require.Error(t, err)
+	assert.Equal(t, before+1, testutil.ToFloat64(notFound))
+}
@@ -0,0 +1,39 @@
+package solver
+
+import (
+	"strings"
+
+	"github.com/cert-manager/webhook-example/pkg/bunny"
+)
+
+// Zone and record names reach the solver from cert-manager, Issuer configs,
+// the admin API and the Bunny API in varying shapes. canonicalName turns
+// names from outside into the form the solver works with; foldName and
+// withinZone compare names regardless of their shape.
+
+// canonicalName returns name without surrounding whitespace, lowercased,
+// in punycode and with a trailing dot. Record names relative to a zone are
+// derived with bunny.RecordName.
+func canonicalName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil
+	}
+	ascii, err := bunny.ToASCII(name)
+	if err != nil {
+		return "", err
+	}
+	return withTrailingDot(ascii), nil
+}
+
+// foldName returns name in a form for comparisons and map keys:
+// lowercased and without surrounding whitespace or the trailing dot.
+func foldName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(name), "."))
+}
+
+// withinZone reports whether name is zone or one of its subdomains.
+func withinZone(name, zone string) bool {
+	name, zone = foldName(name), foldName(zone)
+	return name == zone || strings.HasSuffix(name, "."+zone)
+}
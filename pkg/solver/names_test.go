@@ -0,0 +1,27 @@
+package solver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalName(t *testing.T) {
+	for in, want := range map[string]string{
+		"Example.COM":                    "example.com.",
+		" example.com.\n":                "example.com.",
+		"_acme-challenge.Bücher.example": "_acme-challenge.xn--bcher-kva.example.",
+		"":                               "",
+	} {
+		got, err := canonicalName(in)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, in)
+	}
+}
+
+func TestWithinZone(t *testing.T) {
+	assert.True(t, withinZone("_acme-challenge.WWW.example.com.", "Example.com"))
+	assert.True(t, withinZone("example.com", "example.com."))
+	assert.False(t, withinZone("_acme-challenge.badexample.com.", "example.com."))
+}
@@ -0,0 +1,182 @@
+package solver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const defaultNotifyAfterFailures = 3
+
+// Notification reports a domain whose challenges keep failing.
+type Notification struct {
+	Domain      string `json:"domain"`
+	Failures    int    `json:"failures"`
+	ErrorClass  string `json:"errorClass"`
+	Error       string `json:"error"`
+	Remediation string `json:"remediation"`
+}
+
+// Notifier delivers Notifications, e.g. to a chat channel.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// Error classes of a Notification, with the remediation suggested for them.
+var remediations = map[string]string{
+	"config":         "Fix the solver config of the Issuer referenced by the Certificate.",
+	"auth":           "Check that the Bunny API key is valid and has access to the DNS zone.",
+	"zone-not-found": "Create the DNS zone in Bunny or fix the Issuer's selector so the domain is solved by the right account.",
+	"network":        "Check egress from the webhook to the Bunny API (NetworkPolicies, proxies, DNS).",
+	"propagation":    "Check that the domain is delegated to Bunny's nameservers, or raise the propagation timeout.",
+	"api":            "Check the Bunny status page and the webhook logs; the API rejected or failed the request.",
+}
+
+// classifyError returns the error class of a failed Present.
+func classifyError(err error) string {
+	var fieldErr *configFieldError
+	var apiErr *APIError
+	var netErr net.Error
+	msg := err.Error()
+	switch {
+	case errors.As(err, &fieldErr), strings.Contains(msg, "failed to load config"):
+		return "config"
+	case errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden),
+		strings.Contains(msg, "status 401"), strings.Contains(msg, "status 403"):
+		return "auth"
+	case strings.Contains(msg, "no DNS zone found"):
+		return "zone-not-found"
+	case strings.Contains(msg, "did not propagate"):
+		return "propagation"
+	case errors.As(err, &netErr):
+		return "network"
+	default:
+		return "api"
+	}
+}
+
+// failureTracker counts consecutive Present failures per domain.
+type failureTracker struct {
+	mu       sync.Mutex
+	failures map[string]int
+}
+
+// failed records a failure and returns the number of consecutive failures.
+func (t *failureTracker) failed(domain string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.failures == nil {
+		t.failures = map[string]int{}
+	}
+	t.failures[domain]++
+	return t.failures[domain]
+}
+
+func (t *failureTracker) succeeded(domain string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.failures, domain)
+}
+
+// recordPresentResult notifies once a domain reaches the configured number
+// of consecutive failures. Notifications are sent in the background so a
+// slow receiver does not hold up cert-manager.
+func (c *Solver) recordPresentResult(domain string, err error) {
+	if c.opts.Notifier == nil {
+		return
+	}
+	if err == nil {
+		c.presentFailures.succeeded(domain)
+		return
+	}
+
+	threshold := c.opts.NotifyAfterFailures
+	if threshold <= 0 {
+		threshold = defaultNotifyAfterFailures
+	}
+	failures := c.presentFailures.failed(domain)
+	if failures != threshold {
+		return
+	}
+
+	class := classifyError(err)
+	n := Notification{
+		Domain:      domain,
+		Failures:    failures,
+		ErrorClass:  class,
+		Error:       err.Error(),
+		Remediation: remediations[class],
+	}
+	go func() {
+		if err := c.opts.Notifier.Notify(c.context(), n); err != nil {
+			slog.Error("failed to send failure notification", "domain", domain, "error", err)
+		}
+	}()
+}
+
+// WebhookNotifier POSTs Notifications as JSON to a URL.
+type WebhookNotifier struct {
+	URL string
+}
+
+func (w WebhookNotifier) Notify(ctx context.Context, n Notification) error {
+	return postJSON(ctx, w.URL, n)
+}
+
+// SlackNotifier posts Notifications to a Slack incoming webhook.
+type SlackNotifier struct {
+	URL string
+}
+
+func (s SlackNotifier) Notify(ctx context.Context, n Notification) error {
+	text := fmt.Sprintf(":warning: DNS01 challenges for *%s* failed %d times in a row (%s).\n>%s\n%s",
+		n.Domain, n.Failures, n.ErrorClass, n.Error, n.Remediation)
+	return postJSON(ctx, s.URL, map[string]string{"text": text})
+}
+
+// Notifiers sends to every notifier, returning the errors joined.
+type Notifiers []Notifier
+
+func (ns Notifiers) Notify(ctx context.Context, n Notification) error {
+	var errs []error
+	for _, notifier := range ns {
+		errs = append(errs, notifier.Notify(ctx, n))
+	}
+	return errors.Join(errs...)
+}
+
+func postJSON(ctx context.Context, url string, v interface{}) error {
+	return postJSONAs(ctx, url, "application/json", v)
+}
+
+// postJSONAs POSTs v as JSON with the given content type.
+func postJSONAs(ctx context.Context, url, contentType string, v interface{}) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned status %d: %s", url, resp.StatusCode, body)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type chanNotifier chan Notification
+
+func (c chanNotifier) Notify(_ context.Context, n Notification) error {
+	c <- n
+	return nil
+}
+
+func TestRecordPresentResult(t *testing.T) {
+	notifications := make(chanNotifier, 10)
+	s := New(Options{Notifier: notifications, NotifyAfterFailures: 2})
+	apiErr := errors.New("API request failed with status 401: unauthorized")
+
+	s.recordPresentResult("example.com", apiErr)
+	s.recordPresentResult("example.com", nil)
+	s.recordPresentResult("example.com", apiErr)
+	assert.Empty(t, notifications, "a success resets the count")
+
+	s.recordPresentResult("example.com", apiErr)
+	s.recordPresentResult("example.com", apiErr)
+
+	select {
+	case n := <-notifications:
+		assert.Equal(t, "example.com", n.Domain)
+		assert.Equal(t, 2, n.Failures)
+		assert.Equal(t, "auth", n.ErrorClass)
+		assert.NotEmpty(t, n.Remediation)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "no notification sent")
+	}
+	assert.Empty(t, notifications, "only the failure reaching the threshold notifies")
+}
+
+func TestClassifyError(t *testing.T) {
+	assert.Equal(t, "config", classifyError(fmt.Errorf("failed to load config: %w", &configFieldError{Field: "provider"})))
+	assert.Equal(t, "zone-not-found", classifyError(errors.New("failed to get zone ID: no DNS zone found for example.com")))
+	assert.Equal(t, "api", classifyError(errors.New("API request failed with status 500")))
+	assert.Equal(t, "auth", classifyError(fmt.Errorf("failed to update record 1: %w", &APIError{StatusCode: 401, ErrorKey: "authorization.invalid"})))
+}
@@ -0,0 +1,111 @@
+package solver
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	cmacme "github.com/cert-manager/cert-manager/pkg/apis/acme/v1"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Annotations on a Certificate that tune how its challenges are solved,
+// without needing a dedicated Issuer.
+const (
+	annotationTTL                = "webhook.bunny.net/ttl"
+	annotationPropagationTimeout = "webhook.bunny.net/propagation-timeout"
+)
+
+type certificateOverrides struct {
+	TTL                int
+	PropagationTimeout time.Duration
+}
+
+func parseOverrides(annotations map[string]string) (certificateOverrides, error) {
+	var ov certificateOverrides
+
+	if v, ok := annotations[annotationTTL]; ok {
+		ttl, err := strconv.Atoi(v)
+		if err != nil || ttl <= 0 {
+			return ov, fmt.Errorf("annotation %s must be a positive number of seconds, got %q", annotationTTL, v)
+		}
+		if err := ValidateRecordTTL(ttl); err != nil {
+			return ov, fmt.Errorf("annotation %s %w", annotationTTL, err)
+		}
+		ov.TTL = ttl
+	}
+
+	if v, ok := annotations[annotationPropagationTimeout]; ok {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return ov, fmt.Errorf("annotation %s must be a positive duration, got %q", annotationPropagationTimeout, v)
+		}
+		ov.PropagationTimeout = d
+	}
+
+	return ov, nil
+}
+
+// certificateOverrides returns the overrides set on the Certificate that
+// owns the challenge. The Challenge is owned by an Order, which is owned by
+// a CertificateRequest, which is owned by the Certificate.
+func (a *challengeAnnotator) certificateOverrides(ctx context.Context, uid types.UID) (certificateOverrides, error) {
+	ch := a.challenge(uid)
+	if ch == nil {
+		return certificateOverrides{}, nil
+	}
+
+	cert, err := a.owningCertificate(ctx, ch)
+	if err != nil || cert == nil {
+		return certificateOverrides{}, err
+	}
+
+	return parseOverrides(cert.Annotations)
+}
+
+// owningCertificate walks the owners of ch through the informer caches,
+// falling back to the API server for objects created since the last watch
+// event was processed.
+func (a *challengeAnnotator) owningCertificate(ctx context.Context, ch *cmacme.Challenge) (*cmapi.Certificate, error) {
+	orderRef := metav1.GetControllerOf(ch)
+	if orderRef == nil || orderRef.Kind != "Order" {
+		return nil, nil
+	}
+	order, err := cachedGet(ctx, orderRef.Name, a.orders.Orders(ch.Namespace).Get, a.client.AcmeV1().Orders(ch.Namespace).Get)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order %s/%s: %w", ch.Namespace, orderRef.Name, err)
+	}
+
+	crRef := metav1.GetControllerOf(order)
+	if crRef == nil || crRef.Kind != cmapi.CertificateRequestKind {
+		return nil, nil
+	}
+	cr, err := cachedGet(ctx, crRef.Name, a.certificateRequests.CertificateRequests(ch.Namespace).Get, a.client.CertmanagerV1().CertificateRequests(ch.Namespace).Get)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get certificaterequest %s/%s: %w", ch.Namespace, crRef.Name, err)
+	}
+
+	certRef := metav1.GetControllerOf(cr)
+	if certRef == nil || certRef.Kind != cmapi.CertificateKind {
+		return nil, nil
+	}
+	cert, err := cachedGet(ctx, certRef.Name, a.certificates.Certificates(ch.Namespace).Get, a.client.CertmanagerV1().Certificates(ch.Namespace).Get)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get certificate %s/%s: %w", ch.Namespace, certRef.Name, err)
+	}
+	return cert, nil
+}
+
+// cachedGet reads name from a lister, and from the API server if the
+// lister doesn't know it yet.
+func cachedGet[T any](ctx context.Context, name string, list func(string) (T, error), get func(context.Context, string, metav1.GetOptions) (T, error)) (T, error) {
+	obj, err := list(name)
+	if err == nil || !apierrors.IsNotFound(err) {
+		return obj, err
+	}
+	return get(ctx, name, metav1.GetOptions{})
+}
@@ -0,0 +1,85 @@
+package solver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cmacme "github.com/cert-manager/cert-manager/pkg/apis/acme/v1"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmfake "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned/fake"
+	cmacmelisters "github.com/cert-manager/cert-manager/pkg/client/listers/acme/v1"
+	cmlisters "github.com/cert-manager/cert-manager/pkg/client/listers/certmanager/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestParseOverrides(t *testing.T) {
+	ov, err := parseOverrides(nil)
+	require.NoError(t, err)
+	assert.Equal(t, certificateOverrides{}, ov)
+
+	ov, err = parseOverrides(map[string]string{
+		annotationTTL:                "120",
+		annotationPropagationTimeout: "5m",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, certificateOverrides{TTL: 120, PropagationTimeout: 5 * time.Minute}, ov)
+}
+
+func TestParseOverrides_Invalid(t *testing.T) {
+	for _, annotations := range []map[string]string{
+		{annotationTTL: "soon"},
+		{annotationTTL: "-1"},
+		{annotationPropagationTimeout: "5"},
+		{annotationPropagationTimeout: "-5m"},
+	} {
+		_, err := parseOverrides(annotations)
+		assert.Error(t, err, "expected %v to be rejected", annotations)
+	}
+}
+
+func TestCertificateOverrides(t *testing.T) {
+	isController := true
+	controller := func(kind, name string) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: &isController}}
+	}
+	ch := &cmacme.Challenge{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "ch", UID: "uid-1", OwnerReferences: controller("Order", "order")}}
+	order := &cmacme.Order{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "order", OwnerReferences: controller(cmapi.CertificateRequestKind, "cr")}}
+	cr := &cmapi.CertificateRequest{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "cr", OwnerReferences: controller(cmapi.CertificateKind, "www")}}
+	cert := &cmapi.Certificate{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "www", Annotations: map[string]string{annotationTTL: "120"}}}
+
+	challenges := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{challengeUIDIndex: indexChallengeByUID})
+	require.NoError(t, challenges.Add(ch))
+	indexer := func(objs ...interface{}) cache.Indexer {
+		indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+		for _, obj := range objs {
+			require.NoError(t, indexer.Add(obj))
+		}
+		return indexer
+	}
+	// The Certificate isn't cached yet and is read from the API server.
+	a := &challengeAnnotator{
+		client:              cmfake.NewSimpleClientset(cert),
+		indexer:             challenges,
+		orders:              cmacmelisters.NewOrderLister(indexer(order)),
+		certificateRequests: cmlisters.NewCertificateRequestLister(indexer(cr)),
+		certificates:        cmlisters.NewCertificateLister(indexer()),
+	}
+
+	ov, err := a.certificateOverrides(context.Background(), "uid-1")
+	require.NoError(t, err)
+	assert.Equal(t, certificateOverrides{TTL: 120}, ov)
+
+	ov, err = a.certificateOverrides(context.Background(), "unknown")
+	require.NoError(t, err)
+	assert.Equal(t, certificateOverrides{}, ov)
+
+	_, err = a.certificateOverrides(context.Background(), "uid-1")
+	require.NoError(t, err)
+	for _, action := range a.client.(*cmfake.Clientset).Actions() {
+		assert.Equal(t, "certificates", action.GetResource().Resource, "cached objects are not read from the API server")
+	}
+}
@@ -0,0 +1,77 @@
+package solver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	"github.com/cert-manager/cert-manager/pkg/issuer/acme/dns/util"
+
+	"github.com/cert-manager/webhook-example/pkg/bunny"
+)
+
+// PlannedChange is a DNS change the solver would make for one domain.
+type PlannedChange struct {
+	Domain     string `json:"domain"`
+	Action     string `json:"action"`
+	Zone       string `json:"zone"`
+	ZoneID     int64  `json:"zoneId,omitempty"`
+	RecordName string `json:"recordName"`
+	FQDN       string `json:"fqdn"`
+	Type       string `json:"type"`
+	TTL        int    `json:"ttl"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Plan returns the DNS changes that solving DNS01 challenges for domains
+// would make, without making them. The zone is resolved the same way
+// cert-manager does. With an API key the Bunny zone is looked up as well, so
+// a missing zone shows up in the plan. ttl overrides the default record TTL
+// when positive.
+func Plan(ctx context.Context, apiKey string, domains []string, ttl int) []PlannedChange {
+	if ttl <= 0 {
+		ttl = recordTTL
+	}
+
+	changes := make([]PlannedChange, 0, len(domains))
+	for _, domain := range domains {
+		fqdn := "_acme-challenge." + strings.TrimSuffix(strings.TrimPrefix(domain, "*."), ".") + "."
+		change := PlannedChange{
+			Domain: domain,
+			Action: "create",
+			FQDN:   fqdn,
+			Type:   "TXT",
+			TTL:    ttl,
+		}
+
+		zone, err := util.FindZoneByFqdn(ctx, fqdn, util.RecursiveNameservers)
+		if err != nil {
+			change.Error = fmt.Sprintf("failed to resolve zone: %v", err)
+			changes = append(changes, change)
+			continue
+		}
+		change.Zone = zone
+		change.RecordName = bunny.RecordName(zone, fqdn)
+
+		if apiKey != "" {
+			if zoneID, err := GetZoneID(ctx, zone, bunnyNetDNSConfig{APIKey: apiKey}); err != nil {
+				change.Error = err.Error()
+			} else {
+				change.ZoneID = zoneID
+			}
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// PlanCertificate plans the changes for every DNS name of cert, honouring
+// its TTL override annotation.
+func PlanCertificate(ctx context.Context, apiKey string, cert *cmapi.Certificate) ([]PlannedChange, error) {
+	overrides, err := parseOverrides(cert.Annotations)
+	if err != nil {
+		return nil, err
+	}
+	return Plan(ctx, apiKey, cert.Spec.DNSNames, overrides.TTL), nil
+}
@@ -0,0 +1,96 @@
+package solver
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+
+	"github.com/cert-manager/webhook-example/pkg/bunny"
+	"github.com/cert-manager/webhook-example/pkg/bunny/bunnytest"
+)
+
+func fakeChallenge(api *bunnytest.Server, key string) *v1alpha1.ChallengeRequest {
+	return &v1alpha1.ChallengeRequest{
+		ResolvedFQDN:            "_acme-challenge.example.com.",
+		ResolvedZone:            "example.com.",
+		Key:                     key,
+		AllowAmbientCredentials: true,
+		Config:                  &apiextensionsv1.JSON{Raw: []byte(fmt.Sprintf(`{"apiURL":%q}`, api.URL))},
+	}
+}
+
+func challengeValues(records []bunny.Record) []string {
+	var values []string
+	for _, r := range records {
+		if r.Type == bunny.RecordTypeTXT && r.Name == "_acme-challenge" {
+			values = append(values, r.Value)
+		}
+	}
+	return values
+}
+
+func TestPresentCleanUp_FakeAPI(t *testing.T) {
+	api := bunnytest.NewServer()
+	defer api.Close()
+	api.RequireAPIKey("secret")
+	// Enough decoys that the zone search has to page to find the zone.
+	for i := 0; i < bunny.ZoneSearchPageSize+20; i++ {
+		api.AddZone(fmt.Sprintf("shop%d-example.com", i))
+	}
+	zone := api.AddZone("example.com")
+	api.AddRecord(zone, bunny.Record{Type: bunny.RecordTypeA, Name: "", Value: "192.0.2.1"})
+
+	s := New(Options{APIKey: "secret", APIBase: api.URL})
+
+	require.NoError(t, s.Present(fakeChallenge(api, "one")))
+	require.NoError(t, s.Present(fakeChallenge(api, "one")), "a repeated Present must not fail")
+	require.NoError(t, s.Present(fakeChallenge(api, "two")))
+	assert.ElementsMatch(t, []string{"one", "two"}, challengeValues(api.Records(zone)))
+
+	require.NoError(t, s.CleanUp(fakeChallenge(api, "one")))
+	assert.Equal(t, []string{"two"}, challengeValues(api.Records(zone)))
+	require.NoError(t, s.CleanUp(fakeChallenge(api, "one")), "cleaning up twice must not fail")
+	require.NoError(t, s.CleanUp(fakeChallenge(api, "two")))
+	assert.Empty(t, challengeValues(api.Records(zone)))
+	assert.Len(t, api.Records(zone), 1, "unrelated records must be kept")
+}
+
+func TestPresent_FakeAPIErrors(t *testing.T) {
+	api := bunnytest.NewServer()
+	defer api.Close()
+	api.RequireAPIKey("secret")
+	zone := api.AddZone("example.com")
+
+	err := New(Options{APIKey: "wrong", APIBase: api.URL}).Present(fakeChallenge(api, "token"))
+	var apiErr *bunny.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+
+	api.FailNext(1, http.StatusBadRequest, "dnszone.record.invalid")
+	assert.Error(t, New(Options{APIKey: "secret", APIBase: api.URL}).Present(fakeChallenge(api, "token")))
+	assert.Empty(t, challengeValues(api.Records(zone)))
+}
+
+func TestSolver_NameAndAPIBase(t *testing.T) {
+	assert.Equal(t, DefaultName, New(Options{}).Name())
+
+	api := bunnytest.NewServer()
+	defer api.Close()
+	api.RequireAPIKey("staging")
+	zone := api.AddZone("example.com")
+
+	s := New(Options{Name: "bunny-net-staging", APIKey: "staging", APIBase: api.URL})
+	assert.Equal(t, "bunny-net-staging", s.Name())
+	require.NoError(t, s.Present(&v1alpha1.ChallengeRequest{
+		ResolvedFQDN:            "_acme-challenge.example.com.",
+		ResolvedZone:            "example.com.",
+		Key:                     "token",
+		AllowAmbientCredentials: true,
+	}))
+	assert.Equal(t, []string{"token"}, challengeValues(api.Records(zone)))
+}
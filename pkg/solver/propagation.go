@@ -0,0 +1,204 @@
+package solver
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	defaultPropagationTimeout      = 2 * time.Minute
+	defaultPropagationPollInterval = 5 * time.Second
+
+	dohMediaType = "application/dns-message"
+)
+
+// bunnyNameservers are the authoritative nameservers of every Bunny DNS
+// zone.
+var bunnyNameservers = []string{"kiki.bunny.net:53", "coco.bunny.net:53"}
+
+// propagationCheck is a place a new record must be visible at before
+// Present returns.
+type propagationCheck struct {
+	name   string
+	lookup func(ctx context.Context, fqdn string) ([]string, error)
+}
+
+// propagationChecks returns the configured DoH resolvers and nameservers.
+// VerifyPropagation without nameservers checks Bunny's authoritative ones.
+func (c *Solver) propagationChecks() []propagationCheck {
+	var checks []propagationCheck
+	for _, url := range c.opts.DoHResolvers {
+		url := url
+		checks = append(checks, propagationCheck{name: url, lookup: func(ctx context.Context, fqdn string) ([]string, error) {
+			return dohLookupTXT(ctx, url, fqdn)
+		}})
+	}
+
+	// Configured nameservers may be recursors, which need to be asked for
+	// recursion; Bunny's own are only asked for their zones.
+	servers, recursive := c.opts.PropagationNameservers, true
+	if len(servers) == 0 && c.opts.VerifyPropagation {
+		servers, recursive = bunnyNameservers, false
+	}
+	for _, server := range servers {
+		server := withDefaultPort(server, "53")
+		checks = append(checks, propagationCheck{name: server, lookup: func(ctx context.Context, fqdn string) ([]string, error) {
+			return dnsLookupTXT(ctx, server, fqdn, recursive)
+		}})
+	}
+	return checks
+}
+
+// withDefaultPort appends port to addr unless it has one.
+func withDefaultPort(addr, port string) string {
+	if _, _, err := net.SplitHostPort(addr); err == nil {
+		return addr
+	}
+	return net.JoinHostPort(strings.Trim(addr, "[]"), port)
+}
+
+// waitForPropagation blocks until every propagation check returns value for
+// the TXT record fqdn, polling every interval, or timeout passes. Zero
+// durations use the solver's options. It does nothing without checks.
+func (c *Solver) waitForPropagation(ctx context.Context, fqdn, value string, timeout, interval time.Duration) (err error) {
+	pending := c.propagationChecks()
+	if len(pending) == 0 {
+		return nil
+	}
+	ctx, end := startSpan(ctx, "propagation.wait", attribute.String("dns.fqdn", fqdn), attribute.StringSlice("propagation.checks", checkNames(pending)))
+	defer func() { end(err) }()
+	if timeout <= 0 {
+		timeout = c.opts.PropagationTimeout
+	}
+	if timeout <= 0 {
+		timeout = defaultPropagationTimeout
+	}
+	if interval <= 0 {
+		interval = c.opts.PropagationPollInterval
+	}
+	if interval <= 0 {
+		interval = defaultPropagationPollInterval
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		var lastErr error
+		remaining := pending[:0]
+		for _, check := range pending {
+			values, err := check.lookup(ctx, fqdn)
+			if err != nil {
+				lastErr = err
+			}
+			if !slices.Contains(values, value) {
+				remaining = append(remaining, check)
+			}
+		}
+		pending = remaining
+		if len(pending) == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if lastErr != nil {
+				return fmt.Errorf("record %s did not propagate to %v within %s: %w", fqdn, checkNames(pending), timeout, lastErr)
+			}
+			return fmt.Errorf("record %s did not propagate to %v within %s", fqdn, checkNames(pending), timeout)
+		case <-time.After(interval):
+			trace.SpanFromContext(ctx).AddEvent("poll", trace.WithAttributes(attribute.StringSlice("propagation.pending", checkNames(pending))))
+			logger(ctx).Debug("Waiting for propagation", "record", fqdn, "pending", checkNames(pending))
+		}
+	}
+}
+
+func checkNames(checks []propagationCheck) []string {
+	names := make([]string, 0, len(checks))
+	for _, check := range checks {
+		names = append(names, check.name)
+	}
+	return names
+}
+
+// dnsLookupTXT asks the nameserver at server (host:port) for the TXT
+// records of fqdn, retrying over TCP if the answer is truncated.
+func dnsLookupTXT(ctx context.Context, server, fqdn string, recursive bool) ([]string, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(fqdn), dns.TypeTXT)
+	msg.RecursionDesired = recursive
+
+	answer, _, err := new(dns.Client).ExchangeContext(ctx, msg, server)
+	if err == nil && answer.Truncated {
+		answer, _, err = (&dns.Client{Net: "tcp"}).ExchangeContext(ctx, msg, server)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", server, err)
+	}
+	return txtValues(server, answer)
+}
+
+// dohLookupTXT queries the DNS-over-HTTPS resolver at url (RFC 8484) for the
+// TXT records of fqdn.
+func dohLookupTXT(ctx context.Context, url, fqdn string) ([]string, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(fqdn), dns.TypeTXT)
+	// RFC 8484 asks for ID 0 so responses are cacheable.
+	msg.Id = 0
+	packed, err := msg.Pack()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack DNS query: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+"?dns="+base64.RawURLEncoding.EncodeToString(packed), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", dohMediaType)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response from %s: %w", url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned status %d: %s", url, resp.StatusCode, bytes.TrimSpace(body))
+	}
+
+	answer := new(dns.Msg)
+	if err := answer.Unpack(body); err != nil {
+		return nil, fmt.Errorf("failed to unpack response from %s: %w", url, err)
+	}
+	return txtValues(url, answer)
+}
+
+// txtValues returns the TXT values in the answer from server.
+func txtValues(server string, answer *dns.Msg) ([]string, error) {
+	if answer.Rcode != dns.RcodeSuccess && answer.Rcode != dns.RcodeNameError {
+		return nil, fmt.Errorf("%s answered %s", server, dns.RcodeToString[answer.Rcode])
+	}
+
+	var values []string
+	for _, rr := range answer.Answer {
+		if txt, ok := rr.(*dns.TXT); ok {
+			values = append(values, strings.Join(txt.Txt, ""))
+		}
+	}
+	return values, nil
+}
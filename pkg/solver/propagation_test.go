@@ -0,0 +1,123 @@
+package solver
+
+import (
+	"context"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDoHServer answers TXT queries with the given values.
+func fakeDoHServer(t *testing.T, values ...string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, dohMediaType, r.Header.Get("Accept"))
+		packed, err := base64.RawURLEncoding.DecodeString(r.URL.Query().Get("dns"))
+		require.NoError(t, err)
+		query := new(dns.Msg)
+		require.NoError(t, query.Unpack(packed))
+
+		answer := new(dns.Msg)
+		answer.SetReply(query)
+		for _, v := range values {
+			answer.Answer = append(answer.Answer, &dns.TXT{
+				Hdr: dns.RR_Header{Name: query.Question[0].Name, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 10},
+				Txt: []string{v},
+			})
+		}
+		out, err := answer.Pack()
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", dohMediaType)
+		_, _ = w.Write(out)
+	}))
+}
+
+func TestDoHLookupTXT(t *testing.T) {
+	srv := fakeDoHServer(t, "token-1", "token-2")
+	defer srv.Close()
+
+	values, err := dohLookupTXT(context.Background(), srv.URL, "_acme-challenge.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"token-1", "token-2"}, values)
+}
+
+func TestWaitForPropagation(t *testing.T) {
+	srv := fakeDoHServer(t, "token")
+	defer srv.Close()
+
+	s := New(Options{DoHResolvers: []string{srv.URL}})
+	assert.NoError(t, s.waitForPropagation(context.Background(), "_acme-challenge.example.com.", "token", time.Second, 0))
+	assert.Error(t, s.waitForPropagation(context.Background(), "_acme-challenge.example.com.", "other", 100*time.Millisecond, 0))
+}
+
+// fakeNameserver serves the TXT record values over UDP on a local port.
+func fakeNameserver(t *testing.T, values ...string) string {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := &dns.Server{PacketConn: pc, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, query *dns.Msg) {
+		assert.False(t, query.RecursionDesired)
+		answer := new(dns.Msg)
+		answer.SetReply(query)
+		answer.Authoritative = true
+		for _, v := range values {
+			answer.Answer = append(answer.Answer, &dns.TXT{
+				Hdr: dns.RR_Header{Name: query.Question[0].Name, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 10},
+				Txt: []string{v},
+			})
+		}
+		_ = w.WriteMsg(answer)
+	})}
+	go func() { _ = srv.ActivateAndServe() }()
+	t.Cleanup(func() { _ = srv.Shutdown() })
+	return pc.LocalAddr().String()
+}
+
+func TestWaitForPropagation_Authoritative(t *testing.T) {
+	servers := bunnyNameservers
+	defer func() { bunnyNameservers = servers }()
+	bunnyNameservers = []string{fakeNameserver(t, "token"), fakeNameserver(t, "token", "other")}
+
+	s := New(Options{VerifyPropagation: true})
+	assert.NoError(t, s.waitForPropagation(context.Background(), "_acme-challenge.example.com.", "token", time.Second, 0))
+
+	err := s.waitForPropagation(context.Background(), "_acme-challenge.example.com.", "other", 100*time.Millisecond, 10*time.Millisecond)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), bunnyNameservers[0])
+	assert.NotContains(t, err.Error(), bunnyNameservers[1])
+}
+
+func TestWaitForPropagation_CustomNameservers(t *testing.T) {
+	var recursive atomic.Bool
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := &dns.Server{PacketConn: pc, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, query *dns.Msg) {
+		recursive.Store(query.RecursionDesired)
+		answer := new(dns.Msg)
+		answer.SetReply(query)
+		answer.Answer = append(answer.Answer, &dns.TXT{
+			Hdr: dns.RR_Header{Name: query.Question[0].Name, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 10},
+			Txt: []string{"token"},
+		})
+		_ = w.WriteMsg(answer)
+	})}
+	go func() { _ = srv.ActivateAndServe() }()
+	defer func() { _ = srv.Shutdown() }()
+
+	s := New(Options{PropagationNameservers: []string{pc.LocalAddr().String()}})
+	require.Len(t, s.propagationChecks(), 1, "custom nameservers replace Bunny's")
+	assert.NoError(t, s.waitForPropagation(context.Background(), "_acme-challenge.example.com.", "token", time.Second, 0))
+	assert.True(t, recursive.Load(), "custom nameservers may be recursors")
+}
+
+func TestWithDefaultPort(t *testing.T) {
+	assert.Equal(t, "1.1.1.1:53", withDefaultPort("1.1.1.1", "53"))
+	assert.Equal(t, "ns.example.com:5353", withDefaultPort("ns.example.com:5353", "53"))
+	assert.Equal(t, "[2606:4700:4700::1111]:53", withDefaultPort("2606:4700:4700::1111", "53"))
+}
@@ -0,0 +1,236 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/cert-manager/webhook-example/pkg/bunny"
+)
+
+// bunnyProviderName is the built-in provider, used when an Issuer's config
+// names none.
+const bunnyProviderName = "bunny"
+
+// Provider publishes challenge TXT records in a DNS backend. zone and fqdn
+// are fully qualified.
+type Provider interface {
+	Present(ctx context.Context, zone, fqdn, value string, ttl int) error
+	CleanUp(ctx context.Context, zone, fqdn, value string) error
+}
+
+// SecretFunc returns the value of key in the named Secret of the challenge's
+// resource namespace.
+type SecretFunc func(name, key string) (string, error)
+
+// ProviderFactory returns the Provider for an Issuer whose config selects it.
+// config is the Issuer's providerConfig, which the factory decodes itself.
+type ProviderFactory func(config []byte, secret SecretFunc) (Provider, error)
+
+// bunnyProvider is the built-in Provider backed by the Bunny API. created,
+// if set, is called with every record Present creates. active, if set,
+// reports values of challenges still in progress, in any replica, which
+// upserts must not overwrite.
+type bunnyProvider struct {
+	cfg     bunnyNetDNSConfig
+	created func(zoneID int64, record Record)
+	active  func(fqdn, value string) bool
+}
+
+func (p *bunnyProvider) Present(ctx context.Context, zone, fqdn, value string, ttl int) error {
+	var item Item
+	var err error
+	if p.cfg.ZoneID != 0 {
+		if zone, item, err = p.pinnedZone(ctx, zone, fqdn); err != nil {
+			return err
+		}
+	} else {
+		if zone, _, err = hostedZone(ctx, p.cfg, zone, fqdn); err != nil {
+			return fmt.Errorf("failed to get zone ID: %w", err)
+		}
+		if item, err = getZoneRecords(ctx, p.cfg, zone); err != nil {
+			return err
+		}
+	}
+	zoneID := int64(item.ID)
+
+	// cert-manager retries Present after timeouts and restarts; the record
+	// may already be there.
+	if existing, ok := bunny.FindTXTRecord(item.Records, zone, fqdn, value); ok {
+		logger(ctx).Info("TXT record already exists", "record", fqdn)
+		if p.created != nil {
+			p.created(zoneID, existing)
+		}
+		return nil
+	}
+
+	if p.cfg.Upsert {
+		if stale, ok := p.staleRecord(item, zone, fqdn); ok {
+			logger(ctx).Info("Updating stale TXT record", "record", fqdn, "recordID", stale.ID)
+			record, err := updateTXTRecord(ctx, p.cfg, zoneID, stale.ID, zone, fqdn, value, ttl)
+			if err != nil {
+				return err
+			}
+			if p.created != nil {
+				p.created(zoneID, record)
+			}
+			return nil
+		}
+	}
+
+	record, err := createTXTRecord(ctx, p.cfg, zoneID, zone, fqdn, value, ttl)
+	if err != nil {
+		// The cached ID may belong to a deleted zone.
+		p.cfg.zones.forget(p.cfg, zone)
+		return err
+	}
+	if p.created != nil {
+		p.created(zoneID, record)
+	}
+	return nil
+}
+
+func (p *bunnyProvider) CleanUp(ctx context.Context, zone, fqdn, value string) error {
+	if p.cfg.ZoneID != 0 {
+		zone, item, err := p.pinnedZone(ctx, zone, fqdn)
+		if err != nil {
+			return err
+		}
+		return deleteTXTRecordIn(ctx, p.cfg, item, zone, fqdn, value)
+	}
+	zone, _, err := hostedZone(ctx, p.cfg, zone, fqdn)
+	if err != nil {
+		return fmt.Errorf("failed to get zone ID: %w", err)
+	}
+	return deleteTXTRecord(ctx, p.cfg, zone, fqdn, value)
+}
+
+// staleRecord returns a TXT record for fqdn the solver created whose value
+// doesn't belong to a challenge in progress, e.g. one left behind by a
+// failed CleanUp. Records created by anything else are never reused.
+func (p *bunnyProvider) staleRecord(item Item, zone, fqdn string) (Record, bool) {
+	name := bunny.RecordName(zone, fqdn)
+	for _, record := range item.Records {
+		if record.Type != recordType || !bunny.SameRecordName(record.Name, name) || !ownedRecord(record) {
+			continue
+		}
+		if p.active != nil && p.active(fqdn, record.Value) {
+			continue
+		}
+		return record, true
+	}
+	return Record{}, false
+}
+
+// pinnedZone fetches the zone the Issuer pinned with zoneID, skipping
+// discovery, and returns its name, which fqdn must lie within.
+func (p *bunnyProvider) pinnedZone(ctx context.Context, zone, fqdn string) (_ string, _ Item, err error) {
+	ctx, end := startSpan(ctx, "bunny.zone.lookup", attribute.String("dns.fqdn", fqdn), attribute.Int64("bunny.zone_id", p.cfg.ZoneID))
+	defer func() { end(err) }()
+	item, err := getZoneByID(ctx, p.cfg, p.cfg.ZoneID)
+	if err != nil {
+		return "", Item{}, fmt.Errorf("failed to get pinned zone: %w", err)
+	}
+	zone = withTrailingDot(item.Domain)
+	if !withinZone(fqdn, zone) {
+		return "", Item{}, &configFieldError{Field: "zoneID", Reason: fmt.Sprintf("zone %d is %s, which does not contain %s", item.ID, zone, fqdn)}
+	}
+	return zone, item, nil
+}
+
+// mirroredProvider publishes records in two accounts hosting the same
+// zones. Present fails if the primary account doesn't have the record, which
+// is the one the solver checks and cleans up against; a failure to mirror
+// it to the secondary is only logged. CleanUp removes it from both.
+type mirroredProvider struct {
+	primary, secondary Provider
+}
+
+func (p *mirroredProvider) Present(ctx context.Context, zone, fqdn, value string, ttl int) error {
+	primaryErr := p.primary.Present(ctx, zone, fqdn, value, ttl)
+	secondaryErr := p.secondary.Present(ctx, zone, fqdn, value, ttl)
+	switch {
+	case primaryErr != nil && secondaryErr != nil:
+		return fmt.Errorf("%w; secondary account also failed: %v", primaryErr, secondaryErr)
+	case primaryErr != nil:
+		return fmt.Errorf("%w; the record was only created in the secondary account", primaryErr)
+	case secondaryErr != nil:
+		logger(ctx).Warn("failed to mirror to the secondary account", "record", fqdn, "error", secondaryErr)
+	}
+	return nil
+}
+
+func (p *mirroredProvider) CleanUp(ctx context.Context, zone, fqdn, value string) error {
+	primaryErr := p.primary.CleanUp(ctx, zone, fqdn, value)
+	if err := p.secondary.CleanUp(ctx, zone, fqdn, value); err != nil {
+		return errors.Join(primaryErr, fmt.Errorf("secondary account: %w", err))
+	}
+	return primaryErr
+}
+
+// isBunny reports whether cfg uses the built-in Bunny provider.
+func (cfg bunnyNetDNSConfig) isBunny() bool {
+	return cfg.Provider == "" || cfg.Provider == bunnyProviderName
+}
+
+// provider returns the Provider selected by cfg for the challenge.
+func (c *Solver) provider(ctx context.Context, ch *v1alpha1.ChallengeRequest, cfg bunnyNetDNSConfig) (Provider, error) {
+	if cfg.isBunny() {
+		primary := &bunnyProvider{
+			cfg: cfg,
+			created: func(zoneID int64, record Record) {
+				c.annotator.annotate(ctx, ch.UID, zoneID, record.ID)
+			},
+			active: c.challengeInProgress,
+		}
+		// The secondary account mirrors the webhook-wide account. Keys of
+		// other accounts, from Issuers or bindings, aren't mirrored.
+		if c.opts.SecondaryAPIKey == "" || cfg.keySource != keyAmbient || !ch.AllowAmbientCredentials {
+			return primary, nil
+		}
+		secondary := &bunnyProvider{cfg: bunnyNetDNSConfig{
+			APIKey:          c.opts.SecondaryAPIKey,
+			AuthScheme:      c.opts.AuthScheme,
+			instrumentation: cfg.instrumentation,
+			retry:           cfg.retry,
+			limiter:         cfg.limiter,
+			breakers:        cfg.breakers,
+			zones:           cfg.zones,
+		}}
+		return &mirroredProvider{primary: primary, secondary: secondary}, nil
+	}
+
+	// loadConfig only accepts registered providers.
+	p, err := c.opts.Providers[cfg.Provider](cfg.ProviderConfig, func(name, key string) (string, error) {
+		return c.secretValue(ctx, ch.ResourceNamespace, name, key)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s provider: %w", cfg.Provider, err)
+	}
+	return p, nil
+}
+
+// secretValue returns the trimmed value of key in a Secret.
+func (c *Solver) secretValue(ctx context.Context, namespace, name, key string) (string, error) {
+	if namespace == "" {
+		return "", errors.New("challenge has no resource namespace to read secrets from")
+	}
+	if c.client == nil {
+		return "", errors.New("kubernetes client is not initialized")
+	}
+
+	c.usedSecrets.Store(namespace+"/"+name, struct{}{})
+	secret, err := c.getSecret(ctx, namespace, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to get secret %s/%s: %w", namespace, name, err)
+	}
+	data, ok := secret.Data[key]
+	if !ok {
+		return "", fmt.Errorf("key %q not found in secret %s/%s", key, namespace, name)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
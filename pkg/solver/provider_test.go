@@ -0,0 +1,282 @@
+package solver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+type recordingProvider struct {
+	config  string
+	records map[string]string
+}
+
+func (p *recordingProvider) Present(_ context.Context, zone, fqdn, value string, ttl int) error {
+	p.records[fqdn] = value
+	return nil
+}
+
+func (p *recordingProvider) CleanUp(_ context.Context, zone, fqdn, value string) error {
+	delete(p.records, fqdn)
+	return nil
+}
+
+func TestSolver_Providers(t *testing.T) {
+	p := &recordingProvider{records: map[string]string{}}
+	s := New(Options{Providers: map[string]ProviderFactory{
+		"recording": func(config []byte, _ SecretFunc) (Provider, error) {
+			p.config = string(config)
+			return p, nil
+		},
+	}})
+
+	ch := &v1alpha1.ChallengeRequest{
+		Key:          "token",
+		ResolvedFQDN: "_acme-challenge.example.com.",
+		ResolvedZone: "example.com.",
+		Config:       &apiextensionsv1.JSON{Raw: []byte(`{"provider":"recording","providerConfig":{"zone":"example"}}`)},
+	}
+	require.NoError(t, s.Present(ch))
+	assert.Equal(t, "token", p.records["_acme-challenge.example.com."])
+	assert.JSONEq(t, `{"zone":"example"}`, p.config)
+
+	require.NoError(t, s.CleanUp(ch))
+	assert.Empty(t, p.records)
+
+	ch.Config.Raw = []byte(`{"provider":"unknown"}`)
+	var fieldErr *configFieldError
+	assert.True(t, errors.As(s.Present(ch), &fieldErr))
+}
+
+func TestSolver_KeepRecordsOnCleanup(t *testing.T) {
+	p := &recordingProvider{records: map[string]string{}}
+	s := New(Options{Providers: map[string]ProviderFactory{
+		"recording": func([]byte, SecretFunc) (Provider, error) { return p, nil },
+	}})
+
+	ch := &v1alpha1.ChallengeRequest{
+		Key:          "token",
+		ResolvedFQDN: "_acme-challenge.example.com.",
+		ResolvedZone: "example.com.",
+		Config:       &apiextensionsv1.JSON{Raw: []byte(`{"provider":"recording","keepRecordsOnCleanup":true}`)},
+	}
+	require.NoError(t, s.Present(ch))
+	require.NoError(t, s.CleanUp(ch))
+	assert.Equal(t, "token", p.records["_acme-challenge.example.com."])
+	assert.False(t, s.recordActive(ch.ResolvedFQDN, ch.Key))
+}
+
+type failingProvider struct{ err error }
+
+func (p failingProvider) Present(context.Context, string, string, string, int) error { return p.err }
+
+func (p failingProvider) CleanUp(context.Context, string, string, string) error { return p.err }
+
+func TestMirroredProvider(t *testing.T) {
+	ok := &recordingProvider{records: map[string]string{}}
+	down := failingProvider{err: errors.New("status 500")}
+	ctx := context.Background()
+
+	assert.NoError(t, (&mirroredProvider{primary: ok, secondary: down}).Present(ctx, "example.com.", "_acme-challenge.example.com.", "token", 10))
+	assert.Equal(t, "token", ok.records["_acme-challenge.example.com."])
+	assert.Error(t, (&mirroredProvider{primary: down, secondary: ok}).Present(ctx, "example.com.", "_acme-challenge.example.com.", "token", 10),
+		"a record in the secondary account only doesn't count")
+	assert.Error(t, (&mirroredProvider{primary: down, secondary: down}).Present(ctx, "example.com.", "_acme-challenge.example.com.", "token", 10))
+
+	assert.Error(t, (&mirroredProvider{primary: ok, secondary: down}).CleanUp(ctx, "example.com.", "_acme-challenge.example.com.", "token"))
+	assert.Empty(t, ok.records, "the primary is cleaned up even if the secondary fails")
+}
+
+func TestSolver_MirrorsAmbientKeyOnly(t *testing.T) {
+	s := New(Options{APIKey: "ambient", SecondaryAPIKey: "secondary"})
+	ch := &v1alpha1.ChallengeRequest{AllowAmbientCredentials: true}
+
+	p, err := s.provider(context.Background(), ch, bunnyNetDNSConfig{APIKey: "ambient", keySource: keyAmbient})
+	require.NoError(t, err)
+	assert.IsType(t, &mirroredProvider{}, p)
+
+	for _, source := range []keySource{keyFromIssuer, keyFromBinding} {
+		p, err = s.provider(context.Background(), ch, bunnyNetDNSConfig{APIKey: "other", keySource: source})
+		require.NoError(t, err)
+		assert.IsType(t, &bunnyProvider{}, p, "keys of other accounts must not be mirrored")
+	}
+}
+
+func TestBunnyProvider_PinnedZone(t *testing.T) {
+	var requests []string
+	records := `[]`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/dnszone/42":
+			fmt.Fprintf(w, `{"Id":42,"Domain":"acme.example.net","Records":%s}`, records)
+		case r.Method == http.MethodPut:
+			records = `[{"Id":9,"Type":3,"Name":"www.example.com","Value":"token"}]`
+			fmt.Fprint(w, `{"Id":9}`)
+		}
+	}))
+	defer srv.Close()
+
+	p := &bunnyProvider{cfg: bunnyNetDNSConfig{APIURL: srv.URL, ZoneID: 42}}
+	require.NoError(t, p.Present(context.Background(), "example.com.", "www.example.com.acme.example.net.", "token", 60))
+	require.NoError(t, p.CleanUp(context.Background(), "example.com.", "www.example.com.acme.example.net.", "token"))
+	assert.Equal(t, []string{
+		"GET /dnszone/42", "PUT /dnszone/42/records",
+		"GET /dnszone/42", "DELETE /dnszone/42/records/9",
+	}, requests, "the zone must not be searched for")
+
+	var fieldErr *configFieldError
+	err := p.Present(context.Background(), "example.com.", "_acme-challenge.example.com.", "token", 60)
+	require.ErrorAs(t, err, &fieldErr)
+	assert.Equal(t, "zoneID", fieldErr.Field)
+}
+
+func TestBunnyProvider_PresentIsIdempotent(t *testing.T) {
+	var creates int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/dnszone":
+			fmt.Fprint(w, `{"Items":[{"Id":5,"Domain":"example.com"}]}`)
+		case r.Method == http.MethodGet:
+			fmt.Fprint(w, `{"Id":5,"Domain":"example.com","Records":[{"Id":11,"Type":3,"Name":"_acme-challenge","Value":"token"}]}`)
+		case r.Method == http.MethodPut:
+			creates++
+			fmt.Fprint(w, `{"Id":12}`)
+		}
+	}))
+	defer srv.Close()
+
+	var annotated []int
+	p := &bunnyProvider{
+		cfg:     bunnyNetDNSConfig{APIURL: srv.URL},
+		created: func(_ int64, record Record) { annotated = append(annotated, record.ID) },
+	}
+	require.NoError(t, p.Present(context.Background(), "example.com.", "_acme-challenge.example.com.", "token", 60))
+	assert.Zero(t, creates, "the existing record is reused")
+	assert.Equal(t, []int{11}, annotated)
+}
+
+func TestBunnyProvider_PresentUpsertsStaleRecord(t *testing.T) {
+	var creates int
+	var updated []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/dnszone":
+			fmt.Fprint(w, `{"Items":[{"Id":5,"Domain":"example.com"}]}`)
+		case r.Method == http.MethodGet:
+			fmt.Fprint(w, `{"Id":5,"Domain":"example.com","Records":[`+
+				`{"Id":10,"Type":3,"Name":"_acme-challenge","Value":"foreign"},`+
+				`{"Id":11,"Type":3,"Name":"_acme-challenge","Value":"active","Comment":"cert-manager-webhook-bunny"},`+
+				`{"Id":12,"Type":3,"Name":"_acme-challenge","Value":"stale","Comment":"cert-manager-webhook-bunny"}]}`)
+		case r.Method == http.MethodPost:
+			updated = append(updated, r.URL.Path)
+		case r.Method == http.MethodPut:
+			creates++
+			fmt.Fprint(w, `{"Id":13}`)
+		}
+	}))
+	defer srv.Close()
+
+	var annotated []int
+	p := &bunnyProvider{
+		cfg:     bunnyNetDNSConfig{APIURL: srv.URL, Upsert: true},
+		created: func(_ int64, record Record) { annotated = append(annotated, record.ID) },
+		active:  func(_, value string) bool { return value == "active" },
+	}
+	require.NoError(t, p.Present(context.Background(), "example.com.", "_acme-challenge.example.com.", "token", 60))
+	assert.Zero(t, creates)
+	assert.Equal(t, []string{"/dnszone/5/records/12"}, updated, "only the stale record of the solver is overwritten")
+	assert.Equal(t, []int{12}, annotated)
+
+	p.cfg.Upsert = false
+	require.NoError(t, p.Present(context.Background(), "example.com.", "_acme-challenge.example.com.", "token", 60))
+	assert.Equal(t, 1, creates)
+}
+
+// fakeZone serves a single Bunny zone whose records can be created and
+// deleted.
+type fakeZone struct {
+	mu      sync.Mutex
+	nextID  int
+	records []Record
+}
+
+func (z *fakeZone) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	switch {
+	case r.URL.Path == "/dnszone":
+		fmt.Fprint(w, `{"Items":[{"Id":5,"Domain":"example.com"}]}`)
+	case r.Method == http.MethodGet:
+		_ = json.NewEncoder(w).Encode(Item{ID: 5, Domain: "example.com", Records: z.records})
+	case r.Method == http.MethodPut:
+		var record Record
+		_ = json.NewDecoder(r.Body).Decode(&record)
+		z.nextID++
+		record.ID = z.nextID
+		z.records = append(z.records, record)
+		_ = json.NewEncoder(w).Encode(record)
+	case r.Method == http.MethodDelete:
+		id, _ := strconv.Atoi(r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:])
+		for i, record := range z.records {
+			if record.ID == id {
+				z.records = append(z.records[:i], z.records[i+1:]...)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (z *fakeZone) values() []string {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	var values []string
+	for _, record := range z.records {
+		values = append(values, record.Name+"="+record.Value)
+	}
+	return values
+}
+
+// An apex and a wildcard name in one Certificate share the challenge
+// record name with different values.
+func TestBunnyProvider_SameNameDifferentValues(t *testing.T) {
+	for _, upsert := range []bool{false, true} {
+		t.Run(fmt.Sprintf("upsert=%t", upsert), func(t *testing.T) {
+			zone := &fakeZone{}
+			srv := httptest.NewServer(zone)
+			defer srv.Close()
+
+			active := map[string]bool{}
+			p := &bunnyProvider{
+				cfg:    bunnyNetDNSConfig{APIURL: srv.URL, Upsert: upsert},
+				active: func(_, value string) bool { return active[value] },
+			}
+			ctx := context.Background()
+			for _, value := range []string{"apex", "wildcard"} {
+				active[value] = true
+				require.NoError(t, p.Present(ctx, "example.com.", "_acme-challenge.example.com.", value, 60))
+			}
+			assert.ElementsMatch(t, []string{"_acme-challenge=apex", "_acme-challenge=wildcard"}, zone.values())
+
+			require.NoError(t, p.CleanUp(ctx, "example.com.", "_acme-challenge.example.com.", "wildcard"))
+			assert.Equal(t, []string{"_acme-challenge=apex"}, zone.values())
+			require.NoError(t, p.CleanUp(ctx, "example.com.", "_acme-challenge.example.com.", "apex"))
+			assert.Empty(t, zone.values())
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package solver
+
+import (
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	cmacme "github.com/cert-manager/cert-manager/pkg/apis/acme/v1"
+	cmscheme "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned/scheme"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// Reasons of the Events recorded on Challenges.
+const (
+	reasonPresented = "BunnyRecordPresented"
+	reasonCleanedUp = "BunnyRecordCleanedUp"
+	reasonKept      = "BunnyRecordKept"
+
+	reasonPresentFailed = "BunnyPresentFailed"
+	reasonCleanUpFailed = "BunnyCleanUpFailed"
+)
+
+// newEventRecorder returns a recorder writing Events through cl. The
+// broadcaster is shut down, flushing queued Events, once stopCh is closed.
+func newEventRecorder(cl kubernetes.Interface, stopCh <-chan struct{}) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: cl.CoreV1().Events("")})
+	go func() {
+		<-stopCh
+		broadcaster.Shutdown()
+	}()
+	return broadcaster.NewRecorder(cmscheme.Scheme, corev1.EventSource{Component: "bunny-net-webhook"})
+}
+
+// challenge returns the Challenge with the given UID from the informer
+// cache, or nil if it isn't known.
+func (a *challengeAnnotator) challenge(uid types.UID) *cmacme.Challenge {
+	if a == nil || uid == "" {
+		return nil
+	}
+	objs, err := a.indexer.ByIndex(challengeUIDIndex, string(uid))
+	if err != nil || len(objs) == 0 {
+		return nil
+	}
+	return objs[0].(*cmacme.Challenge)
+}
+
+// recordEvent records an Event on the Challenge ch belongs to. Requests
+// for Challenges the informer hasn't seen, e.g. from the CLI or gRPC, are
+// not recorded.
+func (c *Solver) recordEvent(ch *v1alpha1.ChallengeRequest, eventType, reason, messageFmt string, args ...interface{}) {
+	if c.recorder == nil {
+		return
+	}
+	if obj := c.annotator.challenge(ch.UID); obj != nil {
+		c.recorder.Eventf(obj, eventType, reason, messageFmt, args...)
+	}
+}
+
+// recordFailure records a Warning Event with the error of a failed Present
+// or CleanUp and the suggested fix, so it shows in kubectl describe.
+func (c *Solver) recordFailure(ch *v1alpha1.ChallengeRequest, reason string, err error) {
+	c.recordEvent(ch, corev1.EventTypeWarning, reason, "%v. %s", err, remediations[classifyError(err)])
+}
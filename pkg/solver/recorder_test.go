@@ -0,0 +1,56 @@
+package solver
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	cmacme "github.com/cert-manager/cert-manager/pkg/apis/acme/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestRecordEvent(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{challengeUIDIndex: indexChallengeByUID})
+	require.NoError(t, indexer.Add(&cmacme.Challenge{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "ch", UID: "uid-1"}}))
+
+	recorder := record.NewFakeRecorder(10)
+	s := New(Options{})
+	s.annotator = &challengeAnnotator{indexer: indexer}
+	s.recorder = recorder
+
+	s.recordEvent(&v1alpha1.ChallengeRequest{UID: "uid-1"}, "Normal", reasonPresented, "Created TXT record %s", "_acme-challenge.example.com.")
+	s.recordEvent(&v1alpha1.ChallengeRequest{UID: "unknown"}, "Normal", reasonPresented, "ignored")
+
+	require.Len(t, recorder.Events, 1)
+	assert.Equal(t, "Normal BunnyRecordPresented Created TXT record _acme-challenge.example.com.", <-recorder.Events)
+}
+
+func TestPresentFailureRecordsEvent(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{challengeUIDIndex: indexChallengeByUID})
+	require.NoError(t, indexer.Add(&cmacme.Challenge{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "ch", UID: "uid-1"}}))
+
+	recorder := record.NewFakeRecorder(10)
+	s := New(Options{})
+	s.annotator = &challengeAnnotator{indexer: indexer}
+	s.recorder = recorder
+
+	ch := &v1alpha1.ChallengeRequest{
+		UID:          "uid-1",
+		Key:          "token",
+		ResolvedFQDN: "_acme-challenge.example.com.",
+		ResolvedZone: "example.com.",
+		Config:       &apiextensionsv1.JSON{Raw: []byte(`{"provider":"unknown"}`)},
+	}
+	require.Error(t, s.Present(ch))
+
+	require.Len(t, recorder.Events, 1)
+	event := <-recorder.Events
+	assert.True(t, strings.HasPrefix(event, "Warning BunnyPresentFailed "), event)
+	assert.Contains(t, event, "provider")
+	assert.Contains(t, event, remediations["config"])
+}
@@ -0,0 +1,124 @@
+package solver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+const redacted = "[REDACTED]"
+
+// minSecretLength keeps short strings, which could occur in any message,
+// from being treated as secrets.
+const minSecretLength = 8
+
+// secrets holds every API key the process has used, so they can be removed
+// from logs and errors however they got there, e.g. a response body echoing
+// the request.
+var secrets sync.Map
+
+// registerSecret makes redact hide secret.
+func registerSecret(secret string) {
+	if len(secret) >= minSecretLength {
+		secrets.Store(secret, struct{}{})
+	}
+}
+
+// redact replaces the registered secrets in s.
+func redact(s string) string {
+	secrets.Range(func(k, _ interface{}) bool {
+		s = strings.ReplaceAll(s, k.(string), redacted)
+		return true
+	})
+	return s
+}
+
+// redactedError hides secrets in the message of the error it wraps, which
+// errors.Is and errors.As still see.
+type redactedError struct {
+	err error
+	msg string
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
+
+// redactError returns err with the registered secrets hidden from its
+// message.
+func redactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := redact(err.Error())
+	if msg == err.Error() {
+		return err
+	}
+	return &redactedError{err: err, msg: msg}
+}
+
+// redactPanic re-panics with the registered secrets hidden from the panic
+// value. It must be deferred.
+func redactPanic() {
+	if r := recover(); r != nil {
+		panic(redact(fmt.Sprint(r)))
+	}
+}
+
+// NewRedactingHandler wraps h so the API keys the solver has used never
+// appear in log records, in messages or in attributes.
+func NewRedactingHandler(h slog.Handler) slog.Handler {
+	return redactingHandler{h}
+}
+
+type redactingHandler struct {
+	next slog.Handler
+}
+
+func (h redactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h redactingHandler) Handle(ctx context.Context, r slog.Record) error {
+	out := slog.NewRecord(r.Time, r.Level, redact(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(redactAttr(a))
+		return true
+	})
+	return h.next.Handle(ctx, out)
+}
+
+func (h redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redactedAttrs := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redactedAttrs[i] = redactAttr(a)
+	}
+	return redactingHandler{h.next.WithAttrs(redactedAttrs)}
+}
+
+func (h redactingHandler) WithGroup(name string) slog.Handler {
+	return redactingHandler{h.next.WithGroup(name)}
+}
+
+func redactAttr(a slog.Attr) slog.Attr {
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, redact(v.String()))
+	case slog.KindGroup:
+		group := v.Group()
+		redactedGroup := make([]any, len(group))
+		for i, ga := range group {
+			redactedGroup[i] = redactAttr(ga)
+		}
+		return slog.Group(a.Key, redactedGroup...)
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			return slog.Any(a.Key, redactError(err))
+		}
+		return slog.String(a.Key, redact(fmt.Sprint(v.Any())))
+	default:
+		return slog.Attr{Key: a.Key, Value: v}
+	}
+}
@@ -0,0 +1,61 @@
+package solver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactErrorHidesUsedKeys(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad request: AccessKey "+r.Header.Get("AccessKey"), http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	const key = "echoed-api-key-1234"
+	_, err := getZoneByID(context.Background(), bunnyNetDNSConfig{APIURL: srv.URL, APIKey: key}, 1)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), key)
+
+	redactedErr := redactError(err)
+	assert.NotContains(t, redactedErr.Error(), key)
+	assert.Contains(t, redactedErr.Error(), redacted)
+	assert.True(t, errors.Is(redactedErr, err))
+
+	plain := errors.New("zone not found")
+	assert.Same(t, plain, redactError(plain))
+}
+
+func TestRedactingHandler(t *testing.T) {
+	const key = "logged-api-key-5678"
+	registerSecret(key)
+
+	var buf bytes.Buffer
+	logger := slog.New(NewRedactingHandler(slog.NewTextHandler(&buf, nil)))
+	logger.With("key", key).Info("using "+key,
+		"error", fmt.Errorf("request with %s failed", key),
+		slog.Group("request", "header", "AccessKey: "+key),
+	)
+	assert.NotContains(t, buf.String(), key)
+	assert.Contains(t, buf.String(), redacted)
+}
+
+func TestRedactPanic(t *testing.T) {
+	const key = "panicked-api-key-9012"
+	registerSecret(key)
+	defer func() {
+		assert.Equal(t, "bad key "+redacted, recover())
+	}()
+	func() {
+		defer redactPanic()
+		panic("bad key " + key)
+	}()
+}
@@ -0,0 +1,129 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how Bunny API requests failing with a transient
+// error, i.e. no response, a 5xx status or a 429 rate limit, are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts per request including the
+	// first; 1 disables retries.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry. It doubles with every
+	// further attempt, up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// maxRetryAfter is the longest Retry-After a request waits for. Longer
+// rate limits fail the request, leaving the retry to cert-manager.
+const maxRetryAfter = time.Minute
+
+var defaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    10 * time.Second,
+}
+
+// withDefaults fills the unset fields of p from defaultRetryPolicy.
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = defaultRetryPolicy.MaxAttempts
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = defaultRetryPolicy.BaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = defaultRetryPolicy.MaxDelay
+	}
+	return p
+}
+
+// backoff returns the delay before retrying after the given attempt. Half
+// of the delay is random, so webhooks retrying the same outage spread out.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay << (attempt - 1)
+	if d > p.MaxDelay || d <= 0 {
+		d = p.MaxDelay
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+}
+
+// retryAfter parses the Retry-After header of resp, given in seconds or as
+// an HTTP date. It returns false if there is none.
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// doWithRetry sends req with send, retrying transient failures according
+// to the policy. A Retry-After on the response replaces the backoff delay.
+// Request bodies are replayed through req.GetBody, which http.NewRequest
+// sets for in-memory bodies.
+func (p RetryPolicy) doWithRetry(req *http.Request, send func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	p = p.withDefaults()
+	for attempt := 1; ; attempt++ {
+		resp, err := send(req)
+		if attempt >= p.MaxAttempts || !retryable(resp, err) {
+			return resp, err
+		}
+		delay := p.backoff(attempt)
+		if d, ok := retryAfter(resp, time.Now()); ok {
+			if d > maxRetryAfter {
+				return resp, err
+			}
+			delay = d
+		}
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return resp, err
+			}
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return resp, err
+			}
+			req.Body = body
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
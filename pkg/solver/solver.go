@@ -0,0 +1,1122 @@
+// Package solver implements a cert-manager ACME DNS01 webhook solver for
+// Bunny DNS. It can be served on its own by cmd.RunWebhookServer or embedded
+// alongside solvers for other providers.
+package solver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/sync/singleflight"
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+
+	"github.com/cert-manager/webhook-example/pkg/bunny"
+)
+
+const (
+	// DefaultAPIBase is the public Bunny API.
+	DefaultAPIBase = bunny.DefaultBaseURL
+	recordTTL      = 10
+	recordType     = bunny.RecordTypeTXT
+
+	// recordComment marks the TXT records the solver created, so upserts
+	// and orphan collection leave other _acme-challenge records alone.
+	recordComment = "cert-manager-webhook-bunny"
+
+	defaultOperationTimeout = 2 * time.Minute
+
+	errMissingAPIKey = "API_KEY must be specified when the Issuer has no apiKeySecretRef"
+)
+
+var httpClient = &http.Client{
+	Timeout: 30 * time.Second,
+}
+
+// Options configures a Solver. The zero value is a solver that reads API keys
+// from the Secrets referenced by Issuers and runs no optional features.
+type Options struct {
+	// Name is the solverName Issuers select the solver by, DefaultName if
+	// empty. Solvers with different names, keys and API bases can be served
+	// side by side, e.g. for staging and production accounts.
+	Name string
+
+	// APIBase, if set, replaces the process-wide API base URL for this
+	// solver's challenges. An apiURL in the Issuer config still wins.
+	APIBase string
+
+	// AllowedAPIURLs are endpoints an Issuer's apiURL or zoneEndpoints may
+	// point at when the webhook-wide key or a zone binding's key is used.
+	// Other endpoints only receive the Issuer's own apiKeySecretRef, so an
+	// Issuer can't send credentials it doesn't own to a server of its choice.
+	AllowedAPIURLs []string
+
+	// APIKey is the webhook-wide Bunny API key, used as ambient credentials
+	// by issuers without an apiKeySecretRef. Deprecated in favour of
+	// apiKeySecretRef.
+	APIKey string
+
+	// APIKeyProvider, if set, supplies the webhook-wide API key instead of
+	// APIKey, e.g. a KeyFile following a mounted Secret.
+	APIKeyProvider APIKeyProvider
+
+	// FallbackAPIKeys are webhook-wide keys tried in order when Bunny
+	// rejects the webhook-wide key with 401, so keys can be rotated with an
+	// overlap window.
+	FallbackAPIKeys []string
+
+	// SecondaryAPIKey, if set, is a second Bunny account hosting the same
+	// zones. Challenge records are mirrored to it and cleaned up there too.
+	SecondaryAPIKey string
+
+	// AuthScheme is how API keys are sent to the Bunny API for Issuers that
+	// don't choose themselves: AuthSchemeAccessKey (the default) or
+	// AuthSchemeBearer.
+	AuthScheme string
+
+	// Groups are the API groups the solver is served under. Issuers are
+	// only validated for solvers referencing one of them.
+	Groups []string
+
+	// Namespace is the namespace the solver runs in. It holds the leader
+	// election lease and the defaults and cleanup queue ConfigMaps.
+	Namespace string
+
+	// ClusterResourceNamespace is cert-manager's cluster resource
+	// namespace, where ClusterIssuer Secrets live.
+	ClusterResourceNamespace string
+
+	// KubeAPIQPS and KubeAPIBurst override the client-side rate limits for
+	// API server requests when positive.
+	KubeAPIQPS   float32
+	KubeAPIBurst int
+
+	// LeaderElection runs background jobs on the elected leader only, using
+	// the Lease LeaderElectionID in Namespace.
+	LeaderElection   bool
+	LeaderElectionID string
+
+	// ServedNamespaces restricts the solver to challenges whose resource
+	// namespace is listed. Empty serves all.
+	ServedNamespaces []string
+
+	// AdmissionBindAddress, when set, serves a validating webhook for
+	// Issuers using the TLS certificate and key files.
+	AdmissionBindAddress string
+	AdmissionCertFile    string
+	AdmissionKeyFile     string
+
+	// ZoneBindings resolves API keys from BunnyZoneBinding resources.
+	ZoneBindings bool
+
+	// WatchIssuers validates Issuers in the background.
+	WatchIssuers bool
+
+	// DefaultsConfigMap names a ConfigMap in Namespace holding solver config
+	// merged under every Issuer's config.
+	DefaultsConfigMap string
+
+	// CleanupQueueConfigMap names a ConfigMap in Namespace used to persist
+	// pending record deletions. Setting it makes CleanUp asynchronous.
+	CleanupQueueConfigMap string
+
+	// HTTP01IngressClass, if set, solves the HTTP01 challenges of Issuers
+	// whose http01.ingress solver uses this ingress class, by uploading them
+	// to the Edge Storage zone StorageZone. StoragePassword is the zone's
+	// password and StorageEndpoint its region's API.
+	HTTP01IngressClass string
+	StorageZone        string
+	StoragePassword    string
+	StorageEndpoint    string
+
+	// ClusterProxy routes Bunny API requests through the OpenShift
+	// cluster-wide egress proxy.
+	ClusterProxy bool
+
+	// CheckCertManagerVersion makes Initialize fail on clusters running a
+	// cert-manager release older than the oldest supported one.
+	CheckCertManagerVersion bool
+
+	// CheckAPIService makes CheckAPIService verify the APIService of every
+	// group.
+	CheckAPIService bool
+
+	// Fallback, if set, presents challenges once FallbackAfterFailures
+	// consecutive Bunny API calls have failed (3 if unset).
+	Fallback              Fallback
+	FallbackAfterFailures int
+
+	// DoHResolvers are DNS-over-HTTPS resolver URLs Present waits on until
+	// they return the new record, for clusters where outbound DNS is
+	// blocked. PropagationTimeout bounds the wait (2m if unset) unless the
+	// Certificate overrides it.
+	DoHResolvers       []string
+	PropagationTimeout time.Duration
+
+	// OperationTimeout bounds the Bunny API calls of a single Present or
+	// CleanUp, including retries; 2m if unset. Waiting for propagation is
+	// bounded separately.
+	OperationTimeout time.Duration
+
+	// ShutdownGracePeriod is how long in-flight Present and CleanUp calls
+	// may take to finish once the stop channel closes; 25s if unset.
+	ShutdownGracePeriod time.Duration
+
+	// PropagationPollInterval is how often propagation is checked, 5s if
+	// unset. Issuers can override it and PropagationTimeout.
+	PropagationPollInterval time.Duration
+
+	// VerifyPropagation makes Present also wait until Bunny's authoritative
+	// nameservers serve the new record, queried over DNS.
+	// PropagationNameservers, host or host:port, are queried instead if set,
+	// with or without VerifyPropagation.
+	VerifyPropagation      bool
+	PropagationNameservers []string
+
+	// DelegationZone, when set, is a Bunny zone all challenge records are
+	// written to instead of the zones of the domains being validated.
+	DelegationZone string
+
+	// Providers are additional DNS backends, keyed by the name Issuers
+	// select them with in their config's provider field.
+	Providers map[string]ProviderFactory
+
+	// APIRateLimit caps the Bunny API requests of all challenges together,
+	// in requests per second, allowing bursts of APIRateBurst. Zero means
+	// no limit.
+	APIRateLimit float64
+	APIRateBurst int
+
+	// CircuitBreakerFailures, when positive, is the number of consecutive
+	// transient Bunny API failures after which requests fail fast with
+	// ErrCircuitOpen for CircuitBreakerCooldown (30s by default), until a
+	// probe request succeeds. Each API endpoint and key has its own
+	// breaker.
+	CircuitBreakerFailures int
+	CircuitBreakerCooldown time.Duration
+
+	// RecordTTL is the TTL in seconds of challenge records whose Issuer
+	// doesn't set one, 10 if unset.
+	RecordTTL int
+
+	// KeepRecordsOnCleanup makes CleanUp leave every challenge record in
+	// place. It is meant for debugging failed issuance only.
+	KeepRecordsOnCleanup bool
+
+	// OrphanRecordMaxAge, when positive, deletes _acme-challenge TXT
+	// records in the zones of APIKey once they have been seen for that
+	// long, scanning every OrphanRecordGCInterval (15m if unset).
+	OrphanRecordMaxAge     time.Duration
+	OrphanRecordGCInterval time.Duration
+
+	// ZoneCacheTTL is how long zone IDs are cached. Zero disables the
+	// cache.
+	ZoneCacheTTL time.Duration
+
+	// Retry is the retry policy for Bunny API requests. Unset fields use
+	// the defaults of 4 attempts with delays from 500ms up to 10s.
+	Retry RetryPolicy
+
+	// RecordTransforms rewrite every challenge record's name and value
+	// before it is submitted, after the Issuer's recordTemplates.
+	RecordTransforms []RecordTransform
+
+	// Instrumentation, if set, is told about every Present and CleanUp and
+	// every Bunny API call.
+	Instrumentation Instrumentation
+
+	// Notifier, if set, is told about domains whose Present failed
+	// NotifyAfterFailures times in a row (3 if unset).
+	Notifier            Notifier
+	NotifyAfterFailures int
+
+	// EventSink, if set, receives an event for every step of a challenge:
+	// presented, propagated (with DoHResolvers), cleaned and failed.
+	EventSink EventSink
+}
+
+// New returns a Bunny DNS solver. It must be initialized by the webhook
+// server before use.
+func New(opts Options) *Solver {
+	s := &Solver{opts: opts, lifecycle: newLifecycle()}
+	registerSecret(opts.APIKey)
+	registerSecret(opts.SecondaryAPIKey)
+	registerSecret(opts.StoragePassword)
+	for _, key := range opts.FallbackAPIKeys {
+		registerSecret(key)
+	}
+	if opts.APIRateLimit > 0 {
+		burst := opts.APIRateBurst
+		if burst <= 0 {
+			burst = int(math.Ceil(opts.APIRateLimit))
+		}
+		s.limiter = rate.NewLimiter(rate.Limit(opts.APIRateLimit), burst)
+	}
+	if opts.ZoneCacheTTL > 0 {
+		s.zones = newZoneCache(opts.ZoneCacheTTL)
+	}
+	if opts.CircuitBreakerFailures > 0 {
+		s.breakers = newCircuitBreakers(opts.CircuitBreakerFailures, opts.CircuitBreakerCooldown)
+	}
+	return s
+}
+
+// Solver is the Bunny DNS solver. It implements the cert-manager
+// webhook.Solver interface.
+type Solver struct {
+	opts Options
+
+	client   kubernetes.Interface
+	secrets  corelisters.SecretLister
+	recorder record.EventRecorder
+
+	// limiter is shared by the Bunny API requests of all challenges.
+	// breakers guard each API endpoint and account separately.
+	limiter  *rate.Limiter
+	breakers *circuitBreakers
+	zones    *zoneCache
+
+	// usedSecrets tracks the namespace/name of Secrets referenced by
+	// challenges so rotations of those Secrets can be reported.
+	usedSecrets sync.Map
+
+	annotator *challengeAnnotator
+	defaults  *solverDefaults
+	bindings  *zoneBindings
+	cleanups  *cleanupQueue
+
+	// jobs are started on the leader once Initialize has completed.
+	jobs []BackgroundJob
+
+	lifecycle *lifecycle
+
+	initOnce    sync.Once
+	initErr     error
+	initialized atomic.Bool
+	apiService  atomic.Pointer[apiServiceProbe]
+
+	// bunnyFailures counts consecutive Present calls failed by a Bunny API
+	// outage; fallbackRecords holds the records published by the
+	// fallback, so CleanUp removes them from there. With a cleanup queue
+	// they are also kept in its ConfigMap.
+	bunnyFailures   atomic.Int32
+	fallbackRecords sync.Map
+
+	// activeRecords holds the fqdn/value of challenges between Present and
+	// CleanUp, so upserts leave records of concurrent challenges alone.
+	activeRecords sync.Map
+
+	presentFailures failureTracker
+}
+
+// DefaultName is the solver name Issuers select unless Options.Name says
+// otherwise.
+const DefaultName = "bunny-net"
+
+func (c *Solver) Name() string {
+	if c.opts.Name != "" {
+		return c.opts.Name
+	}
+	return DefaultName
+}
+
+func (c *Solver) Present(ch *v1alpha1.ChallengeRequest) (err error) {
+	defer redactPanic()
+	if ch == nil {
+		return fmt.Errorf("challenge request cannot be nil")
+	}
+	release, err := c.admit()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if ch, err = normalizeChallenge(c.context(), ch); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	ctx, done := c.instrumentation().StartOperation(withChallengeLogger(c.context(), ch), "present", challengeAttrs(ch))
+	ctx, end := startSpan(ctx, "Present", challengeSpanAttrs(ch)...)
+	defer func() {
+		end(err)
+		done(err)
+		observeOperation(presentTotal, presentDuration, start, err)
+	}()
+
+	err = redactError(c.present(ctx, ch))
+	c.recordPresentResult(ch.DNSName, err)
+	if err != nil {
+		c.emit(EventFailed, ch, "present", err)
+		c.recordFailure(ch, reasonPresentFailed, err)
+	}
+	return err
+}
+
+func (c *Solver) present(ctx context.Context, ch *v1alpha1.ChallengeRequest) error {
+	target, cfg, err := c.target(ctx, ch)
+	if err != nil {
+		return err
+	}
+
+	overrides, err := c.annotator.certificateOverrides(ctx, ch.UID)
+	if err != nil {
+		return err
+	}
+	ttl := recordTTL
+	if c.opts.RecordTTL > 0 {
+		ttl = c.opts.RecordTTL
+	}
+	if cfg.TTL > 0 {
+		ttl = cfg.TTL
+	}
+	if overrides.TTL > 0 {
+		ttl = overrides.TTL
+	}
+
+	provider, err := c.provider(ctx, target, cfg)
+	if err != nil {
+		return err
+	}
+	c.activeRecords.Store(recordKey(target.ResolvedFQDN, target.Key), struct{}{})
+	apiCtx, cancel := context.WithTimeout(ctx, c.operationTimeout())
+	defer cancel()
+	if err := provider.Present(apiCtx, target.ResolvedZone, target.ResolvedFQDN, target.Key, ttl); err != nil {
+		c.activeRecords.Delete(recordKey(target.ResolvedFQDN, target.Key))
+		if !cfg.isBunny() || !isOutage(err) || !c.bunnyFailed() {
+			return err
+		}
+		if err := c.presentFallback(ctx, ch.ResolvedFQDN, ch.Key, err); err != nil {
+			return err
+		}
+		c.emit(EventPresented, ch, "", nil)
+		return nil
+	}
+	if cfg.isBunny() {
+		c.bunnySucceeded()
+	}
+
+	logger(ctx).Info("Successfully created DNS record", "record", target.ResolvedFQDN)
+	c.recordEvent(ch, corev1.EventTypeNormal, reasonPresented, "Created TXT record %s", target.ResolvedFQDN)
+	c.emit(EventPresented, target, "", nil)
+	timeout := cfg.PropagationTimeout
+	if overrides.PropagationTimeout > 0 {
+		timeout = overrides.PropagationTimeout
+	}
+	if err := c.waitForPropagation(ctx, target.ResolvedFQDN, target.Key, timeout, cfg.PollInterval); err != nil {
+		return err
+	}
+	if len(c.propagationChecks()) > 0 {
+		c.emit(EventPropagated, target, "", nil)
+	}
+	return nil
+}
+
+// target returns the challenge as its record is written, with delegation,
+// the Issuer's zone override and record transforms applied, together with
+// the config for it.
+func (c *Solver) target(ctx context.Context, ch *v1alpha1.ChallengeRequest) (*v1alpha1.ChallengeRequest, bunnyNetDNSConfig, error) {
+	target := c.delegated(ch)
+	cfg, err := c.loadConfig(ctx, target)
+	if err != nil {
+		return nil, cfg, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if cfg.Zone != "" {
+		zone, err := canonicalName(cfg.Zone)
+		if err != nil {
+			return nil, cfg, &configFieldError{Field: "zone", Reason: err.Error()}
+		}
+		if foldName(zone) != foldName(target.ResolvedZone) {
+			if !withinZone(target.ResolvedFQDN, zone) {
+				return nil, cfg, &configFieldError{Field: "zone", Reason: fmt.Sprintf("%s is not within %s", target.ResolvedFQDN, zone)}
+			}
+			out := *target
+			out.ResolvedZone = zone
+			target = &out
+			// Zone endpoints are matched against the overridden zone.
+			if cfg, err = c.loadConfig(ctx, target); err != nil {
+				return nil, cfg, fmt.Errorf("failed to load config: %w", err)
+			}
+		}
+	}
+
+	target, err = c.transformed(target, cfg)
+	return target, cfg, err
+}
+
+// updateTXTRecord overwrites the TXT record recordID with value.
+func updateTXTRecord(ctx context.Context, cfg bunnyNetDNSConfig, zoneID int64, recordID int, zone, fqdn, value string, ttl int) (_ Record, err error) {
+	ctx, end := startSpan(ctx, "bunny.record.update", attribute.String("dns.fqdn", fqdn), attribute.Int("bunny.record_id", recordID))
+	defer func() { end(err) }()
+
+	record := Record{
+		ID:      recordID,
+		Type:    recordType,
+		Ttl:     ttl,
+		Value:   value,
+		Name:    bunny.RecordName(zone, fqdn),
+		Comment: recordComment,
+	}
+	if err := cfg.client().UpdateRecord(ctx, zoneID, record); err != nil {
+		return Record{}, fmt.Errorf("failed to update record %d: %w", recordID, err)
+	}
+	return record, nil
+}
+
+// recordActive reports whether a challenge for fqdn with value is in
+// progress in this process.
+func (c *Solver) recordActive(fqdn, value string) bool {
+	_, ok := c.activeRecords.Load(recordKey(fqdn, value))
+	return ok
+}
+
+// challengeInProgress reports whether value belongs to a challenge for fqdn
+// that is in progress, in this process or, going by the cluster's
+// Challenges, in another replica.
+func (c *Solver) challengeInProgress(fqdn, value string) bool {
+	return c.recordActive(fqdn, value) || c.annotator.challengeExists(value)
+}
+
+// ownedRecord reports whether the solver created record.
+func ownedRecord(record Record) bool {
+	return record.Comment == recordComment
+}
+
+func recordKey(fqdn, value string) string {
+	return foldName(fqdn) + "/" + value
+}
+
+// createTXTRecord creates the TXT record for fqdn in the zone with the given
+// ID and returns it as created by the Bunny API.
+func createTXTRecord(ctx context.Context, cfg bunnyNetDNSConfig, zoneID int64, zone, fqdn, value string, ttl int) (_ Record, err error) {
+	ctx, end := startSpan(ctx, "bunny.record.create", attribute.String("dns.fqdn", fqdn), attribute.Int64("bunny.zone_id", zoneID))
+	defer func() { end(err) }()
+
+	created, err := cfg.client().CreateRecord(ctx, zoneID, Record{
+		Type:    recordType,
+		Ttl:     ttl,
+		Value:   value,
+		Name:    bunny.RecordName(zone, fqdn),
+		Comment: recordComment,
+	})
+	if errors.Is(err, bunny.ErrMalformedResponse) {
+		logger(ctx).Warn("failed to decode created record", "record", fqdn, "error", err)
+		return Record{}, nil
+	}
+	return created, err
+}
+
+// GetZone looks zone up in the Bunny API. Concurrent lookups of the same
+// zone in the same account, e.g. for a certificate with many names in it,
+// share a single request, which is bound to the context of the first.
+func GetZone(ctx context.Context, zone string, cfg bunnyNetDNSConfig) (ZoneResponse, error) {
+	key := cacheKey(cfg, zone)
+	v, err, _ := zoneLookups.Do(key.apiBase+"\x00"+key.apiKey+"\x00"+key.zone, func() (interface{}, error) {
+		return lookupZone(ctx, zone, cfg)
+	})
+	if err != nil {
+		return ZoneResponse{}, err
+	}
+	return v.(ZoneResponse), nil
+}
+
+// zoneLookups deduplicates concurrent GetZone calls.
+var zoneLookups singleflight.Group
+
+func lookupZone(ctx context.Context, zone string, cfg bunnyNetDNSConfig) (ZoneResponse, error) {
+	zone, err := canonicalName(zone)
+	if err != nil {
+		return ZoneResponse{}, err
+	}
+	item, err := bunny.FindZone(ctx, cfg.client(), zone)
+	if err != nil {
+		return ZoneResponse{}, err
+	}
+	cfg.zones.put(cfg, foldName(zone), int64(item.ID))
+	return ZoneResponse{Items: []Item{item}, CurrentPage: 1, TotalItems: 1}, nil
+}
+
+// getZoneByID returns the zone with the given ID, including its records.
+func getZoneByID(ctx context.Context, cfg bunnyNetDNSConfig, id int64) (Item, error) {
+	item, err := cfg.client().GetZone(ctx, id)
+	if bunny.IsNotFound(err) {
+		return Item{}, fmt.Errorf("%w with ID %d", errZoneNotFound, id)
+	}
+	return item, err
+}
+
+// errZoneNotFound is returned, wrapped, for zones not in the account.
+var errZoneNotFound = bunny.ErrZoneNotFound
+
+// hostedZone returns the Bunny zone records for fqdn go to, and its ID:
+// zone if the account has it, otherwise the closest enclosing domain of
+// fqdn that it does have. cert-manager resolves zones through public SOA
+// records, which may point at a zone hosted elsewhere, e.g. a sub-zone
+// delegated away from the Bunny-hosted parent.
+func hostedZone(ctx context.Context, cfg bunnyNetDNSConfig, zone, fqdn string) (string, int64, error) {
+	ctx, end := startSpan(ctx, "bunny.zone.lookup", attribute.String("dns.zone", zone), attribute.String("dns.fqdn", fqdn))
+	found, id, err := findHostedZone(ctx, cfg, zone, fqdn)
+	end(err)
+	return found, id, err
+}
+
+func findHostedZone(ctx context.Context, cfg bunnyNetDNSConfig, zone, fqdn string) (string, int64, error) {
+	id, err := GetZoneID(ctx, zone, cfg)
+	if err == nil || !errors.Is(err, errZoneNotFound) {
+		return zone, id, err
+	}
+
+	labels := strings.Split(strings.TrimSuffix(fqdn, "."), ".")
+	for i := 1; i < len(labels)-1; i++ {
+		candidate := strings.Join(labels[i:], ".") + "."
+		if foldName(candidate) == foldName(zone) {
+			continue
+		}
+		candidateID, cerr := GetZoneID(ctx, candidate, cfg)
+		if cerr == nil {
+			logger(ctx).Info("Zone is not in Bunny, using the enclosing zone", "missingZone", zone, "enclosingZone", candidate, "record", fqdn)
+			return candidate, candidateID, nil
+		}
+		if !errors.Is(cerr, errZoneNotFound) {
+			return "", 0, cerr
+		}
+	}
+	return "", 0, err
+}
+
+// zonePage returns one page of the account's zones, filtered by search if
+// it isn't empty.
+func zonePage(ctx context.Context, cfg bunnyNetDNSConfig, search string, page, perPage int) (ZoneResponse, error) {
+	return cfg.client().ListZones(ctx, search, page, perPage)
+}
+
+// GetZoneID returns the ID of zone, from the config's zone cache if it
+// has one.
+func GetZoneID(ctx context.Context, zone string, cfg bunnyNetDNSConfig) (int64, error) {
+	if id, ok := cfg.zones.get(cfg, zone); ok {
+		return id, nil
+	}
+	data, err := GetZone(ctx, zone, cfg)
+	if err != nil {
+		return 0, err
+	}
+	return int64(data.Items[0].ID), nil
+}
+
+// CheckAPIKey performs the cheapest authenticated call available, so a
+// revoked key or blocked egress shows up as an error. authScheme is one of
+// the AuthScheme constants, AccessKey if empty.
+func CheckAPIKey(ctx context.Context, apiKey, authScheme string) error {
+	cfg := bunnyNetDNSConfig{APIKey: apiKey, AuthScheme: authScheme}
+	_, err := zonePage(ctx, cfg, "", 1, 1)
+	return err
+}
+
+// CheckAPI verifies the webhook-wide key of the solver against its API
+// endpoint, trying the fallback keys if Bunny rejects it. Without a key there
+// is nothing to verify.
+func (c *Solver) CheckAPI(ctx context.Context) error {
+	cfg := c.ambientConfig()
+	if cfg.APIKey == "" {
+		return nil
+	}
+	_, err := zonePage(ctx, cfg, "", 1, 1)
+	return err
+}
+
+func (c *Solver) CleanUp(ch *v1alpha1.ChallengeRequest) error {
+	defer redactPanic()
+	if ch == nil {
+		return fmt.Errorf("challenge request cannot be nil")
+	}
+	release, err := c.admit()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	ch, err = normalizeChallenge(c.context(), ch)
+	if err != nil {
+		return err
+	}
+
+	if c.cleanups == nil {
+		return c.cleanUp(c.context(), ch)
+	}
+	// Reject bad config up front rather than queueing a deletion that can
+	// never succeed.
+	if _, err := c.loadConfig(c.context(), c.delegated(ch)); err != nil {
+		return redactError(err)
+	}
+	err = c.cleanups.add(c.context(), ch)
+	if errors.Is(err, errCleanupQueueFull) {
+		// Delete the record right away rather than dropping the deletion.
+		slog.Warn("cleanup queue is full, cleaning up synchronously", "fqdn", ch.ResolvedFQDN, "error", err)
+		return c.cleanUp(c.context(), ch)
+	}
+	return redactError(err)
+}
+
+func (c *Solver) cleanUp(ctx context.Context, ch *v1alpha1.ChallengeRequest) (err error) {
+	start := time.Now()
+	ctx, done := c.instrumentation().StartOperation(withChallengeLogger(ctx, ch), "cleanup", challengeAttrs(ch))
+	ctx, end := startSpan(ctx, "CleanUp", challengeSpanAttrs(ch)...)
+	defer func() {
+		err = redactError(err)
+		end(err)
+		done(err)
+		observeOperation(cleanupTotal, cleanupDuration, start, err)
+		if err != nil {
+			c.emit(EventFailed, ch, "cleanup", err)
+			c.recordFailure(ch, reasonCleanUpFailed, err)
+		} else {
+			c.emit(EventCleaned, ch, "", nil)
+		}
+	}()
+
+	if handled, err := c.cleanUpFallback(ctx, ch.ResolvedFQDN, ch.Key); handled || err != nil {
+		return err
+	}
+	target, cfg, err := c.target(ctx, ch)
+	if err != nil {
+		return err
+	}
+	if c.opts.KeepRecordsOnCleanup || cfg.KeepRecordsOnCleanup {
+		logger(ctx).Info("Keeping TXT record for debugging, not deleting it", "record", target.ResolvedFQDN, "value", target.Key)
+		c.activeRecords.Delete(recordKey(target.ResolvedFQDN, target.Key))
+		c.recordEvent(ch, corev1.EventTypeWarning, reasonKept, "Kept TXT record %s because keepRecordsOnCleanup is set", target.ResolvedFQDN)
+		return nil
+	}
+	provider, err := c.provider(ctx, target, cfg)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.operationTimeout())
+	defer cancel()
+	if err := provider.CleanUp(ctx, target.ResolvedZone, target.ResolvedFQDN, target.Key); err != nil {
+		return err
+	}
+	c.activeRecords.Delete(recordKey(target.ResolvedFQDN, target.Key))
+	c.recordEvent(ch, corev1.EventTypeNormal, reasonCleanedUp, "Deleted TXT record %s", target.ResolvedFQDN)
+	return nil
+}
+
+// deleteTXTRecord deletes the TXT record for fqdn with the given value, if
+// it exists.
+func deleteTXTRecord(ctx context.Context, cfg bunnyNetDNSConfig, zone, fqdn, value string) error {
+	item, err := getZoneRecords(ctx, cfg, zone)
+	if err != nil {
+		return err
+	}
+	return deleteTXTRecordIn(ctx, cfg, item, zone, fqdn, value)
+}
+
+// getZoneRecords returns zone with all of its records. Search results
+// aren't relied on for records, since they may be truncated for large
+// zones; the zone is fetched by ID instead.
+func getZoneRecords(ctx context.Context, cfg bunnyNetDNSConfig, zone string) (Item, error) {
+	id, err := GetZoneID(ctx, zone, cfg)
+	if err != nil {
+		return Item{}, fmt.Errorf("failed to get zone ID: %w", err)
+	}
+	item, err := getZoneByID(ctx, cfg, id)
+	if err != nil {
+		// The cached ID may belong to a deleted zone.
+		cfg.zones.forget(cfg, zone)
+		return Item{}, fmt.Errorf("failed to get zone records: %w", err)
+	}
+	return item, nil
+}
+
+// deleteTXTRecordIn deletes the TXT record for fqdn with the given value
+// from the zone item, which must have been fetched with its records.
+func deleteTXTRecordIn(ctx context.Context, cfg bunnyNetDNSConfig, item Item, zone, fqdn, value string) (err error) {
+	record, ok := bunny.FindTXTRecord(item.Records, zone, fqdn, value)
+	if !ok {
+		// Nothing to delete
+		return nil
+	}
+	recordID := record.ID
+	ctx, end := startSpan(ctx, "bunny.record.delete", attribute.String("dns.fqdn", fqdn), attribute.Int("bunny.record_id", recordID))
+	defer func() { end(err) }()
+
+	// Transient failures are retried by cfg.do.
+	err = cfg.client().DeleteRecord(ctx, int64(item.ID), recordID)
+	if bunny.IsNotFound(err) {
+		// Deleted concurrently, e.g. by another replica.
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete record %d: %w", recordID, err)
+	}
+	return nil
+}
+
+// Initialize sets up the solver's clients, informers and background jobs.
+// A solver served by the API servers of several groups is initialized by
+// each of them; only the first call does the work, on its stop channel, and
+// later calls return its result.
+func (c *Solver) Initialize(kubeClientConfig *rest.Config, stopCh <-chan struct{}) error {
+	c.initOnce.Do(func() {
+		c.initErr = c.initialize(kubeClientConfig, stopCh)
+	})
+	return c.initErr
+}
+
+func (c *Solver) initialize(kubeClientConfig *rest.Config, stopCh <-chan struct{}) error {
+	if c.lifecycle == nil {
+		c.lifecycle = newLifecycle()
+	}
+	c.lifecycle.stopOn(stopCh, c.shutdownGracePeriod())
+	kubeClientConfig = c.withClientRateLimits(kubeClientConfig)
+
+	cl, err := kubernetes.NewForConfig(kubeClientConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+	c.client = cl
+
+	dyn, err := dynamic.NewForConfig(kubeClientConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+
+	// Secrets are served from a shared informer so a burst of challenges
+	// doesn't turn into a burst of GETs against the API server. The informer
+	// applies updates as they are watched, so a rotated key is picked up by
+	// the next challenge without any explicit invalidation. A webhook serving
+	// a single namespace only watches Secrets there.
+	var informerOpts []informers.SharedInformerOption
+	if len(c.opts.ServedNamespaces) == 1 {
+		informerOpts = append(informerOpts, informers.WithNamespace(c.opts.ServedNamespaces[0]))
+	}
+	factory := informers.NewSharedInformerFactoryWithOptions(cl, 0, informerOpts...)
+	informer := factory.Core().V1().Secrets()
+	c.secrets = informer.Lister()
+	if _, err := informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldSecret, ok1 := oldObj.(*corev1.Secret)
+			newSecret, ok2 := newObj.(*corev1.Secret)
+			if !ok1 || !ok2 || oldSecret.ResourceVersion == newSecret.ResourceVersion {
+				return
+			}
+			if _, used := c.usedSecrets.Load(newSecret.Namespace + "/" + newSecret.Name); used {
+				slog.Info("Secret updated, cached credentials refreshed", "namespace", newSecret.Namespace, "name", newSecret.Name)
+			}
+		},
+	}); err != nil {
+		return fmt.Errorf("failed to register secret event handler: %w", err)
+	}
+
+	factory.Start(stopCh)
+	for typ, ok := range factory.WaitForCacheSync(stopCh) {
+		if !ok {
+			return fmt.Errorf("failed to sync informer cache for %v", typ)
+		}
+	}
+	c.recorder = newEventRecorder(cl, stopCh)
+
+	if c.opts.CheckCertManagerVersion {
+		if err := checkCertManagerVersion(c.context(), dyn); err != nil {
+			return err
+		}
+	}
+
+	if c.opts.ClusterProxy {
+		if err := applyClusterProxy(c.context(), dyn, cl); err != nil {
+			return err
+		}
+	}
+
+	if c.opts.DefaultsConfigMap != "" {
+		defaults, err := newSolverDefaults(cl, c.opts.Namespace, c.opts.DefaultsConfigMap, stopCh)
+		if err != nil {
+			return err
+		}
+		c.defaults = defaults
+	}
+
+	annotator, err := newChallengeAnnotator(kubeClientConfig, stopCh)
+	if err != nil {
+		return err
+	}
+	c.annotator = annotator
+
+	if c.opts.CleanupQueueConfigMap != "" {
+		cleanups, err := newCleanupQueue(c, cl, c.opts.Namespace, c.opts.CleanupQueueConfigMap)
+		if err != nil {
+			return err
+		}
+		c.cleanups = cleanups
+		c.jobs = append(c.jobs, cleanups.job())
+	}
+
+	if c.opts.HTTP01IngressClass != "" {
+		http01, err := newHTTP01Solver(c.opts, kubeClientConfig)
+		if err != nil {
+			return err
+		}
+		c.jobs = append(c.jobs, http01.job())
+	}
+
+	if c.opts.ZoneBindings {
+		bindings, err := newZoneBindings(dyn, c, stopCh)
+		if err != nil {
+			return err
+		}
+		c.bindings = bindings
+		c.jobs = append(c.jobs, bindings.reconcileJob())
+	}
+
+	if c.opts.OrphanRecordMaxAge > 0 {
+		if c.apiKey() == "" {
+			slog.Warn("Orphaned record collection needs API_KEY, not starting it")
+		} else {
+			c.jobs = append(c.jobs, newOrphanCollector(c, c.opts.OrphanRecordMaxAge, c.opts.OrphanRecordGCInterval).job())
+		}
+	}
+
+	if c.opts.WatchIssuers {
+		watcher, err := newIssuerWatcher(c, kubeClientConfig, c.recorder)
+		if err != nil {
+			return err
+		}
+		c.jobs = append(c.jobs, watcher.job())
+	}
+
+	if c.opts.CheckAPIService {
+		c.apiService.Store(&apiServiceProbe{dyn: dyn, client: cl})
+	}
+
+	if c.opts.AdmissionBindAddress != "" {
+		c.startAdmissionServer(stopCh)
+	}
+
+	if err := c.startBackgroundJobs(c.context(), cl); err != nil {
+		return err
+	}
+
+	c.initialized.Store(true)
+	return nil
+}
+
+func (c *Solver) operationTimeout() time.Duration {
+	if c.opts.OperationTimeout > 0 {
+		return c.opts.OperationTimeout
+	}
+	return defaultOperationTimeout
+}
+
+// Initialized reports whether Initialize has completed, i.e. the solver's
+// informers have synced and it can resolve credentials.
+func (c *Solver) Initialized() bool {
+	return c.initialized.Load()
+}
+
+// withClientRateLimits applies the configured client-side rate limits for
+// API server requests.
+func (c *Solver) withClientRateLimits(cfg *rest.Config) *rest.Config {
+	cfg = rest.CopyConfig(cfg)
+	if c.opts.KubeAPIQPS > 0 {
+		cfg.QPS = c.opts.KubeAPIQPS
+	}
+	if c.opts.KubeAPIBurst > 0 {
+		cfg.Burst = c.opts.KubeAPIBurst
+	}
+	return cfg
+}
+
+// namespaceServed reports whether challenges from namespace are handled by
+// this solver.
+func (c *Solver) namespaceServed(namespace string) bool {
+	if len(c.opts.ServedNamespaces) == 0 {
+		return true
+	}
+	for _, ns := range c.opts.ServedNamespaces {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// getSecret reads a Secret from the informer cache, falling back to the API
+// server for Secrets created since the last watch event was processed.
+func (c *Solver) getSecret(ctx context.Context, namespace, name string) (*corev1.Secret, error) {
+	if c.secrets != nil {
+		secret, err := c.secrets.Secrets(namespace).Get(name)
+		if err == nil {
+			return secret, nil
+		}
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+	}
+	return c.client.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+func (c *Solver) loadConfig(ctx context.Context, ch *v1alpha1.ChallengeRequest) (bunnyNetDNSConfig, error) {
+	if !c.namespaceServed(ch.ResourceNamespace) {
+		return bunnyNetDNSConfig{}, fmt.Errorf("namespace %q is not served by this webhook", ch.ResourceNamespace)
+	}
+
+	var raw []byte
+	if ch.Config != nil {
+		raw = ch.Config.Raw
+	}
+
+	defaults, err := c.defaults.get()
+	if err != nil {
+		return bunnyNetDNSConfig{}, err
+	}
+	if raw, err = mergeConfig(defaults, raw); err != nil {
+		return bunnyNetDNSConfig{}, err
+	}
+
+	cfg, err := decodeConfig(raw)
+	if err != nil {
+		return cfg, err
+	}
+	cfg = c.configure(cfg).forZone(ch.ResolvedZone)
+	if cfg.APIURL == "" {
+		cfg.APIURL = c.opts.APIBase
+	}
+	if !cfg.isBunny() {
+		// Other providers read their own credentials.
+		if _, ok := c.opts.Providers[cfg.Provider]; !ok {
+			return cfg, &configFieldError{Field: "provider", Reason: fmt.Sprintf("%q is not a registered provider", cfg.Provider)}
+		}
+		return cfg, nil
+	}
+
+	apiKey, source, err := c.resolveAPIKey(ctx, cfg, ch)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.APIKey, cfg.keySource = apiKey, source
+	if source != keyFromIssuer && !c.apiURLAllowed(cfg.APIURL) {
+		return cfg, &configFieldError{
+			Field:  "apiURL",
+			Reason: fmt.Sprintf("%s may only be used with the Issuer's own apiKeySecretRef, unless the webhook allows it", cfg.APIURL),
+		}
+	}
+	if apiKey == c.apiKey() {
+		cfg.fallbackKeys = c.opts.FallbackAPIKeys
+	}
+
+	return cfg, nil
+}
+
+// configure attaches the solver's shared API call machinery to cfg: the
+// instrumentation, retry policy, rate limiter, circuit breakers and zone
+// cache, and the solver's auth scheme unless cfg has its own.
+func (c *Solver) configure(cfg bunnyNetDNSConfig) bunnyNetDNSConfig {
+	cfg.instrumentation = c.opts.Instrumentation
+	cfg.retry = c.opts.Retry
+	cfg.limiter = c.limiter
+	cfg.breakers = c.breakers
+	cfg.zones = c.zones
+	if cfg.AuthScheme == "" {
+		cfg.AuthScheme = c.opts.AuthScheme
+	}
+	return cfg
+}
+
+// ambientConfig returns the config for API calls made with the webhook-wide
+// key outside of challenges, e.g. by the admin and external-dns APIs. They
+// share the rate limits and breakers of the solver's challenges.
+func (c *Solver) ambientConfig() bunnyNetDNSConfig {
+	return c.configure(bunnyNetDNSConfig{
+		APIURL:       c.opts.APIBase,
+		APIKey:       c.apiKey(),
+		keySource:    keyAmbient,
+		fallbackKeys: c.opts.FallbackAPIKeys,
+	})
+}
+
+// resolveAPIKey returns the API key for a challenge. A secretRef in the
+// Issuer config always wins; the Secret is looked up in the resource
+// namespace cert-manager assigned to the challenge, which is the Issuer's
+// namespace or the cluster resource namespace for ClusterIssuers.
+//
+// Without a secretRef, a BunnyZoneBinding covering the challenge's zone
+// supplies the key. The webhook's own key is ambient credentials in
+// cert-manager's terms and is only used when the issuer type is allowed to
+// use them.
+func (c *Solver) resolveAPIKey(ctx context.Context, cfg bunnyNetDNSConfig, ch *v1alpha1.ChallengeRequest) (string, keySource, error) {
+	ref := cfg.APIKeySecretRef
+	if ref == nil {
+		if binding := c.bindings.match(ch.ResolvedZone, ch.ResourceNamespace); binding != nil {
+			apiKey, err := c.bindingAPIKey(ctx, binding)
+			return apiKey, keyFromBinding, err
+		}
+		if !ch.AllowAmbientCredentials {
+			return "", keyAmbient, &configFieldError{
+				Field:  "apiKeySecretRef",
+				Reason: "is required because ambient credentials are not allowed for this issuer",
+			}
+		}
+		apiKey := c.apiKey()
+		if apiKey == "" {
+			return "", keyAmbient, errors.New(errMissingAPIKey)
+		}
+		warnDeprecated("API_KEY")
+		return apiKey, keyAmbient, nil
+	}
+
+	apiKey, err := c.secretValue(ctx, ch.ResourceNamespace, ref.Name, ref.Key)
+	return apiKey, keyFromIssuer, err
+}
+
+// apiURLAllowed reports whether credentials the Issuer doesn't own may be
+// sent to apiURL: the solver's and the process-wide API base and the allowed
+// API URLs.
+func (c *Solver) apiURLAllowed(apiURL string) bool {
+	apiURL = strings.TrimSuffix(apiURL, "/")
+	if apiURL == "" || apiURL == strings.TrimSuffix(c.opts.APIBase, "/") || apiURL == bunnyAPIBase {
+		return true
+	}
+	for _, allowed := range c.opts.AllowedAPIURLs {
+		if apiURL == strings.TrimSuffix(allowed, "/") {
+			return true
+		}
+	}
+	return false
+}
+
+// apiKey returns the current webhook-wide API key.
+func (c *Solver) apiKey() string {
+	if c.opts.APIKeyProvider != nil {
+		return c.opts.APIKeyProvider.APIKey()
+	}
+	return c.opts.APIKey
+}
+
+// The API types are those of package bunny.
+type (
+	ZoneResponse = bunny.ZoneList
+	Item         = bunny.Zone
+	Record       = bunny.Record
+	APIError     = bunny.APIError
+)
@@ -0,0 +1,361 @@
+package solver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestNamespaceServed(t *testing.T) {
+	assert.True(t, New(Options{}).namespaceServed("anything"))
+
+	s := New(Options{ServedNamespaces: []string{"team-a", "team-b"}})
+	assert.True(t, s.namespaceServed("team-b"))
+	assert.False(t, s.namespaceServed("team-c"))
+}
+
+func TestTarget_IssuerConfig(t *testing.T) {
+	s := New(Options{Providers: map[string]ProviderFactory{
+		"recording": func([]byte, SecretFunc) (Provider, error) { return &recordingProvider{}, nil },
+	}})
+	ch := &v1alpha1.ChallengeRequest{
+		ResolvedFQDN: "_acme-challenge.www.dev.example.com.",
+		ResolvedZone: "example.com.",
+		Config:       &apiextensionsv1.JSON{Raw: []byte(`{"provider":"recording","ttl":120,"zone":"dev.example.com","propagationTimeout":"3m","pollInterval":"10s"}`)},
+	}
+
+	target, cfg, err := s.target(context.Background(), ch)
+	require.NoError(t, err)
+	assert.Equal(t, 120, cfg.TTL)
+	assert.Equal(t, 3*time.Minute, cfg.PropagationTimeout)
+	assert.Equal(t, 10*time.Second, cfg.PollInterval)
+	assert.Equal(t, "dev.example.com.", target.ResolvedZone)
+	assert.Equal(t, "example.com.", ch.ResolvedZone, "the request must not be modified")
+
+	ch.Config.Raw = []byte(`{"provider":"recording","zone":"example.net"}`)
+	_, _, err = s.target(context.Background(), ch)
+	var fieldErr *configFieldError
+	require.ErrorAs(t, err, &fieldErr)
+	assert.Equal(t, "zone", fieldErr.Field)
+}
+
+func TestPresent_KeepsForeignKeysFromIssuerURLs(t *testing.T) {
+	var leaked atomic.Int32
+	issuerAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		leaked.Add(1)
+	}))
+	defer issuerAPI.Close()
+
+	binding, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&BunnyZoneBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "team"},
+		Spec: BunnyZoneBindingSpec{
+			Zones:           []string{"team.example.com"},
+			APIKeySecretRef: BindingSecretRef{Namespace: "webhook", Name: "binding", Key: "api-key"},
+		},
+	})
+	require.NoError(t, err)
+	informer := cache.NewSharedIndexInformer(&cache.ListWatch{}, &unstructured.Unstructured{}, 0, cache.Indexers{})
+	require.NoError(t, informer.GetStore().Add(&unstructured.Unstructured{Object: binding}))
+
+	s := New(Options{APIKey: "ambient"})
+	s.bindings = &zoneBindings{informer: informer, solver: s}
+	s.client = fake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "webhook", Name: "binding"},
+			Data:       map[string][]byte{"api-key": []byte("binding")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "bunny"},
+			Data:       map[string][]byte{"api-key": []byte("own")},
+		},
+	)
+
+	for _, test := range []struct{ zone, config string }{
+		{"example.com.", `{"apiURL":%q}`},
+		{"example.com.", `{"zoneEndpoints":[{"zones":["example.com"],"apiURL":%q}]}`},
+		{"team.example.com.", `{"apiURL":%q}`},
+		{"team.example.com.", `{"zoneEndpoints":[{"zones":["team.example.com"],"apiURL":%q}]}`},
+	} {
+		ch := &v1alpha1.ChallengeRequest{
+			Key:                     "token",
+			ResolvedFQDN:            "_acme-challenge." + test.zone,
+			ResolvedZone:            test.zone,
+			ResourceNamespace:       "team-a",
+			AllowAmbientCredentials: true,
+			Config:                  &apiextensionsv1.JSON{Raw: []byte(fmt.Sprintf(test.config, issuerAPI.URL))},
+		}
+		var fieldErr *configFieldError
+		require.ErrorAs(t, s.Present(ch), &fieldErr, test.config)
+		assert.Equal(t, "apiURL", fieldErr.Field)
+	}
+	assert.Zero(t, leaked.Load(), "no request may reach the Issuer's endpoint")
+
+	// The Issuer's own key may go to its own endpoint.
+	ch := &v1alpha1.ChallengeRequest{
+		ResolvedZone:      "team.example.com.",
+		ResourceNamespace: "team-a",
+		Config:            &apiextensionsv1.JSON{Raw: []byte(fmt.Sprintf(`{"apiURL":%q,"apiKeySecretRef":{"name":"bunny","key":"api-key"}}`, issuerAPI.URL))},
+	}
+	cfg, err := s.loadConfig(context.Background(), ch)
+	require.NoError(t, err)
+	assert.Equal(t, "own", cfg.APIKey)
+
+	// So may other keys to endpoints the operator allows.
+	s.opts.AllowedAPIURLs = []string{issuerAPI.URL + "/"}
+	ch.Config.Raw = []byte(fmt.Sprintf(`{"apiURL":%q}`, issuerAPI.URL))
+	cfg, err = s.loadConfig(context.Background(), ch)
+	require.NoError(t, err)
+	assert.Equal(t, "binding", cfg.APIKey)
+}
+
+func TestInitialize_Once(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+	stopCh := make(chan struct{})
+	close(stopCh)
+
+	s := New(Options{})
+	err := s.Initialize(&rest.Config{Host: srv.URL}, stopCh)
+	require.Error(t, err)
+	made := requests.Load()
+
+	// The API servers of other groups initialize the same solver again.
+	assert.Equal(t, err, s.Initialize(&rest.Config{Host: srv.URL}, stopCh))
+	assert.Equal(t, made, requests.Load(), "only the first call may do any work")
+}
+
+func TestLoadConfig_APIKeySecretRef(t *testing.T) {
+	s := New(Options{APIKey: "ambient"})
+	s.client = fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "bunny"},
+		Data:       map[string][]byte{"api-key": []byte("from-secret\n")},
+	})
+
+	ch := &v1alpha1.ChallengeRequest{
+		ResourceNamespace: "team-a",
+		Config:            &apiextensionsv1.JSON{Raw: []byte(`{"apiKeySecretRef":{"name":"bunny","key":"api-key"}}`)},
+	}
+	cfg, err := s.loadConfig(context.Background(), ch)
+	require.NoError(t, err)
+	assert.Equal(t, "from-secret", cfg.APIKey)
+
+	ch.Config.Raw = []byte(`{"apiKeySecretRef":{"name":"bunny","key":"other"}}`)
+	_, err = s.loadConfig(context.Background(), ch)
+	assert.ErrorContains(t, err, `key "other" not found`)
+
+	// Without a reference the env key is only used where cert-manager
+	// allows ambient credentials, i.e. for ClusterIssuers.
+	ch.Config = nil
+	var fieldErr *configFieldError
+	_, err = s.loadConfig(context.Background(), ch)
+	require.ErrorAs(t, err, &fieldErr)
+	assert.Equal(t, "apiKeySecretRef", fieldErr.Field)
+
+	ch.AllowAmbientCredentials = true
+	cfg, err = s.loadConfig(context.Background(), ch)
+	require.NoError(t, err)
+	assert.Equal(t, "ambient", cfg.APIKey)
+}
+
+func TestGetSecret_InformerCache(t *testing.T) {
+	cached := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "bunny"},
+		Data:       map[string][]byte{"api-key": []byte("old")},
+	}
+	client := fake.NewSimpleClientset(cached)
+	factory := informers.NewSharedInformerFactory(client, 0)
+	s := New(Options{})
+	s.client = client
+	s.secrets = factory.Core().V1().Secrets().Lister()
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	factory.Start(stopCh)
+	factory.WaitForCacheSync(stopCh)
+	client.ClearActions()
+
+	key, err := s.secretValue(context.Background(), "team-a", "bunny", "api-key")
+	require.NoError(t, err)
+	assert.Equal(t, "old", key)
+	assert.Empty(t, client.Actions(), "cached Secrets are not read from the API server")
+
+	// A rotated key is picked up from the watch.
+	rotated := cached.DeepCopy()
+	rotated.Data["api-key"] = []byte("new")
+	_, err = client.CoreV1().Secrets("team-a").Update(context.Background(), rotated, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		key, err := s.secretValue(context.Background(), "team-a", "bunny", "api-key")
+		return err == nil && key == "new"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Secrets the informer hasn't seen yet are read from the API server.
+	s.secrets = corelisters.NewSecretLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{}))
+	client.ClearActions()
+	key, err = s.secretValue(context.Background(), "team-a", "bunny", "api-key")
+	require.NoError(t, err)
+	assert.Equal(t, "new", key)
+	require.Len(t, client.Actions(), 1)
+	assert.Equal(t, "get", client.Actions()[0].GetVerb())
+
+	_, err = s.secretValue(context.Background(), "team-a", "missing", "api-key")
+	assert.ErrorContains(t, err, "failed to get secret team-a/missing")
+}
+
+func TestGetZonePaginatesSearch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page") {
+		case "1":
+			fmt.Fprint(w, `{"Items":[{"Id":1,"Domain":"myexample.com"},{"Id":2,"Domain":"example.com.au"}],"HasMoreItems":true}`)
+		case "2":
+			fmt.Fprint(w, `{"Items":[{"Id":3,"Domain":"Example.com"}],"HasMoreItems":false}`)
+		default:
+			t.Errorf("unexpected page %q", r.URL.Query().Get("page"))
+		}
+	}))
+	defer srv.Close()
+
+	cfg := bunnyNetDNSConfig{APIURL: srv.URL}
+	id, err := GetZoneID(context.Background(), "example.com.", cfg)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, id)
+
+	_, err = GetZoneID(context.Background(), "example.co.", cfg)
+	assert.EqualError(t, err, "no DNS zone found for example.co, only similarly named zones: myexample.com, example.com.au, Example.com")
+}
+
+func TestGetZoneSearchesPunycode(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "xn--bcher-kva.example", r.URL.Query().Get("search"))
+		fmt.Fprint(w, `{"Items":[{"Id":4,"Domain":"xn--bcher-kva.example"}]}`)
+	}))
+	defer srv.Close()
+
+	id, err := GetZoneID(context.Background(), "Bücher.example.", bunnyNetDNSConfig{APIURL: srv.URL})
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, id)
+}
+
+func TestHostedZoneWalksUpToParent(t *testing.T) {
+	var searches []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		search := r.URL.Query().Get("search")
+		searches = append(searches, search)
+		if search == "example.com" {
+			fmt.Fprint(w, `{"Items":[{"Id":7,"Domain":"example.com"}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"Items":[]}`)
+	}))
+	defer srv.Close()
+
+	cfg := bunnyNetDNSConfig{APIURL: srv.URL}
+	zone, id, err := hostedZone(context.Background(), cfg, "b.example.com.", "_acme-challenge.a.b.example.com.")
+	require.NoError(t, err)
+	assert.Equal(t, "example.com.", zone)
+	assert.EqualValues(t, 7, id)
+	assert.Equal(t, []string{"b.example.com", "a.b.example.com", "example.com"}, searches)
+
+	_, _, err = hostedZone(context.Background(), cfg, "example.net.", "_acme-challenge.example.net.")
+	assert.ErrorIs(t, err, errZoneNotFound)
+}
+
+func TestDeleteTXTRecordFetchesZoneRecords(t *testing.T) {
+	var deleted string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/dnszone":
+			// Search results don't carry the records of large zones.
+			fmt.Fprint(w, `{"Items":[{"Id":5,"Domain":"example.com"}]}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/dnszone/5":
+			fmt.Fprint(w, `{"Id":5,"Domain":"example.com","Records":[{"Id":11,"Type":3,"Name":"_acme-challenge","Value":"token"}]}`)
+		case r.Method == http.MethodDelete:
+			deleted = r.URL.Path
+		}
+	}))
+	defer srv.Close()
+
+	require.NoError(t, deleteTXTRecord(context.Background(), bunnyNetDNSConfig{APIURL: srv.URL}, "example.com.", "_acme-challenge.example.com.", "token"))
+	assert.Equal(t, "/dnszone/5/records/11", deleted)
+}
+
+func TestDeleteTXTRecordChecksStatus(t *testing.T) {
+	var deletes atomic.Int32
+	status := http.StatusForbidden
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			fmt.Fprint(w, `{"Id":5,"Domain":"example.com","Records":[{"Id":11,"Type":3,"Name":"_acme-challenge","Value":"token"}]}`)
+		case http.MethodDelete:
+			if deletes.Add(1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(status)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := bunnyNetDNSConfig{APIURL: srv.URL, ZoneID: 5, retry: RetryPolicy{BaseDelay: time.Millisecond}}
+	p := &bunnyProvider{cfg: cfg}
+	err := p.CleanUp(context.Background(), "example.com.", "_acme-challenge.example.com.", "token")
+	assert.ErrorContains(t, err, "status 403")
+	assert.EqualValues(t, 2, deletes.Load(), "the 503 is retried")
+
+	status = http.StatusNotFound
+	assert.NoError(t, p.CleanUp(context.Background(), "example.com.", "_acme-challenge.example.com.", "token"), "already deleted")
+}
+
+func TestCreateTXTRecordAtApex(t *testing.T) {
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		fmt.Fprint(w, `{"Id":7}`)
+	}))
+	defer srv.Close()
+
+	_, err := createTXTRecord(context.Background(), bunnyNetDNSConfig{APIURL: srv.URL}, 5, "_acme-challenge.example.com.", "_acme-challenge.example.com.", "token", 60)
+	require.NoError(t, err)
+	assert.Equal(t, "", body["Name"], "the apex is sent as an empty name")
+}
+
+func TestAPICallsHonourContext(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := GetZoneID(ctx, "example.com.", bunnyNetDNSConfig{APIURL: srv.URL})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second, "the deadline must not be retried past")
+}
@@ -0,0 +1,38 @@
+package solver
+
+import (
+	"context"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer creates the solver's spans from the global TracerProvider, so they
+// are only recorded once the program embedding the solver installs one.
+var tracer = otel.Tracer("github.com/cert-manager/webhook-example/pkg/solver")
+
+// startSpan starts a span named name. The returned function ends it,
+// marking it failed if err is non-nil.
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, func(err error)) {
+	ctx, span := tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+// challengeSpanAttrs are the attributes of the spans of operations on ch.
+func challengeSpanAttrs(ch *v1alpha1.ChallengeRequest) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("challenge.uid", string(ch.UID)),
+		attribute.String("challenge.namespace", ch.ResourceNamespace),
+		attribute.String("dns.fqdn", ch.ResolvedFQDN),
+		attribute.String("dns.zone", ch.ResolvedZone),
+	}
+}
@@ -0,0 +1,44 @@
+package solver
+
+import (
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func TestOperationSpans(t *testing.T) {
+	// The global provider only delegates to the first provider installed, so
+	// this is not undone; spans of later tests are recorded as well.
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	p := &recordingProvider{records: map[string]string{}}
+	s := New(Options{Providers: map[string]ProviderFactory{
+		"recording": func([]byte, SecretFunc) (Provider, error) { return p, nil },
+	}})
+	ch := &v1alpha1.ChallengeRequest{
+		UID:          "uid-1",
+		Key:          "token",
+		ResolvedFQDN: "_acme-challenge.example.com.",
+		ResolvedZone: "example.com.",
+		Config:       &apiextensionsv1.JSON{Raw: []byte(`{"provider":"recording"}`)},
+	}
+	require.NoError(t, s.Present(ch))
+	require.NoError(t, s.CleanUp(ch))
+	ch.Config.Raw = []byte(`{"provider":"unknown"}`)
+	require.Error(t, s.Present(ch))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	assert.Equal(t, "Present", spans[0].Name())
+	assert.Contains(t, spans[0].Attributes(), challengeSpanAttrs(ch)[0])
+	assert.Equal(t, "CleanUp", spans[1].Name())
+	assert.Equal(t, codes.Error, spans[2].Status().Code)
+}
@@ -0,0 +1,122 @@
+package solver
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+// RecordTransform rewrites the name and value of a challenge record before
+// it is submitted, for DNS setups with their own naming conventions. fqdn
+// and the returned name are fully qualified; the name must stay within zone.
+type RecordTransform func(zone, fqdn, value string) (name, newValue string, err error)
+
+// recordTemplate is a config-driven RecordTransform. Name and Value are
+// text/template templates over templateRecord; an empty template leaves its
+// part unchanged.
+type recordTemplate struct {
+	Name  string `json:"name,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
+// templateRecord is the data recordTemplate templates are executed with.
+type templateRecord struct {
+	Zone  string
+	FQDN  string
+	Value string
+	// Domain is FQDN without the _acme-challenge label and trailing dot.
+	Domain string
+}
+
+var templateFuncs = template.FuncMap{
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+}
+
+func parseRecordTemplate(name, text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	return template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+}
+
+func validateRecordTemplates(path string, templates []recordTemplate) error {
+	for i, t := range templates {
+		field := fmt.Sprintf("%s[%d]", path, i)
+		if t.Name == "" && t.Value == "" {
+			return &configFieldError{Field: field, Reason: "must set name or value"}
+		}
+		if _, err := parseRecordTemplate("name", t.Name); err != nil {
+			return &configFieldError{Field: field + ".name", Reason: err.Error()}
+		}
+		if _, err := parseRecordTemplate("value", t.Value); err != nil {
+			return &configFieldError{Field: field + ".value", Reason: err.Error()}
+		}
+	}
+	return nil
+}
+
+// transform implements RecordTransform. Templates are validated when the
+// config is decoded, so parse errors aren't expected here.
+func (t recordTemplate) transform(zone, fqdn, value string) (string, string, error) {
+	data := templateRecord{
+		Zone:   zone,
+		FQDN:   fqdn,
+		Value:  value,
+		Domain: strings.TrimSuffix(strings.TrimPrefix(fqdn, "_acme-challenge."), "."),
+	}
+	execute := func(name, text, unchanged string) (string, error) {
+		tmpl, err := parseRecordTemplate(name, text)
+		if err != nil || tmpl == nil {
+			return unchanged, err
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(buf.String()), nil
+	}
+
+	name, err := execute("name", t.Name, fqdn)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to execute name template: %w", err)
+	}
+	newValue, err := execute("value", t.Value, value)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to execute value template: %w", err)
+	}
+	return name, newValue, nil
+}
+
+// transformed returns the challenge with the Issuer's record templates and
+// then the solver's RecordTransforms applied to its record name and key.
+func (c *Solver) transformed(ch *v1alpha1.ChallengeRequest, cfg bunnyNetDNSConfig) (*v1alpha1.ChallengeRequest, error) {
+	transforms := make([]RecordTransform, 0, len(cfg.RecordTemplates)+len(c.opts.RecordTransforms))
+	for _, t := range cfg.RecordTemplates {
+		transforms = append(transforms, t.transform)
+	}
+	transforms = append(transforms, c.opts.RecordTransforms...)
+	if len(transforms) == 0 {
+		return ch, nil
+	}
+
+	out := *ch
+	for _, transform := range transforms {
+		name, value, err := transform(out.ResolvedZone, out.ResolvedFQDN, out.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to transform record: %w", err)
+		}
+		name = withTrailingDot(name)
+		if !withinZone(name, out.ResolvedZone) {
+			return nil, fmt.Errorf("failed to transform record: %q is outside zone %q", name, out.ResolvedZone)
+		}
+		out.ResolvedFQDN, out.Key = name, value
+	}
+	return &out, nil
+}
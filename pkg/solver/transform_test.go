@@ -0,0 +1,55 @@
+package solver
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransformed(t *testing.T) {
+	ch := &v1alpha1.ChallengeRequest{
+		ResolvedFQDN: "_acme-challenge.www.example.com.",
+		ResolvedZone: "example.com.",
+		Key:          "token",
+	}
+	cfg, err := decodeConfig([]byte(`{"recordTemplates":[{"name":"_acme-challenge.{{ replace \".\" \"-\" (trimSuffix \".example.com\" .Domain) }}.acme.{{ .Zone }}"}]}`))
+	require.NoError(t, err)
+
+	s := New(Options{RecordTransforms: []RecordTransform{
+		func(zone, fqdn, value string) (string, string, error) {
+			return fqdn, "v=" + value, nil
+		},
+	}})
+	got, err := s.transformed(ch, cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "_acme-challenge.www.acme.example.com.", got.ResolvedFQDN)
+	assert.Equal(t, "v=token", got.Key)
+	assert.Equal(t, "token", ch.Key, "the request must not be modified")
+
+	same, err := New(Options{}).transformed(ch, bunnyNetDNSConfig{})
+	require.NoError(t, err)
+	assert.Same(t, ch, same)
+}
+
+func TestTransformed_OutsideZone(t *testing.T) {
+	ch := &v1alpha1.ChallengeRequest{ResolvedFQDN: "_acme-challenge.example.com.", ResolvedZone: "example.com."}
+	cfg := bunnyNetDNSConfig{RecordTemplates: []recordTemplate{{Name: "_acme-challenge.badexample.com"}}}
+
+	_, err := New(Options{}).transformed(ch, cfg)
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "outside zone"), err.Error())
+}
+
+func TestDecodeConfig_RecordTemplateErrors(t *testing.T) {
+	_, err := decodeConfig([]byte(`{"recordTemplates":[{"name":"{{ .FQDN"}]}`))
+	var fieldErr *configFieldError
+	require.ErrorAs(t, err, &fieldErr)
+	assert.Equal(t, "recordTemplates[0].name", fieldErr.Field)
+
+	_, err = decodeConfig([]byte(`{"apiVersion":"v1beta1","recordTemplates":[{}]}`))
+	require.ErrorAs(t, err, &fieldErr)
+	assert.Equal(t, "recordTemplates[0]", fieldErr.Field)
+}
@@ -0,0 +1,232 @@
+package solver
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	// DefaultVaultRefreshInterval is how often a VaultKey is read again.
+	DefaultVaultRefreshInterval = 5 * time.Minute
+
+	defaultVaultKVMount            = "secret"
+	defaultVaultKey                = "api-key"
+	defaultVaultKubernetesMount    = "kubernetes"
+	defaultServiceAccountTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
+// VaultConfig locates an API key in a HashiCorp Vault KV v2 secret engine.
+type VaultConfig struct {
+	// Address is the Vault server URL, e.g. https://vault.example.com:8200.
+	Address string
+	// Namespace is the Vault Enterprise namespace, if any.
+	Namespace string
+	// CACertFile verifies the Vault server instead of the system roots.
+	CACertFile string
+
+	// KVMount is the mount of the KV v2 engine, "secret" when empty.
+	KVMount string
+	// SecretPath is the path of the secret within the engine, and Key the
+	// field holding the API key, "api-key" when empty.
+	SecretPath string
+	Key        string
+
+	// Token authenticates with a Vault token. Without it, the webhook logs
+	// in with its service account token through the Kubernetes auth method
+	// mounted at KubernetesMount ("kubernetes" when empty) as
+	// KubernetesRole.
+	Token           string
+	KubernetesRole  string
+	KubernetesMount string
+	// ServiceAccountTokenFile is the projected service account token used
+	// for Kubernetes auth, the default in-cluster path when empty.
+	ServiceAccountTokenFile string
+}
+
+// VaultKey is an API key read from Vault. Watch reads it again periodically
+// so a key rotated in Vault is picked up without a restart.
+type VaultKey struct {
+	cfg    VaultConfig
+	client *http.Client
+
+	mu  sync.RWMutex
+	key string
+}
+
+// NewVaultKey reads the API key described by cfg from Vault.
+func NewVaultKey(ctx context.Context, cfg VaultConfig) (*VaultKey, error) {
+	if cfg.Address == "" || cfg.SecretPath == "" {
+		return nil, errors.New("a Vault address and secret path are required")
+	}
+	if cfg.Token == "" && cfg.KubernetesRole == "" {
+		return nil, errors.New("a Vault token or Kubernetes auth role is required")
+	}
+	if cfg.KVMount == "" {
+		cfg.KVMount = defaultVaultKVMount
+	}
+	if cfg.Key == "" {
+		cfg.Key = defaultVaultKey
+	}
+	if cfg.KubernetesMount == "" {
+		cfg.KubernetesMount = defaultVaultKubernetesMount
+	}
+	if cfg.ServiceAccountTokenFile == "" {
+		cfg.ServiceAccountTokenFile = defaultServiceAccountTokenFile
+	}
+	cfg.Address = strings.TrimSuffix(cfg.Address, "/")
+	registerSecret(cfg.Token)
+
+	v := &VaultKey{cfg: cfg, client: &http.Client{Timeout: 30 * time.Second}}
+	if cfg.CACertFile != "" {
+		pem, err := os.ReadFile(cfg.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Vault CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.CACertFile)
+		}
+		v.client.Transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+		}
+	}
+	if _, err := v.refresh(ctx); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// APIKey returns the key last read from Vault.
+func (v *VaultKey) APIKey() string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.key
+}
+
+// Watch reads the key again every interval until ctx is done. Failures keep
+// the previous key.
+func (v *VaultKey) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultVaultRefreshInterval
+	}
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		changed, err := v.refresh(ctx)
+		if err != nil {
+			slog.Error("failed to refresh API key from Vault", "path", v.cfg.SecretPath, "error", err)
+			return
+		}
+		if changed {
+			slog.Info("Refreshed API key from Vault", "path", v.cfg.SecretPath)
+		}
+	}, interval)
+}
+
+func (v *VaultKey) refresh(ctx context.Context) (bool, error) {
+	token := v.cfg.Token
+	if token == "" {
+		var err error
+		if token, err = v.login(ctx); err != nil {
+			return false, err
+		}
+	}
+
+	var secret struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	path := fmt.Sprintf("/v1/%s/data/%s", strings.Trim(v.cfg.KVMount, "/"), strings.Trim(v.cfg.SecretPath, "/"))
+	if err := v.call(ctx, http.MethodGet, path, token, nil, &secret); err != nil {
+		return false, fmt.Errorf("failed to read Vault secret: %w", err)
+	}
+	key, _ := secret.Data.Data[v.cfg.Key].(string)
+	if key = strings.TrimSpace(key); key == "" {
+		return false, fmt.Errorf("Vault secret %s has no %q field", v.cfg.SecretPath, v.cfg.Key)
+	}
+	registerSecret(key)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	changed := v.key != "" && v.key != key
+	v.key = key
+	return changed, nil
+}
+
+// login exchanges the service account token for a Vault token.
+func (v *VaultKey) login(ctx context.Context) (string, error) {
+	jwt, err := os.ReadFile(v.cfg.ServiceAccountTokenFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read service account token: %w", err)
+	}
+	body := map[string]string{"role": v.cfg.KubernetesRole, "jwt": strings.TrimSpace(string(jwt))}
+	var auth struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	path := fmt.Sprintf("/v1/auth/%s/login", strings.Trim(v.cfg.KubernetesMount, "/"))
+	if err := v.call(ctx, http.MethodPost, path, "", body, &auth); err != nil {
+		return "", fmt.Errorf("failed to log in to Vault: %w", err)
+	}
+	if auth.Auth.ClientToken == "" {
+		return "", errors.New("failed to log in to Vault: no client token returned")
+	}
+	registerSecret(auth.Auth.ClientToken)
+	return auth.Auth.ClientToken, nil
+}
+
+func (v *VaultKey) call(ctx context.Context, method, path, token string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, v.cfg.Address+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if v.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.cfg.Namespace)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package solver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fakeVault(t *testing.T, apiKey *string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/auth/kubernetes/login", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if body["role"] != "bunny-webhook" || body["jwt"] != "sa-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"auth":{"client_token":"login-token"}}`))
+	})
+	mux.HandleFunc("GET /v1/kv/data/bunny/dns", func(w http.ResponseWriter, r *http.Request) {
+		if tok := r.Header.Get("X-Vault-Token"); tok != "root-token" && tok != "login-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"data": map[string]string{"api-key": *apiKey}},
+		})
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestVaultKeyToken(t *testing.T) {
+	apiKey := "vault-api-key-1"
+	srv := fakeVault(t, &apiKey)
+
+	v, err := NewVaultKey(context.Background(), VaultConfig{Address: srv.URL, Token: "root-token", KVMount: "kv", SecretPath: "bunny/dns"})
+	require.NoError(t, err)
+	assert.Equal(t, "vault-api-key-1", v.APIKey())
+
+	apiKey = "vault-api-key-2"
+	changed, err := v.refresh(context.Background())
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "vault-api-key-2", v.APIKey())
+
+	_, err = NewVaultKey(context.Background(), VaultConfig{Address: srv.URL, Token: "wrong-token", KVMount: "kv", SecretPath: "bunny/dns"})
+	assert.Error(t, err)
+}
+
+func TestVaultKeyKubernetesAuth(t *testing.T) {
+	apiKey := "vault-api-key-1"
+	srv := fakeVault(t, &apiKey)
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("sa-token\n"), 0o600))
+
+	v, err := NewVaultKey(context.Background(), VaultConfig{
+		Address:                 srv.URL,
+		KVMount:                 "kv",
+		SecretPath:              "bunny/dns",
+		KubernetesRole:          "bunny-webhook",
+		ServiceAccountTokenFile: tokenFile,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "vault-api-key-1", v.APIKey())
+	assert.Equal(t, redacted, redact("login-token"))
+}
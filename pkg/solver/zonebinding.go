@@ -0,0 +1,200 @@
+package solver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+var zoneBindingGVR = schema.GroupVersionResource{
+	Group:    "webhook.bunny.net",
+	Version:  "v1alpha1",
+	Resource: "bunnyzonebindings",
+}
+
+const zoneBindingReconcileInterval = time.Minute
+
+// BunnyZoneBinding binds DNS zones to the Secret holding the API key for the
+// Bunny account that hosts them. It is cluster scoped so platform teams can
+// manage DNS access centrally instead of in every Issuer.
+type BunnyZoneBinding struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BunnyZoneBindingSpec   `json:"spec"`
+	Status BunnyZoneBindingStatus `json:"status,omitempty"`
+}
+
+type BunnyZoneBindingSpec struct {
+	// Zones the binding applies to. A zone also covers its subdomains.
+	Zones []string `json:"zones"`
+
+	// APIKeySecretRef references the Secret holding the Bunny API key.
+	APIKeySecretRef BindingSecretRef `json:"apiKeySecretRef"`
+
+	// AllowedNamespaces restricts which challenge resource namespaces may
+	// use the binding. Empty allows all namespaces.
+	AllowedNamespaces []string `json:"allowedNamespaces,omitempty"`
+}
+
+type BindingSecretRef struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Key       string `json:"key"`
+}
+
+type BunnyZoneBindingStatus struct {
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// zoneBindings serves binding lookups from an informer on every replica.
+type zoneBindings struct {
+	client   dynamic.Interface
+	informer cache.SharedIndexInformer
+	solver   *Solver
+}
+
+func newZoneBindings(client dynamic.Interface, solver *Solver, stopCh <-chan struct{}) (*zoneBindings, error) {
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(client, 0)
+	informer := factory.ForResource(zoneBindingGVR).Informer()
+
+	factory.Start(stopCh)
+	for gvr, ok := range factory.WaitForCacheSync(stopCh) {
+		if !ok {
+			return nil, fmt.Errorf("failed to sync informer cache for %v", gvr)
+		}
+	}
+
+	return &zoneBindings{client: client, informer: informer, solver: solver}, nil
+}
+
+func (b *zoneBindings) list() []*BunnyZoneBinding {
+	var bindings []*BunnyZoneBinding
+	for _, obj := range b.informer.GetStore().List() {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		binding := &BunnyZoneBinding{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, binding); err != nil {
+			slog.Error("failed to decode BunnyZoneBinding", "name", u.GetName(), "error", err)
+			continue
+		}
+		bindings = append(bindings, binding)
+	}
+	return bindings
+}
+
+// match returns the binding with the most specific zone covering zone that
+// namespace is allowed to use.
+func (b *zoneBindings) match(zone, namespace string) *BunnyZoneBinding {
+	if b == nil {
+		return nil
+	}
+	return matchZoneBinding(b.list(), zone, namespace)
+}
+
+func matchZoneBinding(bindings []*BunnyZoneBinding, zone, namespace string) *BunnyZoneBinding {
+	zone = foldName(zone)
+
+	var best *BunnyZoneBinding
+	bestLen := -1
+	for _, binding := range bindings {
+		if !namespaceAllowed(binding.Spec.AllowedNamespaces, namespace) {
+			continue
+		}
+		for _, z := range binding.Spec.Zones {
+			z = foldName(z)
+			if (zone == z || strings.HasSuffix(zone, "."+z)) && len(z) > bestLen {
+				best, bestLen = binding, len(z)
+			}
+		}
+	}
+	return best
+}
+
+func namespaceAllowed(allowed []string, namespace string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, ns := range allowed {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// reconcileJob reports on each binding whether its Secret can be read. It
+// writes status, so it runs on the leader only.
+func (b *zoneBindings) reconcileJob() BackgroundJob {
+	return BackgroundJob{
+		Name: "zone-binding-reconciler",
+		Run: func(ctx context.Context) {
+			wait.UntilWithContext(ctx, b.reconcileAll, zoneBindingReconcileInterval)
+		},
+	}
+}
+
+func (b *zoneBindings) reconcileAll(ctx context.Context) {
+	for _, binding := range b.list() {
+		if err := b.reconcile(ctx, binding); err != nil {
+			slog.Error("failed to reconcile BunnyZoneBinding", "name", binding.Name, "error", err)
+		}
+	}
+}
+
+func (b *zoneBindings) reconcile(ctx context.Context, binding *BunnyZoneBinding) error {
+	cond := metav1.Condition{
+		Type:               "Ready",
+		Status:             metav1.ConditionTrue,
+		Reason:             "SecretFound",
+		Message:            "API key Secret is readable",
+		ObservedGeneration: binding.Generation,
+	}
+	if _, err := b.solver.bindingAPIKey(ctx, binding); err != nil {
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = "SecretError"
+		cond.Message = err.Error()
+	}
+
+	existing := apimeta.FindStatusCondition(binding.Status.Conditions, cond.Type)
+	if existing != nil && existing.Status == cond.Status && existing.Message == cond.Message &&
+		existing.ObservedGeneration == cond.ObservedGeneration {
+		return nil
+	}
+	apimeta.SetStatusCondition(&binding.Status.Conditions, cond)
+
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(binding)
+	if err != nil {
+		return err
+	}
+	_, err = b.client.Resource(zoneBindingGVR).UpdateStatus(ctx, &unstructured.Unstructured{Object: obj}, metav1.UpdateOptions{})
+	return err
+}
+
+// bindingAPIKey reads the API key referenced by a binding.
+func (c *Solver) bindingAPIKey(ctx context.Context, binding *BunnyZoneBinding) (string, error) {
+	ref := binding.Spec.APIKeySecretRef
+	secret, err := c.getSecret(ctx, ref.Namespace, ref.Name)
+	if err != nil {
+		return "", fmt.Errorf("failed to get secret %s/%s: %w", ref.Namespace, ref.Name, err)
+	}
+	data, ok := secret.Data[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("key %q not found in secret %s/%s", ref.Key, ref.Namespace, ref.Name)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
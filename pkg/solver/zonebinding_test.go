@@ -0,0 +1,43 @@
+package solver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMatchZoneBinding(t *testing.T) {
+	binding := func(name string, zones []string, namespaces ...string) *BunnyZoneBinding {
+		return &BunnyZoneBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       BunnyZoneBindingSpec{Zones: zones, AllowedNamespaces: namespaces},
+		}
+	}
+	bindings := []*BunnyZoneBinding{
+		binding("apex", []string{"example.com"}),
+		binding("team", []string{"team.example.com."}, "team"),
+		binding("other", []string{"example.org"}),
+	}
+
+	tests := []struct {
+		zone, namespace string
+		expected        string
+	}{
+		{"example.com.", "default", "apex"},
+		{"sub.example.com.", "default", "apex"},
+		{"team.example.com.", "team", "team"},
+		{"a.team.example.com.", "team", "team"},
+		{"team.example.com.", "default", "apex"},
+		{"EXAMPLE.ORG.", "default", "other"},
+		{"myexample.com.", "default", ""},
+	}
+	for _, test := range tests {
+		got := matchZoneBinding(bindings, test.zone, test.namespace)
+		name := ""
+		if got != nil {
+			name = got.Name
+		}
+		assert.Equal(t, test.expected, name, "zone %s in namespace %s", test.zone, test.namespace)
+	}
+}
@@ -0,0 +1,73 @@
+package solver
+
+import (
+	"sync"
+	"time"
+)
+
+// zoneCache maps zone names to Bunny zone IDs for a while, so repeated
+// challenges for the same domain don't look the zone up every time. Entries
+// are per API endpoint and key, since accounts have different zones.
+type zoneCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[zoneCacheKey]zoneCacheEntry
+}
+
+type zoneCacheKey struct {
+	apiBase, apiKey, zone string
+}
+
+type zoneCacheEntry struct {
+	id      int64
+	expires time.Time
+}
+
+func newZoneCache(ttl time.Duration) *zoneCache {
+	return &zoneCache{ttl: ttl, now: time.Now, entries: make(map[zoneCacheKey]zoneCacheEntry)}
+}
+
+func cacheKey(cfg bunnyNetDNSConfig, zone string) zoneCacheKey {
+	return zoneCacheKey{apiBase: cfg.apiBase(), apiKey: cfg.APIKey, zone: foldName(zone)}
+}
+
+func (c *zoneCache) get(cfg bunnyNetDNSConfig, zone string) (int64, bool) {
+	if c == nil {
+		return 0, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := cacheKey(cfg, zone)
+	entry, ok := c.entries[key]
+	if !ok {
+		return 0, false
+	}
+	if !c.now().Before(entry.expires) {
+		delete(c.entries, key)
+		return 0, false
+	}
+	return entry.id, true
+}
+
+func (c *zoneCache) put(cfg bunnyNetDNSConfig, zone string, id int64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[cacheKey(cfg, zone)] = zoneCacheEntry{id: id, expires: c.now().Add(c.ttl)}
+}
+
+// forget drops zone, e.g. when a request with its cached ID failed because
+// the zone was recreated under a new ID.
+func (c *zoneCache) forget(cfg bunnyNetDNSConfig, zone string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, cacheKey(cfg, zone))
+}
@@ -0,0 +1,76 @@
+package solver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetZoneIDUsesCache(t *testing.T) {
+	var lookups atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups.Add(1)
+		fmt.Fprint(w, `{"Items":[{"Id":42,"Domain":"example.com"}]}`)
+	}))
+	defer srv.Close()
+
+	now := time.Now()
+	cache := newZoneCache(time.Minute)
+	cache.now = func() time.Time { return now }
+	cfg := bunnyNetDNSConfig{APIURL: srv.URL, APIKey: "key", zones: cache}
+
+	for i := 0; i < 3; i++ {
+		id, err := GetZoneID(context.Background(), "example.com.", cfg)
+		require.NoError(t, err)
+		assert.EqualValues(t, 42, id)
+	}
+	assert.EqualValues(t, 1, lookups.Load())
+
+	other := cfg
+	other.APIKey = "other-account"
+	_, err := GetZoneID(context.Background(), "example.com.", other)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, lookups.Load(), "entries are per account")
+
+	now = now.Add(time.Minute)
+	_, err = GetZoneID(context.Background(), "example.com", cfg)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, lookups.Load(), "expired entries are looked up again")
+
+	cache.forget(cfg, "example.com.")
+	_, err = GetZoneID(context.Background(), "example.com.", cfg)
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, lookups.Load())
+}
+
+func TestGetZoneDeduplicatesConcurrentLookups(t *testing.T) {
+	var lookups atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups.Add(1)
+		time.Sleep(200 * time.Millisecond)
+		fmt.Fprint(w, `{"Items":[{"Id":42,"Domain":"example.com"}]}`)
+	}))
+	defer srv.Close()
+
+	cfg := bunnyNetDNSConfig{APIURL: srv.URL, APIKey: "key"}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			zone, err := GetZone(context.Background(), "example.com.", cfg)
+			assert.NoError(t, err)
+			assert.Equal(t, 42, zone.Items[0].ID)
+		}()
+	}
+	wg.Wait()
+	assert.EqualValues(t, 1, lookups.Load())
+}
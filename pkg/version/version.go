@@ -0,0 +1,97 @@
+// Package version reports the build of the webhook. Release builds set the
+// variables with the linker:
+//
+//	go build -ldflags "-X github.com/cert-manager/webhook-example/pkg/version.version=v1.2.3 \
+//		-X github.com/cert-manager/webhook-example/pkg/version.commit=$(git rev-parse HEAD) \
+//		-X github.com/cert-manager/webhook-example/pkg/version.date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Builds without them fall back to the module and VCS information the Go
+// toolchain embeds.
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+const unknown = "unknown"
+
+var (
+	version string
+	commit  string
+	date    string
+)
+
+// Info describes a build.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Date      string `json:"date"`
+	GoVersion string `json:"goVersion"`
+	Platform  string `json:"platform"`
+}
+
+// String formats the build on one line, as printed by --version.
+func (i Info) String() string {
+	return fmt.Sprintf("%s (commit %s, built %s, %s, %s)", i.Version, i.Commit, i.Date, i.GoVersion, i.Platform)
+}
+
+var (
+	once sync.Once
+	info Info
+)
+
+// Get returns the build information of the running binary.
+func Get() Info {
+	once.Do(func() {
+		bi, _ := debug.ReadBuildInfo()
+		info = resolve(version, commit, date, bi)
+	})
+	return info
+}
+
+// resolve fills the values not set by the linker from the build info.
+func resolve(version, commit, date string, bi *debug.BuildInfo) Info {
+	i := Info{
+		Version:   version,
+		Commit:    commit,
+		Date:      date,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	if bi != nil {
+		if i.Version == "" && bi.Main.Version != "(devel)" {
+			i.Version = bi.Main.Version
+		}
+		modified := false
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if i.Commit == "" {
+					i.Commit = s.Value
+				}
+			case "vcs.time":
+				if i.Date == "" {
+					i.Date = s.Value
+				}
+			case "vcs.modified":
+				modified = s.Value == "true"
+			}
+		}
+		if modified && commit == "" && i.Commit != "" {
+			i.Commit += "-dirty"
+		}
+	}
+	if i.Version == "" {
+		i.Version = "dev"
+	}
+	if i.Commit == "" {
+		i.Commit = unknown
+	}
+	if i.Date == "" {
+		i.Date = unknown
+	}
+	return i
+}
@@ -0,0 +1,35 @@
+package version
+
+import (
+	"runtime/debug"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolve(t *testing.T) {
+	bi := &debug.BuildInfo{
+		Main: debug.Module{Version: "v0.3.0"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "abc123"},
+			{Key: "vcs.time", Value: "2024-05-01T10:00:00Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+
+	i := resolve("", "", "", bi)
+	assert.Equal(t, "v0.3.0", i.Version)
+	assert.Equal(t, "abc123-dirty", i.Commit)
+	assert.Equal(t, "2024-05-01T10:00:00Z", i.Date)
+
+	// Linker values win over the build info.
+	i = resolve("v1.0.0", "def456", "2024-06-01T00:00:00Z", bi)
+	assert.Equal(t, "v1.0.0", i.Version)
+	assert.Equal(t, "def456", i.Commit)
+	assert.Equal(t, "2024-06-01T00:00:00Z", i.Date)
+
+	i = resolve("", "", "", &debug.BuildInfo{Main: debug.Module{Version: "(devel)"}})
+	assert.Equal(t, "dev", i.Version)
+	assert.Equal(t, "unknown", i.Commit)
+	assert.Equal(t, "unknown", i.Date)
+}
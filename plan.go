@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	cmclient "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/cert-manager/webhook-example/pkg/solver"
+)
+
+type planReport struct {
+	Changes []solver.PlannedChange `json:"changes"`
+}
+
+// runPlan implements the plan subcommand, printing the DNS changes the
+// webhook would make for a Certificate or a list of domains as JSON without
+// applying them:
+//
+//	webhook plan [--certificate namespace/name] [domain...]
+//
+// It returns the process exit code, which is non-zero if any change could
+// not be planned.
+func runPlan(args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("plan", flag.ContinueOnError)
+	certificate := fs.String("certificate", "", "plan for the DNS names of this Certificate (namespace/name)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+
+	changes, err := planChanges(ctx, *certificate, fs.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(planReport{Changes: changes}); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to encode plan: %v\n", err)
+		return 1
+	}
+	for _, c := range changes {
+		if c.Error != "" {
+			return 1
+		}
+	}
+	return 0
+}
+
+func planChanges(ctx context.Context, certificate string, domains []string) ([]solver.PlannedChange, error) {
+	if certificate == "" {
+		if len(domains) == 0 {
+			return nil, fmt.Errorf("either --certificate or at least one domain is required")
+		}
+		return solver.Plan(ctx, apiKeys.APIKey(), domains, 0), nil
+	}
+
+	namespace, name, ok := strings.Cut(certificate, "/")
+	if !ok {
+		return nil, fmt.Errorf("--certificate must be namespace/name, got %q", certificate)
+	}
+
+	restConfig, err := loadRestConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubernetes config: %w", err)
+	}
+	client, err := cmclient.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cert-manager client: %w", err)
+	}
+	cert, err := client.CertmanagerV1().Certificates(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get certificate %s: %w", certificate, err)
+	}
+	return solver.PlanCertificate(ctx, apiKeys.APIKey(), cert)
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunPlan_RequiresTarget(t *testing.T) {
+	var out bytes.Buffer
+	assert.Equal(t, 1, runPlan(nil, &out))
+	assert.Empty(t, out.String())
+}
+
+func TestRunPlan_InvalidCertificate(t *testing.T) {
+	var out bytes.Buffer
+	assert.Equal(t, 1, runPlan([]string{"--certificate", "no-namespace"}, &out))
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/cert-manager/webhook-example/pkg/solver"
+)
+
+// SolverOptions configures an additional solver served next to the main
+// one under its own solverName, e.g. for a staging account. Everything not
+// set here is shared with the main solver.
+type SolverOptions struct {
+	Name       string `json:"name"`
+	APIKey     string `json:"apiKey,omitempty"`
+	APIKeyFile string `json:"apiKeyFile,omitempty"`
+	APIBase    string `json:"apiBase,omitempty"`
+}
+
+func validateSolvers(mainName string, solvers []SolverOptions) error {
+	if mainName == "" {
+		mainName = solver.DefaultName
+	}
+	names := map[string]bool{mainName: true}
+	for i, s := range solvers {
+		if s.Name == "" {
+			return fmt.Errorf("solvers[%d].name must be set", i)
+		}
+		if names[s.Name] {
+			return fmt.Errorf("solvers[%d].name %q is used by another solver", i, s.Name)
+		}
+		names[s.Name] = true
+		if s.APIKey != "" && s.APIKeyFile != "" {
+			return fmt.Errorf("solvers[%d]: only one of apiKey and apiKeyFile may be set", i)
+		}
+		if s.APIBase != "" {
+			if err := solver.ValidateAPIBase(s.APIBase); err != nil {
+				return fmt.Errorf("solvers[%d].apiBase: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// apply returns the options of the main solver adapted to s. The main
+// account's fallback and secondary keys don't carry over, and state kept in
+// the cluster is kept apart from the main solver's.
+func (s SolverOptions) apply(opts solver.Options) solver.Options {
+	opts.Name = s.Name
+	opts.APIKey = s.APIKey
+	opts.APIBase = s.APIBase
+	opts.FallbackAPIKeys = nil
+	opts.SecondaryAPIKey = ""
+	// Only the main solver serves the admission webhook and uploads HTTP01
+	// challenges.
+	opts.AdmissionBindAddress = ""
+	opts.HTTP01IngressClass = ""
+	if opts.LeaderElectionID != "" {
+		opts.LeaderElectionID += "-" + s.Name
+	}
+	if opts.CleanupQueueConfigMap != "" {
+		opts.CleanupQueueConfigMap += "-" + s.Name
+	}
+	return opts
+}
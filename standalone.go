@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/cert-manager/cert-manager/pkg/issuer/acme/dns/util"
+
+	"github.com/cert-manager/webhook-example/pkg/bunnylego"
+	"github.com/cert-manager/webhook-example/pkg/solver"
+)
+
+// runStandalone implements the present and cleanup subcommands, which run a
+// single challenge through the solver without Kubernetes:
+//
+//	webhook present [--zone zone] <fqdn> <key>
+//	webhook cleanup [--zone zone] <fqdn> <key>
+//
+// The webhook-wide API key is used. The zone defaults to the one found by an
+// SOA lookup of fqdn. It returns the process exit code.
+func runStandalone(action string, args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet(action, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	zone := fs.String("zone", "", "DNS zone containing fqdn (default: found by SOA lookup)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 {
+		fmt.Fprintf(stderr, "usage: webhook %s [--zone zone] <fqdn> <key>\n", action)
+		return 2
+	}
+
+	return runChallenge(action, fs.Arg(0), fs.Arg(1), *zone, options.solverOptions(), stderr)
+}
+
+// runCertbotHook implements the certbot-auth and certbot-cleanup
+// subcommands, to be used as certbot's --manual-auth-hook and
+// --manual-cleanup-hook. The domain and validation token are read from the
+// CERTBOT_DOMAIN and CERTBOT_VALIDATION environment variables. Unlike
+// cert-manager, certbot doesn't wait for the record to propagate, so the auth
+// hook only returns once Bunny's nameservers serve it. For HTTP01
+// challenges, for which certbot also sets CERTBOT_TOKEN, the validation is
+// uploaded to the Edge Storage zone instead.
+func runCertbotHook(action string, args []string, getenv func(string) string, stderr io.Writer) int {
+	fs := flag.NewFlagSet(action, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	zone := fs.String("zone", "", "DNS zone containing the domain (default: found by SOA lookup)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	domain, validation := getenv("CERTBOT_DOMAIN"), getenv("CERTBOT_VALIDATION")
+	if domain == "" || validation == "" {
+		fmt.Fprintf(stderr, "%s must be run by certbot: CERTBOT_DOMAIN and CERTBOT_VALIDATION are required\n", action)
+		return 2
+	}
+
+	if token := getenv("CERTBOT_TOKEN"); token != "" {
+		return runCertbotHTTPHook(action, domain, token, validation, stderr)
+	}
+
+	opts := options.solverOptions()
+	if action == "certbot-auth" {
+		action = "present"
+		opts.VerifyPropagation = true
+	} else {
+		action = "cleanup"
+	}
+	fqdn := "_acme-challenge." + strings.TrimPrefix(domain, "*.")
+	return runChallenge(action, fqdn, validation, *zone, opts, stderr)
+}
+
+// runCertbotHTTPHook uploads or deletes the HTTP01 challenge file for token
+// in the storage zone.
+func runCertbotHTTPHook(action, domain, token, keyAuth string, stderr io.Writer) int {
+	p, err := bunnylego.NewHTTPProviderConfig(&bunnylego.HTTPConfig{
+		StorageZone:     options.StorageZone,
+		StoragePassword: options.StoragePassword,
+		StorageEndpoint: options.StorageEndpoint,
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "HTTP01 challenges need storageZone and storagePassword: %v\n", err)
+		return 2
+	}
+	if action == "certbot-auth" {
+		err = p.Present(domain, token, keyAuth)
+	} else {
+		err = p.CleanUp(domain, token, keyAuth)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "%s failed: %v\n", action, err)
+		return 1
+	}
+	return 0
+}
+
+// runChallenge presents or cleans up the record for fqdn with the
+// webhook-wide API key and returns the process exit code.
+func runChallenge(action, fqdn, key, zone string, opts solver.Options, stderr io.Writer) int {
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+	ch, err := standaloneChallenge(ctx, action, fqdn, key, zone)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return 1
+	}
+
+	opts.CheckAPIService = false
+	opts.APIKeyProvider = apiKeys
+	s := solver.New(opts)
+	if action == "present" {
+		err = s.Present(ch)
+	} else {
+		err = s.CleanUp(ch)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "%s failed: %v\n", action, err)
+		return 1
+	}
+	return 0
+}
+
+// standaloneChallenge builds the request cert-manager would send for fqdn.
+func standaloneChallenge(ctx context.Context, action, fqdn, key, zone string) (*v1alpha1.ChallengeRequest, error) {
+	fqdn = dnsName(fqdn)
+	if zone == "" {
+		found, err := util.FindZoneByFqdn(ctx, fqdn, util.RecursiveNameservers)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find zone for %s, set --zone: %w", fqdn, err)
+		}
+		zone = found
+	}
+	zone = dnsName(zone)
+	if fqdn != zone && !strings.HasSuffix(fqdn, "."+zone) {
+		return nil, fmt.Errorf("%s is not in zone %s", fqdn, zone)
+	}
+
+	ch := &v1alpha1.ChallengeRequest{
+		Action:                  v1alpha1.ChallengeActionPresent,
+		Type:                    "dns-01",
+		DNSName:                 strings.TrimPrefix(strings.TrimSuffix(fqdn, "."), "_acme-challenge."),
+		Key:                     key,
+		ResolvedFQDN:            fqdn,
+		ResolvedZone:            zone,
+		AllowAmbientCredentials: true,
+	}
+	if action == "cleanup" {
+		ch.Action = v1alpha1.ChallengeActionCleanUp
+	}
+	return ch, nil
+}
+
+// dnsName lowercases name and makes it fully qualified.
+func dnsName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, ".")) + "."
+}
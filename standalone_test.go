@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cert-manager/webhook-example/pkg/bunny"
+	"github.com/cert-manager/webhook-example/pkg/bunny/bunnytest"
+	"github.com/cert-manager/webhook-example/pkg/solver"
+)
+
+func TestRunStandalone(t *testing.T) {
+	api := bunnytest.NewServer()
+	defer api.Close()
+	api.RequireAPIKey("secret")
+	zone := api.AddZone("example.com")
+
+	require.NoError(t, solver.SetAPIBase(api.URL))
+	defer solver.SetAPIBase(solver.DefaultAPIBase)
+	defer func(prev solver.APIKeyProvider) { apiKeys = prev }(apiKeys)
+	apiKeys = solver.StaticAPIKey("secret")
+
+	var stderr bytes.Buffer
+	require.Equal(t, 0, runStandalone("present", []string{"--zone", "example.com", "_acme-challenge.WWW.example.com", "token"}, &stderr), stderr.String())
+	records := api.Records(zone)
+	require.Len(t, records, 1)
+	assert.Equal(t, bunny.Record{ID: records[0].ID, Type: bunny.RecordTypeTXT, Name: "_acme-challenge.www", Value: "token", Ttl: records[0].Ttl, Comment: "cert-manager-webhook-bunny"}, records[0])
+
+	require.Equal(t, 0, runStandalone("cleanup", []string{"--zone", "example.com.", "_acme-challenge.www.example.com.", "token"}, &stderr), stderr.String())
+	assert.Empty(t, api.Records(zone))
+}
+
+func TestRunStandalone_Usage(t *testing.T) {
+	var stderr bytes.Buffer
+	assert.Equal(t, 2, runStandalone("present", []string{"_acme-challenge.example.com"}, &stderr))
+	assert.Contains(t, stderr.String(), "usage: webhook present")
+
+	stderr.Reset()
+	assert.Equal(t, 1, runStandalone("cleanup", []string{"--zone", "example.org", "_acme-challenge.example.com", "token"}, &stderr))
+	assert.Contains(t, stderr.String(), "is not in zone example.org.")
+}
+
+func TestRunCertbotHook(t *testing.T) {
+	api := bunnytest.NewServer()
+	defer api.Close()
+	zone := api.AddZone("example.com")
+	nameserver, err := api.StartDNS()
+	require.NoError(t, err)
+
+	require.NoError(t, solver.SetAPIBase(api.URL))
+	defer solver.SetAPIBase(solver.DefaultAPIBase)
+	defer func(prev solver.APIKeyProvider) { apiKeys = prev }(apiKeys)
+	apiKeys = solver.StaticAPIKey("secret")
+	defer func(prev Options) { options = prev }(options)
+	options.PropagationNameservers = []string{nameserver}
+
+	env := map[string]string{"CERTBOT_DOMAIN": "*.example.com", "CERTBOT_VALIDATION": "validation"}
+	getenv := func(name string) string { return env[name] }
+
+	var stderr bytes.Buffer
+	require.Equal(t, 0, runCertbotHook("certbot-auth", []string{"--zone", "example.com"}, getenv, &stderr), stderr.String())
+	records := api.Records(zone)
+	require.Len(t, records, 1)
+	assert.Equal(t, "_acme-challenge", records[0].Name)
+	assert.Equal(t, "validation", records[0].Value)
+
+	require.Equal(t, 0, runCertbotHook("certbot-cleanup", []string{"--zone", "example.com"}, getenv, &stderr), stderr.String())
+	assert.Empty(t, api.Records(zone))
+
+	stderr.Reset()
+	assert.Equal(t, 2, runCertbotHook("certbot-auth", nil, func(string) string { return "" }, &stderr))
+	assert.Contains(t, stderr.String(), "CERTBOT_DOMAIN")
+}
+
+func TestRunCertbotHook_HTTP01(t *testing.T) {
+	files := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "password", r.Header.Get("AccessKey"))
+		switch r.Method {
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			files[r.URL.Path] = string(body)
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodDelete:
+			delete(files, r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	defer func(prev Options) { options = prev }(options)
+	env := map[string]string{"CERTBOT_DOMAIN": "example.com", "CERTBOT_VALIDATION": "token.thumbprint", "CERTBOT_TOKEN": "token"}
+	getenv := func(name string) string { return env[name] }
+
+	var stderr bytes.Buffer
+	assert.Equal(t, 2, runCertbotHook("certbot-auth", nil, getenv, &stderr))
+	assert.Contains(t, stderr.String(), "storageZone")
+
+	options.StorageZone, options.StoragePassword, options.StorageEndpoint = "site", "password", srv.URL
+	require.Equal(t, 0, runCertbotHook("certbot-auth", nil, getenv, &stderr), stderr.String())
+	assert.Equal(t, map[string]string{"/site/.well-known/acme-challenge/token": "token.thumbprint"}, files)
+	require.Equal(t, 0, runCertbotHook("certbot-cleanup", nil, getenv, &stderr), stderr.String())
+	assert.Empty(t, files)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+
+	"github.com/cert-manager/webhook-example/pkg/version"
+)
+
+const tracingServiceName = "cert-manager-webhook-bunny"
+
+// tracingEnabled reports whether an OTLP endpoint is configured for traces.
+func tracingEnabled(getenv func(string) string) bool {
+	return getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// configureTracing installs a TracerProvider exporting the solver's spans
+// over OTLP/gRPC if an OTLP endpoint is configured. The exporter and
+// resource follow the standard OTEL_* variables, e.g.
+// OTEL_EXPORTER_OTLP_INSECURE or OTEL_SERVICE_NAME. The returned function
+// flushes and stops the exporter.
+func configureTracing(ctx context.Context, getenv func(string) string) (func(context.Context) error, error) {
+	if !tracingEnabled(getenv) {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracegrpc.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceName(tracingServiceName),
+			semconv.ServiceVersion(version.Get().Version),
+		),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigureTracingDisabledWithoutEndpoint(t *testing.T) {
+	getenv := func(key string) string {
+		return map[string]string{"OTEL_SERVICE_NAME": "webhook"}[key]
+	}
+	assert.False(t, tracingEnabled(getenv))
+
+	shutdown, err := configureTracing(context.Background(), getenv)
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+
+	assert.True(t, tracingEnabled(func(key string) string {
+		return map[string]string{"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "http://collector:4317"}[key]
+	}))
+}